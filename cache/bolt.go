@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltBucket = []byte("gh-notify")
+	boltKey    = []byte("state")
+)
+
+// boltTimeout is how long to wait for another process to close the database.
+const boltTimeout = 30 * time.Second
+
+// BoltStore keeps the state in an embedded bbolt database. The database is
+// only opened for each load and save, since bbolt locks the file while it is
+// open and several runs may share it.
+type BoltStore struct {
+	path string
+}
+
+func NewBoltStore(path string) *BoltStore {
+	return &BoltStore{path: path}
+}
+
+func (b *BoltStore) Load() (*State, error) {
+	db, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var data []byte
+	err = db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(boltBucket); bucket != nil {
+			// Values are only valid during the transaction
+			if value := bucket.Get(boltKey); value != nil {
+				data = append([]byte(nil), value...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state from %s: %w", b.path, err)
+	}
+	return decodeState(data)
+}
+
+func (b *BoltStore) Save(state *State) error {
+	return b.save(state, true)
+}
+
+func (b *BoltStore) Replace(state *State) error {
+	return b.save(state, false)
+}
+
+// save writes the state in one transaction, merging what is stored when
+// merge is set.
+func (b *BoltStore) save(state *State, merge bool) error {
+	db, err := b.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var data []byte
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err
+		}
+		var current []byte
+		if merge {
+			current = bucket.Get(boltKey)
+		}
+		if data, err = state.encode(current); err != nil {
+			return err
+		}
+		return bucket.Put(boltKey, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write state to %s: %w", b.path, err)
+	}
+
+	state.stored(data)
+	return nil
+}
+
+func (b *BoltStore) open() (*bolt.DB, error) {
+	db, err := bolt.Open(b.path, 0644, &bolt.Options{Timeout: boltTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", b.path, err)
+	}
+	return db, nil
+}
+
+func (b *BoltStore) String() string {
+	return "bolt:" + b.path
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestBoltStore(t *testing.T) *BoltStore {
+	t.Helper()
+	return NewBoltStore(filepath.Join(t.TempDir(), "state.db"))
+}
+
+func TestBoltStoreEmptyDatabase(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if state.SchemaVersion != SchemaVersion || len(state.SentNotifications) != 0 {
+		t.Errorf("empty database loaded as %+v, want a new state", state)
+	}
+}
+
+func TestBoltStoreSaveAndLoad(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	state.MarkNotificationSent("workflow_1")
+	if err := store.Save(state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A new store reads the same file, as the next run would
+	loaded, err := NewBoltStore(store.path).Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := loaded.SentNotifications["workflow_1"]; !ok {
+		t.Errorf("reloaded state lost workflow_1: %v", loaded.SentNotifications)
+	}
+	if got := loaded.AlertCount("workflow_1"); got != 1 {
+		t.Errorf("reloaded alert count = %d, want 1", got)
+	}
+}
+
+func TestBoltStoreMergesConcurrentSave(t *testing.T) {
+	store := newTestBoltStore(t)
+	other := NewBoltStore(store.path)
+
+	first, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	second, err := other.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	first.MarkNotificationSent("workflow_1")
+	if err := store.Save(first); err != nil {
+		t.Fatalf("first Save: %v", err)
+	}
+	second.MarkNotificationSent("workflow_2")
+	if err := other.Save(second); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, key := range []string{"workflow_1", "workflow_2"} {
+		if _, ok := loaded.SentNotifications[key]; !ok {
+			t.Errorf("merged state lost %s: %v", key, loaded.SentNotifications)
+		}
+		if got := loaded.AlertCount(key); got != 1 {
+			t.Errorf("merged alert count for %s = %d, want 1", key, got)
+		}
+	}
+}
+
+func TestBoltStoreReplace(t *testing.T) {
+	store := newTestBoltStore(t)
+
+	stored := NewState()
+	stored.MarkNotificationSent("workflow_1")
+	if err := store.Save(stored); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Replace writes the state as is, without merging workflow_1 back in
+	replacement := NewState()
+	replacement.MarkNotificationSent("workflow_2")
+	if err := store.Replace(replacement); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := loaded.SentNotifications["workflow_1"]; ok {
+		t.Errorf("replaced state kept workflow_1: %v", loaded.SentNotifications)
+	}
+	if _, ok := loaded.SentNotifications["workflow_2"]; !ok {
+		t.Errorf("replaced state lost workflow_2: %v", loaded.SentNotifications)
+	}
+}
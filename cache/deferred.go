@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// DeferredAlert is an alert held back during quiet hours, to be sent with the
+// others in one batch once they end.
+type DeferredAlert struct {
+	Key         string          `json:"key"`
+	Category    string          `json:"category"`
+	Item        json.RawMessage `json:"item"`             // JSON-encoded pull request, issue, notification, invitation or workflow run
+	Change      string          `json:"change,omitempty"` // Why a known item is alerted again
+	Fingerprint *Fingerprint    `json:"fingerprint,omitempty"`
+	DeferredAt  time.Time       `json:"deferred_at"`
+}
+
+// Defer holds an alert back. Deferring a key again replaces its item but
+// keeps when it was first deferred.
+func (s *State) Defer(alert DeferredAlert) {
+	alert.DeferredAt = time.Now()
+	if previous, ok := s.Deferred[alert.Key]; ok {
+		alert.DeferredAt = previous.DeferredAt
+	}
+	s.Deferred[alert.Key] = alert
+	delete(s.removedDeferred, alert.Key)
+	s.deferredChanged = true
+}
+
+// DeferredAlerts returns the deferred alerts, oldest first.
+func (s *State) DeferredAlerts() []DeferredAlert {
+	alerts := make([]DeferredAlert, 0, len(s.Deferred))
+	for _, alert := range s.Deferred {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if !alerts[i].DeferredAt.Equal(alerts[j].DeferredAt) {
+			return alerts[i].DeferredAt.Before(alerts[j].DeferredAt)
+		}
+		return alerts[i].Key < alerts[j].Key
+	})
+	return alerts
+}
+
+// RemoveDeferred forgets deferred alerts once they were sent, queued or
+// dropped.
+func (s *State) RemoveDeferred(keys []string) {
+	for _, key := range keys {
+		if _, ok := s.Deferred[key]; ok {
+			delete(s.Deferred, key)
+			s.removedDeferred[key] = true
+			s.deferredChanged = true
+		}
+	}
+}
+
+// DeferredChanged reports whether alerts were deferred or removed since the
+// state was loaded or last saved.
+func (s *State) DeferredChanged() bool {
+	return s.deferredChanged
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once the rename has succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readStateFile reads path, returning nil data if it doesn't exist.
+func readStateFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return data, nil
+}
+
+// changedOnDisk reports whether data differs from what the state was loaded
+// from (or last saved as).
+func (s *State) changedOnDisk(data []byte) bool {
+	if data == nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return !bytes.Equal(sum[:], s.diskSum)
+}
+
+func (s *State) setDiskSum(data []byte) {
+	if data == nil {
+		s.diskSum = nil
+		return
+	}
+	sum := sha256.Sum256(data)
+	s.diskSum = sum[:]
+}
+
+// merge folds in a state another process saved since this one was loaded.
+// Notification keys, capped keys, fingerprints and cached responses are
+// unioned, keeping the newest timestamp, unless this process pruned them.
+// Alert counts add up the increments both sides made since the load. Outbox
+// messages the other process delivered or dropped are removed here too, so
+// they aren't resent.
+func (s *State) merge(disk *State) {
+	if disk.LastCheck.After(s.LastCheck) {
+		s.LastCheck = disk.LastCheck
+	}
+	if disk.LastDailyReport.After(s.LastDailyReport) {
+		s.LastDailyReport = disk.LastDailyReport
+	}
+
+	for key, sentAt := range disk.SentNotifications {
+		if pruned, ok := s.pruned[key]; ok && !sentAt.After(pruned) {
+			continue
+		}
+		if current, ok := s.SentNotifications[key]; !ok || sentAt.After(current) {
+			s.SentNotifications[key] = sentAt
+		}
+	}
+
+	// A count this process removed has moved by minus its loaded value, so
+	// only what the other process added since survives
+	counts := make(map[string]bool, len(disk.AlertCounts)+len(s.AlertCounts))
+	for key := range disk.AlertCounts {
+		counts[key] = true
+	}
+	for key := range s.AlertCounts {
+		counts[key] = true
+	}
+	for key := range counts {
+		count := disk.AlertCounts[key] + s.AlertCounts[key] - s.loadedCounts[key]
+		if count > 0 {
+			s.AlertCounts[key] = count
+		} else {
+			delete(s.AlertCounts, key)
+		}
+	}
+	// The counts now include the other side's, so a retried save merging
+	// again must only add what changed after this disk state
+	s.loadedCounts = make(map[string]int, len(disk.AlertCounts))
+	for key, count := range disk.AlertCounts {
+		s.loadedCounts[key] = count
+	}
+
+	for key, seenAt := range disk.Capped {
+		if pruned, ok := s.prunedCapped[key]; ok && !seenAt.After(pruned) {
+			continue
+		}
+		if seenAt.After(s.Capped[key]) {
+			s.Capped[key] = seenAt
+		}
+	}
+
+	for key, fingerprint := range disk.Fingerprints {
+		if pruned, ok := s.prunedFingerprints[key]; ok && !fingerprint.NotifiedAt.After(pruned) {
+			continue
+		}
+		if current, ok := s.Fingerprints[key]; !ok || fingerprint.NotifiedAt.After(current.NotifiedAt) {
+			s.Fingerprints[key] = fingerprint
+		}
+	}
+
+	// Legacy keys are only ever removed, so keep those neither side removed
+	for key := range s.LegacyKeys {
+		if _, ok := disk.LegacyKeys[key]; !ok {
+			delete(s.LegacyKeys, key)
+		}
+	}
+
+	s.httpMu.Lock()
+	for url, entry := range disk.HTTPCache {
+		if pruned, ok := s.prunedHTTP[url]; ok && !entry.StoredAt.After(pruned) {
+			continue
+		}
+		if current, ok := s.HTTPCache[url]; !ok || entry.StoredAt.After(current.StoredAt) {
+			s.HTTPCache[url] = entry
+		}
+	}
+	s.httpMu.Unlock()
+
+	onDisk := make(map[string]bool, len(disk.Outbox))
+	for _, entry := range disk.Outbox {
+		onDisk[entry.ID] = true
+	}
+	outbox := s.Outbox[:0]
+	ours := make(map[string]bool, len(s.Outbox))
+	for _, entry := range s.Outbox {
+		if s.loadedOutbox[entry.ID] && !onDisk[entry.ID] {
+			continue // Delivered or dropped by the other process
+		}
+		ours[entry.ID] = true
+		outbox = append(outbox, entry)
+	}
+	for _, entry := range disk.Outbox {
+		if !ours[entry.ID] && !s.removedOutbox[entry.ID] {
+			outbox = append(outbox, entry)
+		}
+	}
+	s.Outbox = outbox
+
+	for key := range s.Deferred {
+		if _, ok := disk.Deferred[key]; !ok && s.loadedDeferred[key] {
+			delete(s.Deferred, key) // Sent or dropped by the other process
+		}
+	}
+	for key, alert := range disk.Deferred {
+		if _, ok := s.Deferred[key]; !ok && !s.removedDeferred[key] {
+			s.Deferred[key] = alert
+		}
+	}
+}
+
+// resetTracking forgets the deletions, loaded alert counts and loaded outbox
+// and deferred alerts recorded since the last load or save, once they have
+// been written out.
+func (s *State) resetTracking() {
+	s.pruned = make(map[string]time.Time)
+	s.prunedCapped = make(map[string]time.Time)
+	s.prunedFingerprints = make(map[string]time.Time)
+	s.prunedHTTP = make(map[string]time.Time)
+	s.loadedCounts = make(map[string]int, len(s.AlertCounts))
+	for key, count := range s.AlertCounts {
+		s.loadedCounts[key] = count
+	}
+	s.removedOutbox = make(map[string]bool)
+	s.loadedOutbox = make(map[string]bool, len(s.Outbox))
+	for _, entry := range s.Outbox {
+		s.loadedOutbox[entry.ID] = true
+	}
+	s.removedDeferred = make(map[string]bool)
+	s.loadedDeferred = make(map[string]bool, len(s.Deferred))
+	for key := range s.Deferred {
+		s.loadedDeferred[key] = true
+	}
+}
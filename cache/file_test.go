@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.json")
+	if err := os.WriteFile(path, []byte("old contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("file contains %q, want %q", data, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Errorf("file mode = %v, want 0644", perm)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the state file", len(entries))
+	}
+}
+
+func TestWriteFileAtomicMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "cache.json")
+	if err := writeFileAtomic(path, []byte("{}"), 0644); err == nil {
+		t.Error("writeFileAtomic succeeded in a missing directory")
+	}
+}
+
+func TestSaveMergesConcurrentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	week := 7 * 24 * time.Hour
+	old := time.Now().Add(-week - time.Hour)
+
+	base := NewState()
+	base.MarkNotificationSent("workflow_1")
+	base.MarkNotificationSent("workflow_old")
+	base.SentNotifications["workflow_old"] = old
+	base.Capped["workflow_old"] = old
+	base.SetFingerprint("stale_pr_o/r#1", Fingerprint{State: "open", NotifiedAt: old})
+	if err := base.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	first, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	second, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+
+	// The first run cleans up and alerts; the second alerts meanwhile
+	first.CleanupOldEntries(week)
+	first.MarkNotificationSent("workflow_1")
+	first.MarkNotificationSent("invitation_1")
+	second.MarkNotificationSent("workflow_1")
+	second.MarkNotificationSent("workflow_2")
+	if err := second.Save(path); err != nil {
+		t.Fatalf("Save second: %v", err)
+	}
+	if err := first.Save(path); err != nil {
+		t.Fatalf("Save first: %v", err)
+	}
+
+	merged, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	for _, key := range []string{"workflow_1", "workflow_2", "invitation_1"} {
+		if _, ok := merged.SentNotifications[key]; !ok {
+			t.Errorf("sent notification %s lost in the merge", key)
+		}
+	}
+	if got := merged.AlertCount("workflow_1"); got != 3 {
+		t.Errorf("workflow_1 count = %d, want 3 (1 before, 1 from each run)", got)
+	}
+	if _, ok := merged.SentNotifications["workflow_old"]; ok {
+		t.Error("pruned notification came back from the other run's copy")
+	}
+	if _, ok := merged.Capped["workflow_old"]; ok {
+		t.Error("pruned capped key came back from the other run's copy")
+	}
+	if got := merged.AlertCount("workflow_old"); got != 0 {
+		t.Errorf("pruned alert count came back as %d", got)
+	}
+	if _, ok := merged.Fingerprint("stale_pr_o/r#1"); ok {
+		t.Error("pruned fingerprint came back from the other run's copy")
+	}
+}
+
+func TestMergeTwiceKeepsCounts(t *testing.T) {
+	state := NewState()
+	state.MarkNotificationSent("workflow_1")
+
+	// A retried save (see RedisStore.Save) merges each newer stored state
+	disk := NewState()
+	disk.AlertCounts["workflow_1"] = 2
+	state.merge(disk)
+	disk.AlertCounts["workflow_1"] = 3
+	state.merge(disk)
+
+	if got := state.AlertCount("workflow_1"); got != 4 {
+		t.Errorf("count = %d after merging twice, want 4", got)
+	}
+}
+
+func TestConcurrentSavesKeepEveryKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	if err := NewState().Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	const runs = 8
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			state, err := LoadState(path)
+			if err != nil {
+				t.Errorf("LoadState: %v", err)
+				return
+			}
+			state.MarkNotificationSent(fmt.Sprintf("workflow_%d", i))
+			state.MarkNotificationSent("workflow_shared")
+			if err := state.Save(path); err != nil {
+				t.Errorf("Save: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	for i := 0; i < runs; i++ {
+		if _, ok := state.SentNotifications[fmt.Sprintf("workflow_%d", i)]; !ok {
+			t.Errorf("workflow_%d lost by a concurrent save", i)
+		}
+	}
+	if got := state.AlertCount("workflow_shared"); got != runs {
+		t.Errorf("shared count = %d, want %d", got, runs)
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// Fingerprint is what an alerted item looked like when it was last notified,
+// so a later run can tell whether it has materially changed since.
+type Fingerprint struct {
+	State       string    `json:"state"` // "open", or "gone" once it dropped out of the alert's results
+	Title       string    `json:"title,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Labels      []string  `json:"labels,omitempty"` // Sorted
+	ReviewState string    `json:"review_state,omitempty"`
+	HeadSHA     string    `json:"head_sha,omitempty"`
+	NotifiedAt  time.Time `json:"notified_at"`
+}
+
+// StateGone marks a fingerprint whose item is no longer listed.
+const StateGone = "gone"
+
+// Fingerprint returns the fingerprint stored for a notification key.
+func (s *State) Fingerprint(key string) (Fingerprint, bool) {
+	fingerprint, ok := s.Fingerprints[key]
+	return fingerprint, ok
+}
+
+// SetFingerprint stores the fingerprint of a notified item.
+func (s *State) SetFingerprint(key string, fingerprint Fingerprint) {
+	if fingerprint.NotifiedAt.IsZero() {
+		fingerprint.NotifiedAt = time.Now()
+	}
+	s.Fingerprints[key] = fingerprint
+	s.fingerprintsChanged = true
+}
+
+// MarkGone sets the fingerprints whose key starts with prefix (one of the
+// key prefixes, e.g. ReviewRequestPrefix) and isn't in seen to StateGone.
+// seen must be the complete list GitHub returned, including items filters
+// dropped, or items merely left out come back as reopened.
+func (s *State) MarkGone(prefix string, seen map[string]bool) {
+	for key, fingerprint := range s.Fingerprints {
+		if !strings.HasPrefix(key, prefix) || seen[key] || fingerprint.State == StateGone {
+			continue
+		}
+		fingerprint.State = StateGone
+		s.Fingerprints[key] = fingerprint
+		s.fingerprintsChanged = true
+	}
+}
+
+// FingerprintsChanged reports whether fingerprints were stored or marked gone
+// since the state was loaded or last saved.
+func (s *State) FingerprintsChanged() bool {
+	return s.fingerprintsChanged
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Notification keys identify an alert in SentNotifications and the outbox.
+// Changing a format means existing keys stop matching and every open alert
+// is sent again, so add a migration (see migrations) along with it. repo is
+// the "owner/repo" an item belongs to.
+
+// Prefixes of the repository-qualified keys
+const (
+	ReviewRequestPrefix = "review_request_"
+	StalePRPrefix       = "stale_pr_"
+	AssignedIssuePrefix = "assigned_issue_"
+)
+
+func ReviewRequestKey(repo string, number int) string {
+	return fmt.Sprintf("%s%s#%d", ReviewRequestPrefix, repo, number)
+}
+
+func StalePRKey(repo string, number int) string {
+	return fmt.Sprintf("%s%s#%d", StalePRPrefix, repo, number)
+}
+
+func AssignedIssueKey(repo string, number int) string {
+	return fmt.Sprintf("%s%s#%d", AssignedIssuePrefix, repo, number)
+}
+
+// qualifiedKeyPattern matches the repository-qualified keys above, whose
+// schema version 1 form ("review_request_12") had no repository.
+var qualifiedKeyPattern = regexp.MustCompile(`^(review_request|stale_pr|assigned_issue)_[^#]+#(\d+)$`)
+
+// legacyKeyPattern matches those schema version 1 keys.
+var legacyKeyPattern = regexp.MustCompile(`^(review_request|stale_pr|assigned_issue)_\d+$`)
+
+// legacyKey returns the unqualified schema version 1 form of key, or "" if
+// key never had one.
+func legacyKey(key string) string {
+	match := qualifiedKeyPattern.FindStringSubmatch(key)
+	if match == nil {
+		return ""
+	}
+	return match[1] + "_" + match[2]
+}
+
+func InvitationKey(id int) string {
+	return fmt.Sprintf("invitation_%d", id)
+}
+
+func NotificationKey(threadID string) string {
+	return fmt.Sprintf("notification_%s", threadID)
+}
+
+func WorkflowKey(runID int) string {
+	return fmt.Sprintf("workflow_%d", runID)
+}
+
+// ReviewKey identifies a review submitted on one of the user's pull requests.
+func ReviewKey(repo string, number, reviewID int) string {
+	return fmt.Sprintf("review_%s#%d@%d", repo, number, reviewID)
+}
+
+// MembershipKey identifies the user being added to a repository.
+func MembershipKey(repo string) string {
+	return fmt.Sprintf("member_%s", repo)
+}
+
+// CommitKey identifies a pushed commit held back during quiet hours.
+func CommitKey(repo, sha string) string {
+	return fmt.Sprintf("commit_%s@%s", repo, sha)
+}
+
+// DeliveryKey identifies a webhook delivery by its X-GitHub-Delivery GUID, so
+// redeliveries aren't alerted twice.
+func DeliveryKey(guid string) string {
+	return fmt.Sprintf("delivery_%s", guid)
+}
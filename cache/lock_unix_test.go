@@ -0,0 +1,44 @@
+//go:build unix
+
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFileExcludesOtherHolders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile: %v", err)
+	}
+
+	locked := make(chan func())
+	go func() {
+		unlockOther, err := lockFile(path)
+		if err != nil {
+			t.Errorf("second lockFile: %v", err)
+			close(locked)
+			return
+		}
+		locked <- unlockOther
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("second lockFile returned while the lock was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case unlockOther, ok := <-locked:
+		if ok {
+			unlockOther()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lockFile still blocked after unlock")
+	}
+}
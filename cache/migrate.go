@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// SchemaVersion is the layout of the state this build reads and writes.
+// Files without a schema_version are version 0.
+const SchemaVersion = 2
+
+// migrations[v] upgrades a decoded state document from version v to v+1. A
+// migration may rewrite any top-level field, including the notification keys
+// in sent_notifications, so a key format change doesn't resend every alert.
+var migrations = []func(doc map[string]json.RawMessage) error{
+	migrateV0,
+	migrateV1,
+}
+
+// migrate brings the raw state data up to SchemaVersion.
+func migrate(data []byte) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	version := 0
+	if raw, ok := doc["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("invalid schema_version %s: %w", raw, err)
+		}
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("state has schema version %d, newer than this build supports (%d)", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return data, nil
+	}
+
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate state from schema version %d: %w", version, err)
+		}
+		slog.Info("migrated cache state", "from", version, "to", version+1)
+	}
+	doc["schema_version"] = json.RawMessage(fmt.Sprint(SchemaVersion))
+	return json.Marshal(doc)
+}
+
+// migrateV0 drops the processed_prs, processed_issues and
+// processed_notifications maps, which were never read.
+func migrateV0(doc map[string]json.RawMessage) error {
+	delete(doc, "processed_prs")
+	delete(doc, "processed_issues")
+	delete(doc, "processed_notifications")
+	return nil
+}
+
+// migrateV1 moves review request, stale PR and assigned issue keys, which
+// only had the number, to legacy_keys. They can't be qualified with their
+// repository here, so IsNotificationSent honors them for every repository's
+// item with that number until legacyKeyWindow has passed, and cleanup then
+// drops them.
+func migrateV1(doc map[string]json.RawMessage) error {
+	raw, ok := doc["sent_notifications"]
+	if !ok {
+		return nil
+	}
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		return err
+	}
+
+	legacy := make(map[string]json.RawMessage)
+	for key, sentAt := range sent {
+		if legacyKeyPattern.MatchString(key) {
+			legacy[key] = sentAt
+			delete(sent, key)
+		}
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	var err error
+	if doc["sent_notifications"], err = json.Marshal(sent); err != nil {
+		return err
+	}
+	doc["legacy_keys"], err = json.Marshal(legacy)
+	return err
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrateV0DropsUnusedMaps(t *testing.T) {
+	doc := map[string]json.RawMessage{
+		"sent_notifications":      json.RawMessage(`{"workflow_1":"2026-01-01T00:00:00Z"}`),
+		"processed_prs":           json.RawMessage(`{"1":true}`),
+		"processed_issues":        json.RawMessage(`{"2":true}`),
+		"processed_notifications": json.RawMessage(`{"3":true}`),
+	}
+	if err := migrateV0(doc); err != nil {
+		t.Fatalf("migrateV0: %v", err)
+	}
+	for _, field := range []string{"processed_prs", "processed_issues", "processed_notifications"} {
+		if _, ok := doc[field]; ok {
+			t.Errorf("%s kept", field)
+		}
+	}
+	if _, ok := doc["sent_notifications"]; !ok {
+		t.Error("sent_notifications dropped")
+	}
+}
+
+func TestMigrateV1MovesUnqualifiedKeys(t *testing.T) {
+	doc := map[string]json.RawMessage{
+		"sent_notifications": json.RawMessage(`{
+			"review_request_12": "2026-01-01T00:00:00Z",
+			"stale_pr_3": "2026-01-02T00:00:00Z",
+			"assigned_issue_12": "2026-01-03T00:00:00Z",
+			"workflow_99": "2026-01-04T00:00:00Z"
+		}`),
+	}
+	if err := migrateV1(doc); err != nil {
+		t.Fatalf("migrateV1: %v", err)
+	}
+
+	var sent, legacy map[string]time.Time
+	if err := json.Unmarshal(doc["sent_notifications"], &sent); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(doc["legacy_keys"], &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent["workflow_99"].IsZero() {
+		t.Errorf("sent_notifications = %v, want only workflow_99", sent)
+	}
+	want := map[string]string{
+		"review_request_12": "2026-01-01",
+		"stale_pr_3":        "2026-01-02",
+		"assigned_issue_12": "2026-01-03",
+	}
+	if len(legacy) != len(want) {
+		t.Errorf("legacy_keys = %v, want %d keys", legacy, len(want))
+	}
+	for key, day := range want {
+		if got := legacy[key].Format("2006-01-02"); got != day {
+			t.Errorf("legacy_keys[%s] = %s, want %s", key, got, day)
+		}
+	}
+}
+
+func TestMigrateV1WithoutLegacyKeys(t *testing.T) {
+	sent := json.RawMessage(`{"workflow_1":"2026-01-01T00:00:00Z"}`)
+	doc := map[string]json.RawMessage{"sent_notifications": sent}
+	if err := migrateV1(doc); err != nil {
+		t.Fatalf("migrateV1: %v", err)
+	}
+	if _, ok := doc["legacy_keys"]; ok {
+		t.Error("legacy_keys added with nothing to move")
+	}
+	if string(doc["sent_notifications"]) != string(sent) {
+		t.Errorf("sent_notifications rewritten: %s", doc["sent_notifications"])
+	}
+
+	if err := migrateV1(map[string]json.RawMessage{}); err != nil {
+		t.Errorf("migrateV1 without sent_notifications: %v", err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	_, err := migrate([]byte(`{"schema_version": 99}`))
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("migrate of a newer schema = %v, want an error", err)
+	}
+}
+
+func TestLegacyKeysHoldBackOnlyWithinTheWindow(t *testing.T) {
+	state := NewState()
+	state.LegacyKeys = map[string]time.Time{
+		"assigned_issue_12": time.Now().Add(-time.Hour),
+		"stale_pr_3":        time.Now().Add(-legacyKeyWindow - time.Hour),
+	}
+
+	// Either repository's #12 may be the one alerted, so both are held back
+	for _, key := range []string{AssignedIssueKey("a/one", 12), AssignedIssueKey("b/two", 12)} {
+		if !state.IsNotificationSent(key, 0) {
+			t.Errorf("%s not held back by its recent legacy key", key)
+		}
+	}
+	if len(state.SentNotifications) != 0 {
+		t.Errorf("lookup wrote sent notifications: %v", state.SentNotifications)
+	}
+	if state.IsNotificationSent(StalePRKey("a/one", 3), 48*time.Hour) {
+		t.Error("legacy key held an alert back past the legacy window")
+	}
+
+	state.CleanupOldEntries(7 * 24 * time.Hour)
+	if _, ok := state.LegacyKeys["stale_pr_3"]; ok {
+		t.Error("cleanup kept a legacy key past the legacy window")
+	}
+	if _, ok := state.LegacyKeys["assigned_issue_12"]; !ok {
+		t.Error("cleanup dropped a legacy key within the legacy window")
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MaxOutboxAttempts is how many times a queued message is tried before it is
+// dropped.
+const MaxOutboxAttempts = 5
+
+// OutboxEntry is a rendered message whose delivery to one destination
+// failed. It is retried at the start of the next run, and its notification
+// keys are marked sent only once it is delivered.
+type OutboxEntry struct {
+	ID              string          `json:"id"`
+	Message         json.RawMessage `json:"message"`                    // JSON-encoded notify.Message
+	Destination     int             `json:"destination"`                // Index of the destination in the configuration
+	DestinationType string          `json:"destination_type,omitempty"` // Its type, to notice the configuration changed
+	Part            int             `json:"part,omitempty"`             // First part of a split message not yet delivered
+	Keys            []string        `json:"keys,omitempty"`
+	Attempts        int             `json:"attempts"`
+	CreatedAt       time.Time       `json:"created_at"`
+	LastAttempt     time.Time       `json:"last_attempt"`
+	LastError       string          `json:"last_error,omitempty"`
+}
+
+// Enqueue adds an entry for a message whose first delivery attempt failed
+// with sendErr. Only its message, destination, part and keys are used.
+func (s *State) Enqueue(entry OutboxEntry, sendErr error) {
+	now := time.Now()
+	entry.ID = fmt.Sprintf("%d-%d", now.UnixNano(), len(s.Outbox))
+	entry.Attempts = 1
+	entry.CreatedAt = now
+	entry.LastAttempt = now
+	entry.LastError = sendErr.Error()
+	s.Outbox = append(s.Outbox, entry)
+	s.outboxChanged = true
+}
+
+// PendingMessages returns a copy of the outbox, oldest first.
+func (s *State) PendingMessages() []OutboxEntry {
+	return append([]OutboxEntry(nil), s.Outbox...)
+}
+
+// IsNotificationQueued reports whether key belongs to a message waiting in
+// the outbox or to an alert deferred for quiet hours, so the alert isn't
+// queued or deferred a second time.
+func (s *State) IsNotificationQueued(key string) bool {
+	if _, ok := s.Deferred[key]; ok {
+		return true
+	}
+	for _, entry := range s.Outbox {
+		for _, queued := range entry.Keys {
+			if queued == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Delivered removes the message from the outbox and marks its keys sent. The
+// same message queued for other destinations no longer carries the keys, so
+// they are marked only once.
+func (s *State) Delivered(id string) {
+	for i, entry := range s.Outbox {
+		if entry.ID == id {
+			for _, key := range entry.Keys {
+				s.MarkNotificationSent(key)
+			}
+			s.Outbox = append(s.Outbox[:i], s.Outbox[i+1:]...)
+			s.removedOutbox[id] = true
+			s.outboxChanged = true
+			s.unqueueKeys(entry.Keys)
+			return
+		}
+	}
+}
+
+// unqueueKeys removes keys from every outbox entry.
+func (s *State) unqueueKeys(keys []string) {
+	done := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		done[key] = true
+	}
+	for i := range s.Outbox {
+		entry := &s.Outbox[i]
+		var kept []string
+		for _, key := range entry.Keys {
+			if !done[key] {
+				kept = append(kept, key)
+			}
+		}
+		entry.Keys = kept
+	}
+}
+
+// DeliveryFailed records another failed attempt, which got as far as part
+// (see OutboxEntry.Part). The message is dropped, and true returned, if the
+// failure is permanent or MaxOutboxAttempts is reached.
+func (s *State) DeliveryFailed(id string, part int, err error, permanent bool) bool {
+	for i := range s.Outbox {
+		entry := &s.Outbox[i]
+		if entry.ID != id {
+			continue
+		}
+		entry.Attempts++
+		entry.LastAttempt = time.Now()
+		entry.LastError = err.Error()
+		if part > entry.Part {
+			entry.Part = part
+		}
+		s.outboxChanged = true
+
+		if permanent || entry.Attempts >= MaxOutboxAttempts {
+			s.Outbox = append(s.Outbox[:i], s.Outbox[i+1:]...)
+			s.removedOutbox[id] = true
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// OutboxChanged reports whether the outbox was modified since the state was
+// loaded or last saved.
+func (s *State) OutboxChanged() bool {
+	return s.outboxChanged
+}
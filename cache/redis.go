@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRedisKey holds the state unless the URL sets ?key=.
+	DefaultRedisKey = "gh-notify:state"
+	redisTimeout    = 30 * time.Second
+	// redisMaxRetries bounds how often Save retries after another client
+	// changed the key between its read and write.
+	redisMaxRetries = 10
+)
+
+// RedisStore keeps the state as one JSON value in Redis, or any server that
+// speaks its protocol. Save uses WATCH/MULTI/EXEC so concurrent writers
+// merge rather than overwrite each other.
+type RedisStore struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+	key      string
+}
+
+// NewRedisStore parses a redis:// or rediss:// (TLS) URL of the form
+// redis://[user:password@]host[:port][/db][?key=name].
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if parsed.Scheme != "redis" && parsed.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid redis URL %q: scheme must be redis or rediss", rawURL)
+	}
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid redis URL %q: missing host", rawURL)
+	}
+
+	store := &RedisStore{
+		addr:   parsed.Host,
+		useTLS: parsed.Scheme == "rediss",
+		key:    DefaultRedisKey,
+	}
+	if parsed.Port() == "" {
+		store.addr = net.JoinHostPort(parsed.Hostname(), "6379")
+	}
+	if parsed.User != nil {
+		store.username = parsed.User.Username()
+		store.password, _ = parsed.User.Password()
+	}
+	if db := strings.Trim(parsed.Path, "/"); db != "" {
+		if store.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis URL %q: database must be a number", rawURL)
+		}
+	}
+	if key := parsed.Query().Get("key"); key != "" {
+		store.key = key
+	}
+	return store, nil
+}
+
+func (r *RedisStore) Load() (*State, error) {
+	conn, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	data, err := r.get(conn)
+	if err != nil {
+		return nil, err
+	}
+	return decodeState(data)
+}
+
+func (r *RedisStore) Save(state *State) error {
+	conn, err := r.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for attempt := 0; attempt < redisMaxRetries; attempt++ {
+		if _, err := conn.do("WATCH", r.key); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", r.key, err)
+		}
+		current, err := r.get(conn)
+		if err != nil {
+			return err
+		}
+		data, err := state.encode(current)
+		if err != nil {
+			return err
+		}
+
+		if _, err := conn.do("MULTI"); err != nil {
+			return fmt.Errorf("failed to start transaction: %w", err)
+		}
+		if _, err := conn.do("SET", r.key, string(data)); err != nil {
+			return fmt.Errorf("failed to queue write: %w", err)
+		}
+		reply, err := conn.do("EXEC")
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", r.key, err)
+		}
+		if reply == nil {
+			// Another client wrote the key; back off a little and merge again
+			time.Sleep(time.Duration(rand.Int63n(int64(attempt+1) * int64(50*time.Millisecond))))
+			continue
+		}
+		state.stored(data)
+		return nil
+	}
+	return fmt.Errorf("failed to write %s: it kept changing concurrently", r.key)
+}
+
+func (r *RedisStore) Replace(state *State) error {
+	conn, err := r.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	data, err := state.encode(nil)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.do("SET", r.key, string(data)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", r.key, err)
+	}
+	state.stored(data)
+	return nil
+}
+
+func (r *RedisStore) String() string {
+	return fmt.Sprintf("redis:%s/%d/%s", r.addr, r.db, r.key)
+}
+
+// get returns the stored state, or nil if the key doesn't exist.
+func (r *RedisStore) get(conn *respConn) ([]byte, error) {
+	reply, err := conn.do("GET", r.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", r.key, err)
+	}
+	if reply == nil {
+		return nil, nil
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("failed to read %s: unexpected reply %v", r.key, reply)
+	}
+	return data, nil
+}
+
+// dial connects, authenticates and selects the database.
+func (r *RedisStore) dial() (*respConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if r.useTLS {
+		host, _, _ := net.SplitHostPort(r.addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", r.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", r.addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(redisTimeout))
+	client := newRESPConn(conn)
+
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.username != "" {
+			args = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := client.do(args...); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("redis authentication failed: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := client.do("SELECT", strconv.Itoa(r.db)); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to select redis database %d: %w", r.db, err)
+		}
+	}
+	return client, nil
+}
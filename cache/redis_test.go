@@ -0,0 +1,344 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis is an in-process server speaking enough RESP2 for RedisStore:
+// AUTH, SELECT, GET, SET, WATCH, UNWATCH, MULTI, EXEC and DISCARD, with
+// optimistic locking on watched keys.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	versions map[string]int // Bumped on every write, for WATCH
+	execs    int
+	// beforeExec runs before the nth EXEC (1-based) is applied, to simulate
+	// another client writing between the WATCH and the EXEC.
+	beforeExec map[int]func(f *fakeRedis)
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{
+		listener:   listener,
+		password:   password,
+		values:     make(map[string]string),
+		versions:   make(map[string]int),
+		beforeExec: make(map[int]func(f *fakeRedis)),
+	}
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+func (f *fakeRedis) url(path string) string {
+	return "redis://" + f.listener.Addr().String() + path
+}
+
+// value returns a stored value, and whether the key exists.
+func (f *fakeRedis) value(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.values[key]
+	return value, ok
+}
+
+func (f *fakeRedis) execCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.execs
+}
+
+// set writes a key the way another client would.
+func (f *fakeRedis) set(key, value string) {
+	f.values[key] = value
+	f.versions[key]++
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := f.password == ""
+	var watched map[string]int
+	var queued [][]string
+	inMulti := false
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(args[0])
+
+		if !authenticated && name != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		if inMulti && name != "EXEC" && name != "DISCARD" {
+			queued = append(queued, args)
+			io.WriteString(conn, "+QUEUED\r\n")
+			continue
+		}
+
+		f.mu.Lock()
+		switch name {
+		case "AUTH":
+			if args[len(args)-1] == f.password {
+				authenticated = true
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case "SELECT":
+			io.WriteString(conn, "+OK\r\n")
+		case "WATCH":
+			watched = make(map[string]int)
+			for _, key := range args[1:] {
+				watched[key] = f.versions[key]
+			}
+			io.WriteString(conn, "+OK\r\n")
+		case "UNWATCH":
+			watched = nil
+			io.WriteString(conn, "+OK\r\n")
+		case "MULTI":
+			inMulti, queued = true, nil
+			io.WriteString(conn, "+OK\r\n")
+		case "DISCARD":
+			inMulti, queued, watched = false, nil, nil
+			io.WriteString(conn, "+OK\r\n")
+		case "EXEC":
+			f.execs++
+			if hook := f.beforeExec[f.execs]; hook != nil {
+				hook(f)
+			}
+			conflict := false
+			for key, version := range watched {
+				conflict = conflict || f.versions[key] != version
+			}
+			if conflict {
+				io.WriteString(conn, "*-1\r\n")
+			} else {
+				fmt.Fprintf(conn, "*%d\r\n", len(queued))
+				for _, command := range queued {
+					io.WriteString(conn, f.apply(command))
+				}
+			}
+			inMulti, queued, watched = false, nil, nil
+		default:
+			io.WriteString(conn, f.apply(args))
+		}
+		f.mu.Unlock()
+	}
+}
+
+// apply runs GET or SET and returns the reply.
+func (f *fakeRedis) apply(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		f.set(args[1], args[2])
+		return "+OK\r\n"
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk length %q", line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func newTestRedisStore(t *testing.T, rawURL string) *RedisStore {
+	t.Helper()
+	store, err := NewRedisStore(rawURL)
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	return store
+}
+
+func TestRedisStoreMissingKey(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := newTestRedisStore(t, server.url("/2?key=test:state"))
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if state.SchemaVersion != SchemaVersion || len(state.SentNotifications) != 0 {
+		t.Errorf("missing key loaded as %+v, want a new state", state)
+	}
+	if _, ok := server.value("test:state"); ok {
+		t.Error("Load created the key")
+	}
+}
+
+func TestRedisStoreSaveAndLoad(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	store := newTestRedisStore(t, "redis://:secret@"+server.listener.Addr().String()+"?key=test:state")
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	state.MarkNotificationSent("workflow_1")
+	if err := store.Save(state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	value, _ := server.value("test:state")
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		t.Fatalf("stored value is not JSON: %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := loaded.SentNotifications["workflow_1"]; !ok {
+		t.Errorf("reloaded state lost workflow_1: %v", loaded.SentNotifications)
+	}
+}
+
+func TestRedisStoreMergesConcurrentSave(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := newTestRedisStore(t, server.url(""))
+
+	first, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	second, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	first.MarkNotificationSent("workflow_1")
+	if err := store.Save(first); err != nil {
+		t.Fatalf("first Save: %v", err)
+	}
+	second.MarkNotificationSent("workflow_2")
+	if err := store.Save(second); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, key := range []string{"workflow_1", "workflow_2"} {
+		if _, ok := loaded.SentNotifications[key]; !ok {
+			t.Errorf("merged state lost %s: %v", key, loaded.SentNotifications)
+		}
+	}
+}
+
+func TestRedisStoreRetriesConflictingWrite(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := newTestRedisStore(t, server.url(""))
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Another client saves between this Save's read and its EXEC
+	other := NewState()
+	other.MarkNotificationSent("workflow_other")
+	otherData, err := other.encode(nil)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	server.beforeExec[1] = func(f *fakeRedis) {
+		f.set(DefaultRedisKey, string(otherData))
+	}
+
+	state.MarkNotificationSent("workflow_1")
+	if err := store.Save(state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if execs := server.execCount(); execs != 2 {
+		t.Errorf("EXEC ran %d times, want 2 (conflict, then retry)", execs)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, key := range []string{"workflow_1", "workflow_other"} {
+		if _, ok := loaded.SentNotifications[key]; !ok {
+			t.Errorf("saved state lost %s: %v", key, loaded.SentNotifications)
+		}
+	}
+}
+
+func TestRedisStoreGivesUpOnConstantConflicts(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store := newTestRedisStore(t, server.url(""))
+	for i := 1; i <= redisMaxRetries; i++ {
+		n := i
+		server.beforeExec[i] = func(f *fakeRedis) {
+			f.set(DefaultRedisKey, fmt.Sprintf(`{"schema_version": %d, "last_check": "2026-10-16T00:00:0%dZ"}`, SchemaVersion, n%10))
+		}
+	}
+
+	if err := store.Save(NewState()); err == nil {
+		t.Fatal("Save succeeded although every EXEC conflicted")
+	}
+	if execs := server.execCount(); execs != redisMaxRetries {
+		t.Errorf("EXEC ran %d times, want %d", execs, redisMaxRetries)
+	}
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// respConn is a minimal client for the Redis serialization protocol (RESP2),
+// enough for the handful of commands RedisStore needs.
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisError is an error reply ("-ERR ...") from the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func newRESPConn(conn net.Conn) *respConn {
+	return &respConn{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do sends a command and returns its reply: a string for simple strings,
+// int64, []byte for bulk strings, []interface{} for arrays, or nil for null
+// replies. Error replies are returned as a redisError.
+func (c *respConn) do(args ...string) (interface{}, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+func (c *respConn) readReply() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2) // Including the trailing CRLF
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			// Errors inside an array (e.g. from EXEC) stay as values
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+// readLine reads one CRLF-terminated line without the terminator.
+func (c *respConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
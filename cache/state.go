@@ -3,37 +3,111 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"log/slog"
+	"sync"
 	"time"
 )
 
 type State struct {
-	LastCheck         time.Time            `json:"last_check"`
-	LastDailyReport   time.Time            `json:"last_daily_report"`
-	SentNotifications map[string]time.Time `json:"sent_notifications"`
-	ProcessedPRs      map[string]bool      `json:"processed_prs"`
-	ProcessedIssues   map[string]bool      `json:"processed_issues"`
-	ProcessedNotifs   map[string]bool      `json:"processed_notifications"`
+	SchemaVersion     int                      `json:"schema_version"`
+	LastCheck         time.Time                `json:"last_check"`
+	LastDailyReport   time.Time                `json:"last_daily_report"`
+	SentNotifications map[string]time.Time     `json:"sent_notifications"`
+	LegacyKeys        map[string]time.Time     `json:"legacy_keys,omitempty"`  // Pre-repository keys, kept for legacyKeyWindow (see migrateV1)
+	AlertCounts       map[string]int           `json:"alert_counts,omitempty"` // How often each key was sent
+	Capped            map[string]time.Time     `json:"capped,omitempty"`       // Keys held back by max_repeats, with when that was last seen
+	Fingerprints      map[string]Fingerprint   `json:"fingerprints,omitempty"` // Keyed like SentNotifications
+	HTTPCache         map[string]HTTPEntry     `json:"http_cache,omitempty"`
+	Outbox            []OutboxEntry            `json:"outbox,omitempty"`
+	Deferred          map[string]DeferredAlert `json:"deferred,omitempty"` // Alerts held back during quiet hours, by key
+
+	httpMu              sync.Mutex // HTTPCache is written from concurrent API calls
+	httpCacheChanged    bool
+	outboxChanged       bool
+	fingerprintsChanged bool
+	deferredChanged     bool
+	cappedChanged       bool
+
+	// Bookkeeping for merging with changes other processes saved meanwhile
+	diskSum            []byte               // SHA-256 of the file as last read or written
+	pruned             map[string]time.Time // Notification keys removed by cleanup, with their timestamp
+	prunedCapped       map[string]time.Time // Capped keys removed by cleanup, with when they were last seen
+	prunedFingerprints map[string]time.Time // Fingerprints removed by cleanup, with their NotifiedAt
+	prunedHTTP         map[string]time.Time // Cached responses removed by cleanup, with their StoredAt
+	loadedCounts       map[string]int       // AlertCounts when last read or written, to merge increments
+	removedOutbox      map[string]bool      // Outbox entries delivered or dropped
+	loadedOutbox       map[string]bool      // Outbox entries present when last read or written
+	removedDeferred    map[string]bool      // Deferred alerts sent, queued or dropped
+	loadedDeferred     map[string]bool      // Deferred alerts present when last read or written
+}
+
+// HTTPEntry is a cached GitHub API response used for conditional requests.
+type HTTPEntry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Link         string    `json:"link,omitempty"`
+	Body         []byte    `json:"body"`
+	StoredAt     time.Time `json:"stored_at"`
+	PollAfter    time.Time `json:"poll_after,omitempty"`
 }
 
 func NewState() *State {
-	return &State{
+	state := &State{
+		SchemaVersion:     SchemaVersion,
 		LastCheck:         time.Now(),
 		LastDailyReport:   time.Now().AddDate(0, 0, -1), // Yesterday
 		SentNotifications: make(map[string]time.Time),
-		ProcessedPRs:      make(map[string]bool),
-		ProcessedIssues:   make(map[string]bool),
-		ProcessedNotifs:   make(map[string]bool),
+		AlertCounts:       make(map[string]int),
+		Capped:            make(map[string]time.Time),
+		Fingerprints:      make(map[string]Fingerprint),
+		HTTPCache:         make(map[string]HTTPEntry),
+		Deferred:          make(map[string]DeferredAlert),
 	}
+	state.resetTracking()
+	return state
 }
 
+// LoadState reads the state file, holding its lock while reading. A missing
+// file yields a new state.
+//
+// The lock is not kept until Save: a run spends most of its time calling the
+// GitHub API and the notifiers, and serve keeps its state for its whole life.
+// Runs that overlap instead each save their own changes, and Save merges in
+// whatever the others saved meanwhile. The state records what it removed and
+// how far it moved each alert count since loading, so the merge neither
+// brings back pruned entries nor loses increments made by either side.
 func LoadState(filepath string) (*State, error) {
-	data, err := os.ReadFile(filepath)
+	unlock, err := lockFile(filepath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return NewState(), nil
-		}
-		return nil, fmt.Errorf("failed to read state file: %w", err)
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := readStateFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return decodeState(data)
+}
+
+// decodeState parses stored state data; nil data yields a new state.
+func decodeState(data []byte) (*State, error) {
+	if data == nil {
+		return NewState(), nil
+	}
+	state, err := parseState(data)
+	if err != nil {
+		return nil, err
+	}
+	state.setDiskSum(data)
+	return state, nil
+}
+
+// parseState decodes stored state data, migrating it to SchemaVersion first.
+func parseState(data []byte) (*State, error) {
+	data, err := migrate(data)
+	if err != nil {
+		return nil, err
 	}
 
 	var state State
@@ -45,43 +119,170 @@ func LoadState(filepath string) (*State, error) {
 	if state.SentNotifications == nil {
 		state.SentNotifications = make(map[string]time.Time)
 	}
+	if state.AlertCounts == nil {
+		state.AlertCounts = make(map[string]int)
+	}
+	if state.Capped == nil {
+		state.Capped = make(map[string]time.Time)
+	}
+	if state.Fingerprints == nil {
+		state.Fingerprints = make(map[string]Fingerprint)
+	}
+	if state.HTTPCache == nil {
+		state.HTTPCache = make(map[string]HTTPEntry)
+	}
+	if state.Deferred == nil {
+		state.Deferred = make(map[string]DeferredAlert)
+	}
+	state.resetTracking()
 
 	return &state, nil
 }
 
+// Save writes the state atomically while holding the file's lock. If another
+// process saved the file since this state was loaded, its changes are merged
+// in first (see merge), so no sent notification is lost.
 func (s *State) Save(filepath string) error {
-	data, err := json.MarshalIndent(s, "", "  ")
+	return s.save(filepath, true)
+}
+
+// Replace writes the state over the file without merging what is on disk,
+// for deliberately discarding the cache.
+func (s *State) Replace(filepath string) error {
+	return s.save(filepath, false)
+}
+
+func (s *State) save(filepath string, merge bool) error {
+	unlock, err := lockFile(filepath)
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+		return err
 	}
+	defer unlock()
 
-	if err := os.WriteFile(filepath, data, 0644); err != nil {
+	var current []byte
+	if merge {
+		if current, err = readStateFile(filepath); err != nil {
+			return err
+		}
+	}
+	data, err := s.encode(current)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(filepath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
+	s.stored(data)
 	return nil
 }
 
+// encode returns the data to store. current is what is stored now (nil for
+// nothing, or to overwrite it); if it changed since the state was loaded it
+// is merged in first.
+func (s *State) encode(current []byte) ([]byte, error) {
+	if s.changedOnDisk(current) {
+		disk, err := parseState(current)
+		if err != nil {
+			slog.Warn("stored state is unreadable, overwriting it", "error", err)
+		} else {
+			slog.Debug("stored state changed since load, merging")
+			s.merge(disk)
+		}
+	}
+
+	s.httpMu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.httpMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return data, nil
+}
+
+// stored records that data, as returned by encode, was written.
+func (s *State) stored(data []byte) {
+	s.setDiskSum(data)
+	s.resetTracking()
+	s.httpMu.Lock()
+	s.httpCacheChanged = false
+	s.httpMu.Unlock()
+	s.outboxChanged = false
+	s.fingerprintsChanged = false
+	s.deferredChanged = false
+	s.cappedChanged = false
+}
+
+// legacyKeyWindow is how long a pre-repository key from schema version 1
+// (see migrateV1) keeps suppressing the items it may stand for: one default
+// cooldown. It can't tell which repository it was sent for, so it is then
+// dropped rather than risk silencing another repository's item for good.
+const legacyKeyWindow = 24 * time.Hour
+
 func (s *State) IsNotificationSent(key string, cooldown time.Duration) bool {
 	if lastSent, exists := s.SentNotifications[key]; exists {
 		// Special case: if cooldown is 0, just check if it was ever sent (for workflow failures)
 		if cooldown == 0 {
-			fmt.Printf("DEBUG: One-time check for '%s': was sent before, blocking repeat\n", key)
+			slog.Debug("one-time alert already sent", "key", key)
 			return true
 		}
 
 		timeSince := time.Since(lastSent)
 		withinCooldown := timeSince < cooldown
-		// Debug logging for cooldown check
-		fmt.Printf("DEBUG: Cooldown check for '%s': last sent %.2f hours ago, cooldown %.2f hours, within cooldown: %t\n",
-			key, timeSince.Hours(), cooldown.Hours(), withinCooldown)
+		slog.Debug("cooldown check", "key", key, "since_last_sent", timeSince.Round(time.Second),
+			"cooldown", cooldown, "within_cooldown", withinCooldown)
 		return withinCooldown
 	}
-	return false
+	return s.legacySent(key, cooldown)
+}
+
+// legacySent reports whether key's unqualified legacy form was sent within
+// cooldown, capped at legacyKeyWindow. Any repository's item with the same
+// number matches it, so it only holds alerts back for that long.
+func (s *State) legacySent(key string, cooldown time.Duration) bool {
+	legacy := legacyKey(key)
+	if legacy == "" {
+		return false
+	}
+	sentAt, ok := s.LegacyKeys[legacy]
+	if !ok {
+		return false
+	}
+	if cooldown == 0 || cooldown > legacyKeyWindow {
+		cooldown = legacyKeyWindow
+	}
+	withinCooldown := time.Since(sentAt) < cooldown
+	slog.Debug("legacy key check", "key", key, "legacy_key", legacy, "within_cooldown", withinCooldown)
+	return withinCooldown
 }
 
 func (s *State) MarkNotificationSent(key string) {
 	s.SentNotifications[key] = time.Now()
+	s.AlertCounts[key]++
+}
+
+// AlertCount returns how many times key has been sent since it was last
+// cleaned up.
+func (s *State) AlertCount(key string) int {
+	return s.AlertCounts[key]
+}
+
+// MarkCapped records that key is still being held back because it reached
+// its max_repeats, so cleanup keeps its count even once the last send is
+// old. The time is refreshed at most daily, to avoid a save every run.
+func (s *State) MarkCapped(key string) {
+	if last, ok := s.Capped[key]; ok && time.Since(last) < 24*time.Hour {
+		return
+	}
+	s.Capped[key] = time.Now()
+	s.cappedChanged = true
+}
+
+// CappedChanged reports whether keys were marked capped since the state was
+// loaded or last saved.
+func (s *State) CappedChanged() bool {
+	return s.cappedChanged
 }
 
 func (s *State) CleanupOldEntries(maxAge time.Duration) bool {
@@ -92,9 +293,100 @@ func (s *State) CleanupOldEntries(maxAge time.Duration) bool {
 	for key, timestamp := range s.SentNotifications {
 		if timestamp.Before(cutoff) {
 			delete(s.SentNotifications, key)
+			s.pruned[key] = timestamp
 			removedAny = true
 		}
 	}
 
+	// Capped alerts keep their count for as long as they keep coming up
+	for key, seenAt := range s.Capped {
+		if seenAt.Before(cutoff) {
+			delete(s.Capped, key)
+			s.prunedCapped[key] = seenAt
+			removedAny = true
+		}
+	}
+	for key := range s.AlertCounts {
+		_, sent := s.SentNotifications[key]
+		_, capped := s.Capped[key]
+		if !sent && !capped {
+			delete(s.AlertCounts, key)
+			removedAny = true
+		}
+	}
+
+	// Forget items that haven't been notified for as long
+	for key, fingerprint := range s.Fingerprints {
+		if fingerprint.NotifiedAt.Before(cutoff) {
+			delete(s.Fingerprints, key)
+			s.prunedFingerprints[key] = fingerprint.NotifiedAt
+			removedAny = true
+		}
+	}
+
+	for key, alert := range s.Deferred {
+		if alert.DeferredAt.Before(cutoff) {
+			delete(s.Deferred, key)
+			s.removedDeferred[key] = true
+			s.deferredChanged = true
+			removedAny = true
+		}
+	}
+
+	legacyCutoff := time.Now().Add(-legacyKeyWindow)
+	for key, timestamp := range s.LegacyKeys {
+		if timestamp.Before(legacyCutoff) {
+			delete(s.LegacyKeys, key)
+			removedAny = true
+		}
+	}
+
+	// Clean up HTTP responses that haven't been refreshed recently
+	s.httpMu.Lock()
+	for url, entry := range s.HTTPCache {
+		if entry.StoredAt.Before(cutoff) {
+			delete(s.HTTPCache, url)
+			s.prunedHTTP[url] = entry.StoredAt
+			removedAny = true
+		}
+	}
+	s.httpMu.Unlock()
+
 	return removedAny
 }
+
+// GetHTTPEntry returns the cached response for url, if any.
+func (s *State) GetHTTPEntry(url string) (HTTPEntry, bool) {
+	s.httpMu.Lock()
+	defer s.httpMu.Unlock()
+	entry, ok := s.HTTPCache[url]
+	return entry, ok
+}
+
+// httpRefreshInterval is how far a revalidated response's StoredAt has to
+// move before the refresh alone is worth saving. Without it, a response that
+// keeps coming back 304 would be pruned by CleanupOldEntries once the saved
+// StoredAt fell out of the retention window.
+const httpRefreshInterval = 24 * time.Hour
+
+// PutHTTPEntry stores the response for url. Changes to the validators or body
+// count as a cache change, and so does a StoredAt at least
+// httpRefreshInterval newer; a refreshed poll interval alone does not.
+func (s *State) PutHTTPEntry(url string, entry HTTPEntry) {
+	s.httpMu.Lock()
+	defer s.httpMu.Unlock()
+	if previous, ok := s.HTTPCache[url]; !ok || previous.ETag != entry.ETag ||
+		previous.LastModified != entry.LastModified || string(previous.Body) != string(entry.Body) ||
+		entry.StoredAt.Sub(previous.StoredAt) >= httpRefreshInterval {
+		s.httpCacheChanged = true
+	}
+	s.HTTPCache[url] = entry
+}
+
+// HTTPCacheChanged reports whether any cached response was added or updated
+// since the state was loaded.
+func (s *State) HTTPCacheChanged() bool {
+	s.httpMu.Lock()
+	defer s.httpMu.Unlock()
+	return s.httpCacheChanged
+}
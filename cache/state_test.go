@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCleanupKeepsCappedAlertCounts(t *testing.T) {
+	state := NewState()
+	for i := 0; i < 3; i++ {
+		state.MarkNotificationSent("workflow_capped")
+		state.MarkNotificationSent("workflow_quiet")
+	}
+	week := 7 * 24 * time.Hour
+	old := time.Now().Add(-week - time.Hour)
+	state.SentNotifications["workflow_capped"] = old
+	state.SentNotifications["workflow_quiet"] = old
+
+	// Still listed and held back, so its count must survive the cleanup
+	state.MarkCapped("workflow_capped")
+	if !state.CappedChanged() {
+		t.Error("MarkCapped did not flag the state as changed")
+	}
+
+	state.CleanupOldEntries(week)
+	if got := state.AlertCount("workflow_capped"); got != 3 {
+		t.Errorf("capped alert count = %d after cleanup, want 3", got)
+	}
+	if got := state.AlertCount("workflow_quiet"); got != 0 {
+		t.Errorf("stale alert count = %d after cleanup, want 0", got)
+	}
+
+	// Once the alert stops coming up, its count goes too
+	state.Capped["workflow_capped"] = old
+	state.CleanupOldEntries(week)
+	if got := state.AlertCount("workflow_capped"); got != 0 {
+		t.Errorf("alert count = %d once no longer capped, want 0", got)
+	}
+}
+
+func TestMarkCappedRefreshesDaily(t *testing.T) {
+	state := NewState()
+	state.MarkCapped("workflow_1")
+	state.stored(nil)
+
+	state.MarkCapped("workflow_1")
+	if state.CappedChanged() {
+		t.Error("MarkCapped flagged a change within the same day")
+	}
+
+	state.Capped["workflow_1"] = time.Now().Add(-25 * time.Hour)
+	state.MarkCapped("workflow_1")
+	if !state.CappedChanged() {
+		t.Error("MarkCapped did not refresh a day-old entry")
+	}
+}
+
+func TestIsNotificationQueuedWhileDeferred(t *testing.T) {
+	state := NewState()
+	if state.IsNotificationQueued("workflow_1") {
+		t.Fatal("unknown key reported queued")
+	}
+
+	state.Defer(DeferredAlert{Key: "workflow_1", Category: "workflows"})
+	if !state.IsNotificationQueued("workflow_1") {
+		t.Error("deferred key not reported queued, so it would be deferred again")
+	}
+
+	state.RemoveDeferred([]string{"workflow_1"})
+	if state.IsNotificationQueued("workflow_1") {
+		t.Error("key still reported queued after it left the deferred alerts")
+	}
+}
+
+func TestPutHTTPEntrySavesRefreshes(t *testing.T) {
+	now := time.Now()
+	stored := HTTPEntry{ETag: `"abc"`, Body: []byte("[]"), StoredAt: now.Add(-2 * time.Hour)}
+
+	tests := []struct {
+		name    string
+		entry   HTTPEntry
+		changed bool
+	}{
+		{"same response, new poll interval", HTTPEntry{ETag: `"abc"`, Body: []byte("[]"), StoredAt: stored.StoredAt, PollAfter: now.Add(time.Minute)}, false},
+		{"revalidated within a day", HTTPEntry{ETag: `"abc"`, Body: []byte("[]"), StoredAt: now}, false},
+		{"revalidated a day later", HTTPEntry{ETag: `"abc"`, Body: []byte("[]"), StoredAt: stored.StoredAt.Add(httpRefreshInterval)}, true},
+		{"new ETag", HTTPEntry{ETag: `"def"`, Body: []byte("[]"), StoredAt: now}, true},
+		{"new body", HTTPEntry{ETag: `"abc"`, Body: []byte("[1]"), StoredAt: now}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewState()
+			state.HTTPCache["https://api.github.com/notifications"] = stored
+			state.PutHTTPEntry("https://api.github.com/notifications", tt.entry)
+			if got := state.HTTPCacheChanged(); got != tt.changed {
+				t.Errorf("HTTPCacheChanged() = %v, want %v", got, tt.changed)
+			}
+		})
+	}
+}
+
+func TestRefreshedHTTPEntrySurvivesCleanup(t *testing.T) {
+	// An entry stored 6 days ago and revalidated by every run since: once a
+	// refresh is saved, cleanup keeps it even with a one day window
+	url := "https://api.github.com/notifications"
+	state := NewState()
+	state.HTTPCache[url] = HTTPEntry{ETag: `"abc"`, Body: []byte("[]"), StoredAt: time.Now().Add(-6 * 24 * time.Hour)}
+
+	entry, _ := state.GetHTTPEntry(url)
+	entry.StoredAt = time.Now()
+	state.PutHTTPEntry(url, entry)
+	if !state.HTTPCacheChanged() {
+		t.Fatal("a refresh after 6 days was not marked for saving")
+	}
+
+	data, err := state.encode(nil)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	reloaded, err := decodeState(data)
+	if err != nil {
+		t.Fatalf("decodeState: %v", err)
+	}
+	reloaded.CleanupOldEntries(24 * time.Hour)
+	if _, ok := reloaded.GetHTTPEntry(url); !ok {
+		t.Error("cleanup pruned an entry refreshed within the window")
+	}
+}
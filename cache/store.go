@@ -0,0 +1,36 @@
+package cache
+
+// Store persists the cache state. Save merges in changes another process
+// stored since the state was loaded (see State.Save); Replace overwrites
+// them.
+type Store interface {
+	Load() (*State, error)
+	Save(state *State) error
+	Replace(state *State) error
+	String() string // Where the state is kept, for logs
+}
+
+// FileStore keeps the state in a JSON file (see LoadState and State.Save).
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) Load() (*State, error) {
+	return LoadState(f.path)
+}
+
+func (f *FileStore) Save(state *State) error {
+	return state.Save(f.path)
+}
+
+func (f *FileStore) Replace(state *State) error {
+	return state.Replace(f.path)
+}
+
+func (f *FileStore) String() string {
+	return f.path
+}
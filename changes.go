@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+)
+
+func pullRequestFingerprint(pr github.PullRequest) cache.Fingerprint {
+	return cache.Fingerprint{
+		State:       pr.State,
+		Title:       pr.Title,
+		UpdatedAt:   pr.UpdatedAt,
+		Labels:      labelNames(pr.Labels),
+		ReviewState: pr.ReviewState(),
+		HeadSHA:     pr.HeadSHA(),
+	}
+}
+
+func issueFingerprint(issue github.Issue) cache.Fingerprint {
+	return cache.Fingerprint{
+		State:     issue.State,
+		Title:     issue.Title,
+		UpdatedAt: issue.UpdatedAt,
+		Labels:    labelNames(issue.Labels),
+	}
+}
+
+func labelNames(labels []github.Label) []string {
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// changeReason compares an item with its fingerprint from the last alert and
+// returns why it should be alerted again under the category's policy, or ""
+// if nothing it cares about changed.
+func changeReason(cfg *config.Config, category string, previous, current cache.Fingerprint) string {
+	notifyOn := func(change string) bool {
+		return cfg.NotifyOnChange(category, change)
+	}
+
+	switch {
+	case previous.State == cache.StateGone && notifyOn(config.ChangeReopened):
+		switch category {
+		case config.CategoryReviewRequests:
+			return "review requested again"
+		case config.CategoryStalePRs:
+			return "stale again"
+		}
+		return "reopened"
+	case previous.HeadSHA != "" && current.HeadSHA != "" && previous.HeadSHA != current.HeadSHA && notifyOn(config.ChangeNewCommits):
+		return "new commits pushed"
+	case current.ReviewState != "" && previous.ReviewState != current.ReviewState && notifyOn(config.ChangeReviewState):
+		return "review: " + strings.ToLower(strings.ReplaceAll(current.ReviewState, "_", " "))
+	case previous.Title != "" && previous.Title != current.Title && notifyOn(config.ChangeTitle):
+		return "title changed"
+	case strings.Join(previous.Labels, ",") != strings.Join(current.Labels, ",") && notifyOn(config.ChangeLabels):
+		return "labels changed"
+	case current.UpdatedAt.After(previous.UpdatedAt) && notifyOn(config.ChangeUpdated):
+		return "updated"
+	}
+	return ""
+}
+
+// trackChange looks up the item's fingerprint under key and returns why it
+// should be alerted again (see changeReason). Items alerted before
+// fingerprints existed get one now, so their next change is noticed.
+func trackChange(state *cache.State, cfg *config.Config, category, key string, current cache.Fingerprint) string {
+	previous, ok := state.Fingerprint(key)
+	if !ok {
+		if _, sent := state.SentNotifications[key]; sent {
+			state.SetFingerprint(key, current)
+		}
+		return ""
+	}
+	return changeReason(cfg, category, previous, current)
+}
+
+// listedKeys returns the keys of the review requests, stale PRs and assigned
+// issues in result, for telling which fingerprinted or deferred items dropped
+// out (see cache.State.MarkGone).
+func listedKeys(result *github.CheckResult) map[string]bool {
+	listed := make(map[string]bool, len(result.PRsNeedingReview)+len(result.StaleOwnPRs)+len(result.AssignedIssues))
+	for _, pr := range result.PRsNeedingReview {
+		listed[cache.ReviewRequestKey(pr.RepoFullName(), pr.Number)] = true
+	}
+	for _, pr := range result.StaleOwnPRs {
+		listed[cache.StalePRKey(pr.RepoFullName(), pr.Number)] = true
+	}
+	for _, issue := range result.AssignedIssues {
+		listed[cache.AssignedIssueKey(issue.RepoFullName(), issue.Number)] = true
+	}
+	return listed
+}
+
+// alreadyAlerted reports whether the category's policy holds back another
+// alert about key: it is a one-time alert that was sent, is within its
+// cooldown, or has been repeated max_repeats times. Capped keys are recorded
+// so their count survives cleanup.
+func alreadyAlerted(state *cache.State, cfg *config.Config, category, key string) bool {
+	policy := cfg.PolicyFor(category)
+	cooldown := cfg.CooldownFor(category)
+	if policy.Once {
+		cooldown = 0 // Sent at all
+	}
+	if state.IsNotificationSent(key, cooldown) {
+		return true
+	}
+	if policy.MaxRepeats > 0 && state.AlertCount(key) >= policy.MaxRepeats {
+		state.MarkCapped(key)
+		return true
+	}
+	return false
+}
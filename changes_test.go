@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+)
+
+func TestTrackChangeListedAgain(t *testing.T) {
+	tests := []struct {
+		category string
+		prefix   string
+		key      string
+		want     string
+	}{
+		{config.CategoryReviewRequests, cache.ReviewRequestPrefix, cache.ReviewRequestKey("acme/widgets", 1), "review requested again"},
+		{config.CategoryStalePRs, cache.StalePRPrefix, cache.StalePRKey("acme/widgets", 1), "stale again"},
+		{config.CategoryAssignedIssues, cache.AssignedIssuePrefix, cache.AssignedIssueKey("acme/widgets", 1), "reopened"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			cfg := &config.Config{Policies: map[string]config.Policy{
+				tt.category: {NotifyOn: []string{config.ChangeReopened}},
+			}}
+			state := cache.NewState()
+			fingerprint := cache.Fingerprint{Title: "Add widgets"}
+			state.SetFingerprint(tt.key, fingerprint)
+
+			if change := trackChange(state, cfg, tt.category, tt.key, fingerprint); change != "" {
+				t.Fatalf("unchanged item: change = %q, want none", change)
+			}
+			// A check whose complete list no longer has the item
+			state.MarkGone(tt.prefix, map[string]bool{})
+			if change := trackChange(state, cfg, tt.category, tt.key, fingerprint); change != tt.want {
+				t.Errorf("listed again: change = %q, want %q", change, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/notify"
+)
+
+// command is a gh-notify subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"check", "Check for new alerts once and notify about them", runCheckCommand},
+		{"digest", "Send the morning, evening or weekly digest", runDigestCommand},
+		{"daemon", "Run continuously, scheduling checks and digests itself", runDaemonCommand},
+		{"serve", "Receive GitHub webhooks and alert about them as they arrive", runServeCommand},
+		{"cache", "Show, reset or prune the notification cache", runCacheCommand},
+		{"test-webhook", "Send a test message to every configured destination", runTestWebhookCommand},
+		{"config", "Validate the configuration", runConfigCommand},
+	}
+}
+
+const (
+	configFlagUsage = "Config file (default: $GH_NOTIFY_CONFIG or ./gh-notify.yaml)"
+	dryRunUsage     = "Print the rendered messages instead of sending them and leave the cache untouched (or set DRY_RUN)"
+	logFormatUsage  = "Log format, text or json (default: $LOG_FORMAT or text); LOG_LEVEL sets the level"
+)
+
+// runCLI dispatches to a subcommand and returns the exit code. Without one it
+// falls back to CHECK_TYPE/SCHEDULE_TYPE so existing workflows keep working.
+func runCLI(args []string) int {
+	flags := flag.NewFlagSet("gh-notify", flag.ContinueOnError)
+	daemon := flags.Bool("daemon", false, "Same as the daemon command")
+	configPath := flags.String("config", "", configFlagUsage)
+	flags.StringVar(&logFormat, "log-format", "", logFormatUsage)
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintln(out, "usage: gh-notify [-config file] [-log-format text|json] <command> [flags]")
+		fmt.Fprintln(out, "\nCommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(out, "  %-13s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintln(out, "\nRun \"gh-notify <command> -h\" for the command's flags.")
+		fmt.Fprintln(out, "Without a command, CHECK_TYPE (instant, morning, evening, weekly, digest,")
+		fmt.Fprintln(out, "both or auto with SCHEDULE_TYPE) selects what to run.")
+		fmt.Fprintln(out, "\nFlags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := setupLogging(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// A -config given before the command applies to it as well
+	if *configPath != "" {
+		os.Setenv("GH_NOTIFY_CONFIG", *configPath)
+	}
+
+	if *daemon {
+		return runDaemonCommand(flags.Args())
+	}
+	if flags.NArg() == 0 {
+		return runLegacy(flags)
+	}
+
+	name := flags.Arg(0)
+	if name == "help" {
+		flags.Usage()
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(flags.Args()[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "gh-notify: unknown command %q\n\n", name)
+	flags.Usage()
+	return 2
+}
+
+// newFlagSet creates the flag set for a subcommand, with the shared -config
+// and -log-format flags.
+func newFlagSet(name, usage, description string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := flags.String("config", "", configFlagUsage)
+	flags.StringVar(&logFormat, "log-format", logFormat, logFormatUsage)
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "usage: gh-notify %s\n\n%s\n\nFlags:\n", usage, description)
+		flags.PrintDefaults()
+	}
+	return flags, configPath
+}
+
+// parseFlags parses a subcommand's flags and applies -log-format.
+func parseFlags(flags *flag.FlagSet, args []string) {
+	flags.Parse(args)
+	if err := setupLogging(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// configFile returns the -config value, or the default config file.
+func configFile(path string) string {
+	if path != "" {
+		return path
+	}
+	return config.Path()
+}
+
+// runLegacy maps CHECK_TYPE and SCHEDULE_TYPE onto a single run, as the
+// binary did before it had subcommands.
+func runLegacy(flags *flag.FlagSet) int {
+	checkType := os.Getenv("CHECK_TYPE")
+	scheduleType := os.Getenv("SCHEDULE_TYPE")
+	if checkType == "" {
+		flags.Usage()
+		return 2
+	}
+
+	// Auto-detect based on schedule trigger
+	if checkType == "auto" {
+		switch scheduleType {
+		case "15 23 * * *", "0 0 * * *": // ~7 AM Vietnam time - Morning digest
+			checkType = "morning"
+		case "0 14 * * *": // 9 PM Vietnam time - Evening digest
+			checkType = "evening"
+		default: // Every 2 hours - Instant checks
+			checkType = "instant"
+		}
+	}
+
+	slog.Debug("determined check type", "check_type", checkType, "schedule", scheduleType)
+
+	a, err := newApp(config.Path(), false)
+	if err != nil {
+		slog.Error("failed to start", "error", err)
+		return 1
+	}
+	a.run(checkType)
+
+	slog.Info("GitHub Notifier completed")
+	return 0
+}
+
+// runCheckCommand handles "gh-notify check".
+func runCheckCommand(args []string) int {
+	flags, configPath := newFlagSet("check", "check [-dry-run] [-config file]",
+		"Checks review requests, stale PRs, assigned issues, notifications, invitations\nand failed workflows, and sends an alert for anything not notified yet.")
+	dryRun := flags.Bool("dry-run", false, dryRunUsage)
+	parseFlags(flags, args)
+
+	a, err := newApp(configFile(*configPath), *dryRun)
+	if err != nil {
+		slog.Error("failed to start", "error", err)
+		return 1
+	}
+	a.run("instant")
+
+	slog.Info("GitHub Notifier completed")
+	return 0
+}
+
+// runDigestCommand handles "gh-notify digest [--morning|--evening|--weekly]".
+func runDigestCommand(args []string) int {
+	flags, configPath := newFlagSet("digest", "digest [--morning|--evening|--weekly] [-dry-run] [-config file]",
+		"Sends a digest. Without a flag, the morning or evening digest is picked from\nthe current time in the configured timezone.")
+	morning := flags.Bool("morning", false, "Send the morning briefing (what needs attention)")
+	evening := flags.Bool("evening", false, "Send the evening summary (today's accomplishments)")
+	weekly := flags.Bool("weekly", false, "Send the weekly summary (the last 7 days' accomplishments)")
+	dryRun := flags.Bool("dry-run", false, dryRunUsage)
+	parseFlags(flags, args)
+
+	kind := "digest"
+	selected := 0
+	for name, set := range map[string]bool{"morning": *morning, "evening": *evening, "weekly": *weekly} {
+		if set {
+			kind = name
+			selected++
+		}
+	}
+	if selected > 1 {
+		fmt.Fprintln(os.Stderr, "digest: choose only one of --morning, --evening and --weekly")
+		return 2
+	}
+
+	a, err := newApp(configFile(*configPath), *dryRun)
+	if err != nil {
+		slog.Error("failed to start", "error", err)
+		return 1
+	}
+	a.run(kind)
+
+	slog.Info("GitHub Notifier completed")
+	return 0
+}
+
+// runDaemonCommand handles "gh-notify daemon" (and the legacy -daemon flag).
+func runDaemonCommand(args []string) int {
+	flags, configPath := newFlagSet("daemon", "daemon [-dry-run] [-config file]",
+		"Runs instant checks every CHECK_INTERVAL and the digests at DAILY_REPORT_TIME\nand EVENING_REPORT_TIME until interrupted.")
+	dryRun := flags.Bool("dry-run", false, dryRunUsage)
+	parseFlags(flags, args)
+
+	a, err := newApp(configFile(*configPath), *dryRun)
+	if err != nil {
+		slog.Error("failed to start", "error", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := runDaemon(ctx, a.githubClient, a.notifier, a.store, a.state, a.username, a.cfg, a.rules); err != nil {
+		slog.Error("daemon stopped with error", "error", err)
+		return 1
+	}
+	slog.Info("daemon stopped")
+	return 0
+}
+
+// runServeCommand handles "gh-notify serve".
+func runServeCommand(args []string) int {
+	flags, configPath := newFlagSet("serve", "serve [-addr host:port] [-dry-run] [-config file]",
+		"Receives GitHub webhook deliveries on /webhook, verifies their signature with\nGITHUB_WEBHOOK_SECRET and alerts about them straight away.")
+	addr := flags.String("addr", "", "Listen address (default: $SERVE_ADDR or :8080)")
+	dryRun := flags.Bool("dry-run", false, dryRunUsage)
+	parseFlags(flags, args)
+
+	a, err := newApp(configFile(*configPath), *dryRun)
+	if err != nil {
+		slog.Error("failed to start", "error", err)
+		return 1
+	}
+	if *addr == "" {
+		*addr = a.cfg.ServeAddr
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := runServe(ctx, a, *addr); err != nil {
+		slog.Error("webhook server stopped with error", "error", err)
+		return 1
+	}
+	slog.Info("webhook server stopped")
+	return 0
+}
+
+// runCacheCommand handles "gh-notify cache show|reset|prune".
+func runCacheCommand(args []string) int {
+	const usage = "usage: gh-notify cache show|reset|prune [flags]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	var flags *flag.FlagSet
+	var configPath *string
+	var httpOnly *bool
+	var maxAge *time.Duration
+	switch args[0] {
+	case "show":
+		flags, configPath = newFlagSet("cache show", "cache show [-config file]",
+			"Prints the cache's timestamps and every sent-notification key with its age.")
+	case "reset":
+		flags, configPath = newFlagSet("cache reset", "cache reset [-http] [-config file]",
+			"Clears the cache so every current alert is sent again on the next check.")
+		httpOnly = flags.Bool("http", false, "Only clear the cached GitHub API responses")
+	case "prune":
+		flags, configPath = newFlagSet("cache prune", "cache prune [-max-age duration] [-config file]",
+			"Removes sent-notification keys and API responses older than -max-age.")
+		maxAge = flags.Duration("max-age", 7*24*time.Hour, "Remove entries older than this")
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	parseFlags(flags, args[1:])
+
+	// The cache commands don't need a token or destinations, so skip validation
+	cfg, err := config.Read(configFile(*configPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	store, err := newStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	state, err := store.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "show":
+		showCache(store, state)
+		return 0
+
+	case "reset":
+		if *httpOnly {
+			fmt.Printf("Removing %d cached API responses\n", len(state.HTTPCache))
+			state.HTTPCache = make(map[string]cache.HTTPEntry)
+		} else {
+			fmt.Printf("Removing %d notification keys and %d cached API responses\n",
+				len(state.SentNotifications), len(state.HTTPCache))
+			state = cache.NewState()
+		}
+
+	case "prune":
+		notifications, responses := len(state.SentNotifications), len(state.HTTPCache)
+		state.CleanupOldEntries(*maxAge)
+		fmt.Printf("Removed %d notification keys and %d cached API responses older than %v\n",
+			notifications-len(state.SentNotifications), responses-len(state.HTTPCache), *maxAge)
+	}
+
+	// A full reset must not merge back what is stored
+	save := store.Save
+	if args[0] == "reset" && !*httpOnly {
+		save = store.Replace
+	}
+	if err := save(state); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Cache %s saved\n", store)
+	return 0
+}
+
+// showCache prints a summary of the cache state and its notification keys.
+func showCache(store cache.Store, state *cache.State) {
+	fmt.Printf("Cache: %s (schema version %d)\n", store, state.SchemaVersion)
+	fmt.Printf("Last check: %s\n", state.LastCheck.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Last daily report: %s\n", state.LastDailyReport.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Cached API responses: %d\n", len(state.HTTPCache))
+	fmt.Printf("Undelivered messages: %d\n", len(state.Outbox))
+	for _, entry := range state.Outbox {
+		fmt.Printf("  - %s: destination %d, %d attempts, %d keys, last error: %s\n",
+			entry.ID, entry.Destination+1, entry.Attempts, len(entry.Keys), entry.LastError)
+	}
+	fmt.Printf("Deferred alerts: %d\n", len(state.Deferred))
+	for _, alert := range state.DeferredAlerts() {
+		fmt.Printf("  - %s: deferred %.2f hours ago\n", alert.Key, time.Since(alert.DeferredAt).Hours())
+	}
+	fmt.Printf("Sent notifications: %d\n", len(state.SentNotifications))
+
+	keys := make([]string, 0, len(state.SentNotifications))
+	for key := range state.SentNotifications {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("  - %s: %.2f hours ago\n", key, time.Since(state.SentNotifications[key]).Hours())
+	}
+}
+
+// runTestWebhookCommand handles "gh-notify test-webhook".
+func runTestWebhookCommand(args []string) int {
+	flags, configPath := newFlagSet("test-webhook", "test-webhook [-message text] [-config file]",
+		"Sends a test message to each configured destination and reports the result.")
+	text := flags.String("message", "✅ GitHub Notifier test message – this destination is set up correctly.", "Text of the test message")
+	parseFlags(flags, args)
+
+	cfg, err := config.Read(configFile(*configPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(cfg.Destinations) == 0 {
+		fmt.Fprintln(os.Stderr, "No destinations configured (set DISCORD_WEBHOOK / WEBHOOK_URL or destinations in the config file)")
+		return 1
+	}
+
+	failed := 0
+	for _, destination := range cfg.Destinations {
+		notifier, err := notify.NewNotifier(destination.Type, destination.URL)
+		if err == nil {
+			err = notifier.SendMessage(notify.TextMessage(*text))
+		}
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", destination.Type, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s: test message sent\n", destination.Type)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runConfigCommand handles "gh-notify config validate [-file path]".
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: gh-notify config validate [-file gh-notify.yaml]")
+		return 2
+	}
+
+	flags, configPath := newFlagSet("config validate", "config validate [-file gh-notify.yaml]",
+		"Loads and validates the configuration without running anything.")
+	file := flags.String("file", "", "Config file to validate (same as -config)")
+	parseFlags(flags, args[1:])
+
+	path := *file
+	if path == "" {
+		path = configFile(*configPath)
+	}
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	source := cfg.File
+	if source == "" {
+		source = "environment"
+	}
+	fmt.Printf("✅ Configuration from %s is valid\n", source)
+	fmt.Printf("  Destinations: %d\n", len(cfg.Destinations))
+	for _, destination := range cfg.Destinations {
+		fmt.Printf("    - %s\n", destination.Type)
+	}
+	fmt.Printf("  Timezone: %s, check interval: %v, digests at %s and %s\n",
+		cfg.Timezone, cfg.CheckInterval, cfg.DailyReportTime, cfg.EveningReportTime)
+	if len(cfg.QuietHours.Windows) > 0 {
+		fmt.Printf("  Quiet hours: %d windows, urgent: %s\n", len(cfg.QuietHours.Windows), strings.Join(cfg.QuietHours.Urgent, ", "))
+	}
+	return 0
+}
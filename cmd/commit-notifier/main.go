@@ -2,64 +2,241 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/wilfierd/gh-notify/config"
 	"github.com/wilfierd/gh-notify/github"
+	"github.com/wilfierd/gh-notify/internal/logging"
 	"github.com/wilfierd/gh-notify/notify"
 )
 
 func main() {
 	// Load .env file if it exists
 	_ = godotenv.Load()
-	
-	// Get environment variables from GitHub Actions
-	sha := os.Getenv("GITHUB_SHA")
-	commitMessage := os.Getenv("COMMIT_MESSAGE")
-	author := os.Getenv("COMMIT_AUTHOR")
-	repoName := os.Getenv("GITHUB_REPOSITORY")
-	commitURL := os.Getenv("COMMIT_URL")
-	repoURL := os.Getenv("REPO_URL")
-	discordWebhook := os.Getenv("DISCORD_WEBHOOK")
-	githubToken := os.Getenv("GH_TOKEN")
 
-	if discordWebhook == "" {
-		log.Fatal("DISCORD_WEBHOOK environment variable is required")
+	// LOG_LEVEL and LOG_FORMAT work as they do for gh-notify
+	if err := logging.Setup(""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// The action's inputs arrive as environment variables (see action.yml),
+	// read by config like gh-notify.yaml's settings
+	cfg, err := config.Read(config.Path())
+	if err == nil {
+		err = cfg.ValidateAnnounce()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
 	// Create Discord notifier
-	discordNotifier := notify.NewDiscordNotifier(discordWebhook)
-
-	// Get avatar URL if GitHub token is available
-	var avatarURL string
-	fmt.Printf("DEBUG: githubToken exists: %v, author: '%s'\n", githubToken != "", author)
-
-	if githubToken != "" && author != "" {
-		client := github.NewClient(githubToken)
-		fmt.Printf("DEBUG: Fetching user info for: %s\n", author)
-		if user, err := client.GetUserByUsername(author); err == nil {
-			avatarURL = user.AvatarURL
-			fmt.Printf("DEBUG: Successfully got avatar URL: %s\n", avatarURL)
-		} else {
-			fmt.Printf("DEBUG: Failed to get user info: %v\n", err)
-		}
-	} else {
-		fmt.Printf("DEBUG: Skipping avatar fetch - missing token or author\n")
+	discordNotifier := notify.NewDiscordNotifier(cfg.DiscordWebhook())
+
+	// Pull requests, releases, tags and failed workflow runs get their own
+	// cards; everything else announces the pushed commits
+	eventName := os.Getenv("GITHUB_EVENT_NAME")
+	announcement, err := readAnnouncement(eventName, os.Getenv("GITHUB_EVENT_PATH"), &cfg.Announce)
+	if err != nil {
+		fatal("failed to read event", "event", eventName, "error", err)
+	}
+	if announcement == nil {
+		slog.Info("nothing to announce", "event", eventName)
+		return
 	}
 
-	fmt.Printf("DEBUG: Final avatarURL: '%s'\n", avatarURL)
+	avatarURL := resolveAvatar(cfg, announcement.sender)
+	slog.Debug("avatar resolved", "avatar_url", avatarURL)
 
-	// Format commit notification
-	discordMessage, err := notify.FormatCommitNotification(sha, commitMessage, author, repoName, commitURL, repoURL, avatarURL)
+	discordMessage, err := announcement.format(avatarURL)
 	if err != nil {
-		log.Fatalf("Failed to format commit notification: %v", err)
+		fatal("failed to format notification", "error", err)
 	}
 
 	// Send notification
 	if err := discordNotifier.SendMessage(discordMessage); err != nil {
-		log.Fatalf("Failed to send commit notification: %v", err)
+		fatal("failed to send notification", "error", err)
 	}
 
-	fmt.Println("Commit notification sent successfully!")
+	slog.Info("notification sent", "event", eventName)
+}
+
+// fatal logs an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// announcement is an event worth a notification. format builds the message
+// once the sender's avatar is known.
+type announcement struct {
+	sender github.User
+	format func(avatarURL string) (*notify.Message, error)
+}
+
+// readAnnouncement reads the payload of the event that triggered the run. It
+// returns nil for events that aren't announced, such as closed but unmerged
+// pull requests, successful workflow runs or triggers without a card.
+func readAnnouncement(eventName, path string, settings *config.Announce) (*announcement, error) {
+	title := settings.Title
+	switch eventName {
+	case "pull_request", "pull_request_target":
+		var event github.PullRequestEvent
+		if err := github.ReadEvent(path, &event); err != nil {
+			return nil, err
+		}
+		if event.Action != "opened" && (event.Action != "closed" || !event.PullRequest.Merged) {
+			return nil, nil
+		}
+		return &announcement{sender: event.Sender, format: func(avatarURL string) (*notify.Message, error) {
+			return notify.FormatPullRequestNotification(&event, title, avatarURL)
+		}}, nil
+
+	case "release":
+		var event github.ReleaseEvent
+		if err := github.ReadEvent(path, &event); err != nil {
+			return nil, err
+		}
+		if event.Action != "published" || event.Release.Draft {
+			return nil, nil
+		}
+		return &announcement{sender: event.Sender, format: func(avatarURL string) (*notify.Message, error) {
+			return notify.FormatReleaseNotification(&event, title, avatarURL)
+		}}, nil
+
+	case "create":
+		var event github.CreateEvent
+		if err := github.ReadEvent(path, &event); err != nil {
+			return nil, err
+		}
+		if event.RefType != "tag" {
+			return nil, nil
+		}
+		return tagAnnouncement(&event, title), nil
+
+	case "workflow_run":
+		var event github.WorkflowRunEvent
+		if err := github.ReadEvent(path, &event); err != nil {
+			return nil, err
+		}
+		if event.Action != "completed" || !event.WorkflowRun.Failed() {
+			return nil, nil
+		}
+		return &announcement{sender: event.Sender, format: func(avatarURL string) (*notify.Message, error) {
+			return notify.FormatWorkflowRunNotification(&event, title, avatarURL)
+		}}, nil
+
+	case "push", "workflow_dispatch", "":
+		return pushAnnouncement(eventName, path, settings), nil
+	}
+
+	// Other triggers, such as schedule or issues, have nothing to announce
+	return nil, nil
+}
+
+// pushAnnouncement announces a push. The full push payload lists every
+// commit; without it (manual runs, local testing) it falls back to the head
+// commit from the action's inputs.
+func pushAnnouncement(eventName, path string, settings *config.Announce) *announcement {
+	title := settings.Title
+	event := readPushEvent(eventName, path)
+	if event == nil {
+		event = pushEventFromCommit(settings.Commit)
+	}
+	if event.IsTag() && event.Created && !event.Deleted {
+		return tagAnnouncement(&github.CreateEvent{
+			Ref:        event.RefName(),
+			RefType:    "tag",
+			Sender:     event.Sender,
+			Repository: event.Repository,
+		}, title)
+	}
+	return &announcement{sender: event.Sender, format: func(avatarURL string) (*notify.Message, error) {
+		return notify.FormatPushNotification(event, title, avatarURL)
+	}}
+}
+
+func tagAnnouncement(event *github.CreateEvent, title string) *announcement {
+	return &announcement{sender: event.Sender, format: func(avatarURL string) (*notify.Message, error) {
+		return notify.FormatTagNotification(event, title, avatarURL)
+	}}
+}
+
+// readPushEvent reads the push payload GitHub Actions saves at
+// GITHUB_EVENT_PATH, or returns nil if the run wasn't triggered by a push.
+func readPushEvent(eventName, path string) *github.PushEvent {
+	if path == "" || (eventName != "" && eventName != "push") {
+		return nil
+	}
+
+	var event github.PushEvent
+	if err := github.ReadEvent(path, &event); err != nil {
+		slog.Warn("ignoring unreadable push payload", "path", path, "error", err)
+		return nil
+	}
+	if event.Ref == "" {
+		return nil
+	}
+	return &event
+}
+
+// pushEventFromCommit builds a single-commit push from the head commit the
+// action passes in.
+func pushEventFromCommit(commit config.Commit) *github.PushEvent {
+	actor := commit.Actor
+	if actor == "" {
+		actor = commit.Author
+	}
+
+	event := &github.PushEvent{
+		Sender: github.User{Login: actor},
+		Repository: github.EventRepository{
+			Repo:    github.Repo{FullName: commit.Repository},
+			HTMLURL: commit.RepoURL,
+		},
+	}
+	if commit.Branch != "" {
+		event.Ref = "refs/heads/" + commit.Branch
+	}
+	if commit.SHA != "" {
+		event.Commits = []github.PushCommit{{
+			ID:      commit.SHA,
+			Message: commit.Message,
+			URL:     commit.URL,
+			Author:  github.PushAuthor{Name: commit.Author},
+		}}
+	}
+	return event
+}
+
+// resolveAvatar returns the sender's avatar, from the payload or else the API,
+// or "" when the action's include-avatar input is off.
+func resolveAvatar(cfg *config.Config, sender github.User) string {
+	if !cfg.Announce.IncludeAvatar {
+		return ""
+	}
+	if sender.AvatarURL != "" {
+		return sender.AvatarURL
+	}
+	return fetchAvatarURL(cfg.GitHubToken, sender.Login)
+}
+
+// fetchAvatarURL looks up the user's avatar, if a GitHub token is available.
+func fetchAvatarURL(githubToken, login string) string {
+	if githubToken == "" || login == "" {
+		slog.Debug("skipping avatar lookup", "has_token", githubToken != "", "login", login)
+		return ""
+	}
+
+	client := github.NewClient(githubToken)
+	user, err := client.GetUserByUsername(login)
+	if err != nil {
+		slog.Warn("failed to look up avatar", "login", login, "error", err)
+		return ""
+	}
+	slog.Debug("avatar looked up", "login", login, "avatar_url", user.AvatarURL)
+	return user.AvatarURL
 }
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+	"github.com/wilfierd/gh-notify/notify"
+)
+
+// render formats an announcement and returns its single card.
+func render(t *testing.T, a *announcement, avatarURL string) notify.Card {
+	t.Helper()
+	message, err := a.format(avatarURL)
+	if err != nil {
+		t.Fatalf("format: %v", err)
+	}
+	if len(message.Cards) != 1 {
+		t.Fatalf("got %d cards, want 1", len(message.Cards))
+	}
+	return message.Cards[0]
+}
+
+// cardText joins everything a reader sees on the card.
+func cardText(card notify.Card) string {
+	parts := []string{card.Title, card.Description}
+	for _, field := range card.Fields {
+		parts = append(parts, field.Name, field.Value)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestReadAnnouncement(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		payload string   // File in testdata
+		skipped bool     // No announcement expected
+		title   string   // Expected card title
+		color   int      // Expected card color
+		want    []string // Substrings of the card text
+		notWant []string
+	}{
+		{
+			name:    "opened pull request",
+			event:   "pull_request",
+			payload: "pull_request_opened.json",
+			title:   "🔀 Pull Request Opened",
+			color:   notify.ColorBlue,
+			want:    []string{"**alice** opened [#42 Add dark mode](https://github.com/acme/app/pull/42)", "Adds a dark theme.", "`dark-mode` → `main`", "3 commits, 5 files, +120 −8"},
+		},
+		{
+			name:    "merged pull request",
+			event:   "pull_request_target",
+			payload: "pull_request_merged.json",
+			title:   "✅ Pull Request Merged",
+			color:   notify.ColorPurple,
+			want:    []string{"**bob** merged [#42 Add dark mode]"},
+			notWant: []string{"Adds a dark theme."},
+		},
+		{
+			name:    "closed unmerged pull request",
+			event:   "pull_request",
+			payload: "pull_request_closed.json",
+			skipped: true,
+		},
+		{
+			name:    "published release with notes",
+			event:   "release",
+			payload: "release_published.json",
+			title:   "🚀 New Release: v1.2.0 Dark mode",
+			color:   notify.ColorGreen,
+			want:    []string{"**carol** published [v1.2.0 Dark mode](https://github.com/acme/app/releases/tag/v1.2.0)", "- Dark mode by @alice in #42", "`v1.2.0`"},
+			notWant: []string{"pre-release"},
+		},
+		{
+			name:    "draft release",
+			event:   "release",
+			payload: "release_draft.json",
+			skipped: true,
+		},
+		{
+			name:    "prerelease",
+			event:   "release",
+			payload: "release_prerelease.json",
+			title:   "🚀 New Release: v1.3.0-rc.1",
+			color:   notify.ColorGreen,
+			want:    []string{"[v1.3.0-rc.1](https://github.com/acme/app/releases/tag/v1.2.0) (pre-release)"},
+		},
+		{
+			name:    "created tag",
+			event:   "create",
+			payload: "create_tag.json",
+			title:   "🏷️ New Tag: v1.2.0",
+			color:   notify.ColorBlue,
+			want:    []string{"**alice** created tag [`v1.2.0`](https://github.com/acme/app/tree/v1.2.0)", "[acme/app](https://github.com/acme/app)"},
+		},
+		{
+			name:    "created branch",
+			event:   "create",
+			payload: "create_branch.json",
+			skipped: true,
+		},
+		{
+			name:    "failed workflow run",
+			event:   "workflow_run",
+			payload: "workflow_run_failure.json",
+			title:   "❌ Workflow Failed: CI",
+			color:   notify.ColorRed,
+			want:    []string{"[CI #17](https://github.com/acme/app/actions/runs/987) failure on **Add dark mode**", "`main`", "[`3333333`](https://github.com/acme/app/commit/3333333333333333333333333333333333333333)", "`push` from alice"},
+		},
+		{
+			name:    "successful workflow run",
+			event:   "workflow_run",
+			payload: "workflow_run_success.json",
+			skipped: true,
+		},
+		{
+			name:    "unknown event",
+			event:   "issues",
+			payload: "pull_request_opened.json",
+			skipped: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := readAnnouncement(tt.event, filepath.Join("testdata", tt.payload), &config.Announce{})
+			if err != nil {
+				t.Fatalf("readAnnouncement: %v", err)
+			}
+			if tt.skipped {
+				if a != nil {
+					t.Fatalf("got an announcement, want %s skipped", tt.event)
+				}
+				return
+			}
+			if a == nil {
+				t.Fatal("got no announcement")
+			}
+			if a.sender.Login != "alice" {
+				t.Errorf("sender = %q, want alice", a.sender.Login)
+			}
+
+			card := render(t, a, "")
+			if card.Title != tt.title {
+				t.Errorf("title = %q, want %q", card.Title, tt.title)
+			}
+			if card.Color != tt.color {
+				t.Errorf("color = %#x, want %#x", card.Color, tt.color)
+			}
+			if card.Author != nil {
+				t.Errorf("author = %+v, want none without an avatar", card.Author)
+			}
+			text := cardText(card)
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("card does not contain %q:\n%s", want, text)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(text, notWant) {
+					t.Errorf("card contains %q:\n%s", notWant, text)
+				}
+			}
+		})
+	}
+}
+
+func TestReadAnnouncementTitleAndAvatar(t *testing.T) {
+	a, err := readAnnouncement("release", filepath.Join("testdata", "release_published.json"), &config.Announce{Title: "Shipped!"})
+	if err != nil {
+		t.Fatalf("readAnnouncement: %v", err)
+	}
+	card := render(t, a, "https://avatars.githubusercontent.com/u/3")
+	if card.Title != "Shipped!" {
+		t.Errorf("title = %q, want the configured title", card.Title)
+	}
+	if card.Author == nil || card.Author.Name != "carol" || card.Author.IconURL != "https://avatars.githubusercontent.com/u/3" {
+		t.Errorf("author = %+v, want carol with the avatar", card.Author)
+	}
+}
+
+func TestReadAnnouncementErrors(t *testing.T) {
+	if _, err := readAnnouncement("release", filepath.Join("testdata", "missing.json"), &config.Announce{}); err == nil {
+		t.Error("missing payload: got no error")
+	}
+}
+
+func TestPushAnnouncement(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		title   string
+		color   int
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "12 commits",
+			payload: "push.json",
+			title:   "📝 12 New Commits Pushed",
+			color:   notify.ColorOrange, // Includes a breaking change
+			want: []string{
+				"**alice** pushed 12 commits to `dark-mode`",
+				"💥 Includes breaking changes",
+				"[`0101010`](https://github.com/acme/app/commit/0101010101010101010101010101010101010101)",
+				"[`1212121`](https://github.com/acme/app/commit/1212121212121212121212121212121212121212)",
+				"[View changes](https://github.com/acme/app/compare/aaaaaaaaaaaa...121212121212)",
+				"🌿 Branch", "`dark-mode`",
+			},
+		},
+		{
+			name:    "forced push",
+			payload: "push_forced.json",
+			title:   "📝 New Commit Pushed",
+			color:   notify.ColorOrange,
+			want:    []string{"⚠️ **alice** force-pushed 1 commit to `dark-mode`"},
+		},
+		{
+			name:    "created branch",
+			payload: "push_created.json",
+			title:   "📝 New Commit Pushed",
+			color:   notify.ColorBlue,
+			want:    []string{"**alice** created branch `feature/login`"},
+			notWant: []string{"🚀 Commits"},
+		},
+		{
+			name:    "deleted branch",
+			payload: "push_deleted.json",
+			title:   "📝 New Commit Pushed",
+			color:   notify.ColorRed,
+			want:    []string{"**alice** deleted branch `old-branch`"},
+			notWant: []string{"View changes"},
+		},
+		{
+			name:    "created tag",
+			payload: "push_tag.json",
+			title:   "🏷️ New Tag: v1.2.0",
+			color:   notify.ColorBlue,
+			want:    []string{"**alice** created tag [`v1.2.0`](https://github.com/acme/app/tree/v1.2.0)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := readAnnouncement("push", filepath.Join("testdata", tt.payload), &config.Announce{})
+			if err != nil {
+				t.Fatalf("readAnnouncement: %v", err)
+			}
+			card := render(t, a, "")
+			if card.Title != tt.title {
+				t.Errorf("title = %q, want %q", card.Title, tt.title)
+			}
+			if card.Color != tt.color {
+				t.Errorf("color = %#x, want %#x", card.Color, tt.color)
+			}
+			text := cardText(card)
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("card does not contain %q:\n%s", want, text)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(text, notWant) {
+					t.Errorf("card contains %q:\n%s", notWant, text)
+				}
+			}
+		})
+	}
+}
+
+func TestPushAnnouncementListsEveryCommit(t *testing.T) {
+	a, err := readAnnouncement("push", filepath.Join("testdata", "push.json"), &config.Announce{})
+	if err != nil {
+		t.Fatalf("readAnnouncement: %v", err)
+	}
+	card := render(t, a, "")
+	if card.URL != "https://github.com/acme/app/compare/aaaaaaaaaaaa...121212121212" {
+		t.Errorf("card URL = %q, want the compare URL", card.URL)
+	}
+
+	var commits notify.Field
+	for _, field := range card.Fields {
+		if field.Name == "🚀 Commits" {
+			commits = field
+		}
+	}
+	lines := strings.Split(commits.Value, "\n")
+	if len(lines) != 12 {
+		t.Fatalf("got %d commit lines, want 12:\n%s", len(lines), commits.Value)
+	}
+	for i, line := range lines {
+		sha := strings.Repeat(fmt.Sprintf("%02d", i+1), 20)[:7]
+		if !strings.Contains(line, "`"+sha+"`") || !strings.HasSuffix(line, "— alice") {
+			t.Errorf("line %d = %q, want commit %s by alice", i, line, sha)
+		}
+	}
+	if commits.MoreURL != card.URL {
+		t.Errorf("commits link to %q when cut, want the compare URL", commits.MoreURL)
+	}
+}
+
+func TestPushAnnouncementFallback(t *testing.T) {
+	// Without GITHUB_EVENT_PATH, as in workflow_dispatch runs, the head
+	// commit comes from the action's inputs
+	settings := &config.Announce{
+		Title: "Deployed",
+		Commit: config.Commit{
+			SHA:        "abcdef0123456789abcdef0123456789abcdef01",
+			Message:    "fix: handle empty config\n\nLonger explanation.",
+			Author:     "Alice Smith",
+			URL:        "https://github.com/acme/app/commit/abcdef0123456789abcdef0123456789abcdef01",
+			Branch:     "release",
+			Repository: "acme/app",
+			RepoURL:    "https://github.com/acme/app",
+			Actor:      "alice",
+		},
+	}
+	for _, event := range []string{"workflow_dispatch", "push", ""} {
+		a, err := readAnnouncement(event, "", settings)
+		if err != nil {
+			t.Fatalf("%q: readAnnouncement: %v", event, err)
+		}
+		if a.sender.Login != "alice" {
+			t.Errorf("%q: sender = %q, want the actor", event, a.sender.Login)
+		}
+		card := render(t, a, "")
+		if card.Title != "Deployed" {
+			t.Errorf("%q: title = %q, want the configured title", event, card.Title)
+		}
+		text := cardText(card)
+		for _, want := range []string{
+			"**alice** pushed 1 commit to `release`",
+			"[`abcdef0`](https://github.com/acme/app/commit/abcdef0123456789abcdef0123456789abcdef01)",
+			"handle empty config",
+			"— Alice Smith",
+			"[acme/app](https://github.com/acme/app)",
+		} {
+			if !strings.Contains(text, want) {
+				t.Errorf("%q: card does not contain %q:\n%s", event, want, text)
+			}
+		}
+		if strings.Contains(text, "Longer explanation") {
+			t.Errorf("%q: card shows the commit body:\n%s", event, text)
+		}
+	}
+
+	// A payload from another event isn't read as a push
+	a, err := readAnnouncement("workflow_dispatch", filepath.Join("testdata", "push.json"), settings)
+	if err != nil {
+		t.Fatalf("readAnnouncement: %v", err)
+	}
+	if text := cardText(render(t, a, "")); !strings.Contains(text, "1 commit") {
+		t.Errorf("workflow_dispatch used the push payload:\n%s", text)
+	}
+}
+
+func TestResolveAvatar(t *testing.T) {
+	sender := github.User{Login: "alice", AvatarURL: "https://avatars.githubusercontent.com/u/1"}
+	cfg := &config.Config{Announce: config.Announce{IncludeAvatar: true}}
+	if got := resolveAvatar(cfg, sender); got != sender.AvatarURL {
+		t.Errorf("avatar = %q, want the payload's", got)
+	}
+	// Without a token there is no lookup
+	if got := resolveAvatar(cfg, github.User{Login: "alice"}); got != "" {
+		t.Errorf("avatar without token = %q, want none", got)
+	}
+	cfg.Announce.IncludeAvatar = false
+	if got := resolveAvatar(cfg, sender); got != "" {
+		t.Errorf("avatar with include-avatar off = %q, want none", got)
+	}
+}
@@ -1,36 +1,425 @@
 package config
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Alert categories used by cooldowns, category toggles and filters
+const (
+	CategoryReviewRequests = "review_requests"
+	CategoryStalePRs       = "stale_prs"
+	CategoryAssignedIssues = "assigned_issues"
+	CategoryNotifications  = "notifications"
+	CategoryInvitations    = "invitations"
+	CategoryWorkflows      = "workflows"
+)
+
+// Categories lists every alert category in the order alerts are built.
+var Categories = []string{
+	CategoryReviewRequests,
+	CategoryStalePRs,
+	CategoryAssignedIssues,
+	CategoryNotifications,
+	CategoryInvitations,
+	CategoryWorkflows,
+}
+
+// Changes a category can re-alert on, regardless of its cooldown (see
+// Policy.NotifyOn)
+const (
+	ChangeNewCommits  = "new_commits"  // New commits were pushed to the pull request
+	ChangeReviewState = "review_state" // The latest approval or change request changed
+	ChangeReopened    = "reopened"     // The item is listed again after dropping out: reopened, re-requested, reassigned or stale again
+	ChangeTitle       = "title"        // The title was edited
+	ChangeLabels      = "labels"       // Labels were added or removed
+	ChangeUpdated     = "updated"      // Any update at all (updated_at moved)
+)
+
+// Changes lists every change kind.
+var Changes = []string{ChangeNewCommits, ChangeReviewState, ChangeReopened, ChangeTitle, ChangeLabels, ChangeUpdated}
+
+// ChangeCategories are the categories whose items are fingerprinted, and so
+// can have change policies.
+var ChangeCategories = []string{CategoryReviewRequests, CategoryStalePRs, CategoryAssignedIssues}
+
+// Policy controls when a category alerts about an item again.
+type Policy struct {
+	Cooldown   time.Duration // How long a repeating alert stays quiet; 0 uses cooldowns (see CooldownFor)
+	Once       bool          // Alert about an item only once rather than after every cooldown
+	MaxRepeats int           // Stop repeating an alert after this many sends; 0 for no limit
+	StaleAfter time.Duration // stale_prs only: inactivity before an open PR counts as stale
+	NotifyOn   []string      // Changes that re-alert despite the above (see ChangeCategories)
+}
+
+// DefaultStaleAfter is how long a PR goes without activity before it is stale.
+const DefaultStaleAfter = 48 * time.Hour
+
 type Config struct {
-	GitHubToken     string
-	DiscordWebhook  string
-	Username        string
-	CheckInterval   time.Duration
-	DailyReportTime string
-	CacheFile       string
-	Timezone        string
-	TrackAllCommits bool     // Enable tracking commits from all repositories in daily digest
+	GitHubToken       string
+	Username          string
+	Destinations      []Destination // Where alerts and digests are delivered
+	CheckInterval     time.Duration
+	DailyReportTime   string // Morning digest time (HH:MM in Timezone), used by daemon mode
+	EveningReportTime string // Evening digest time (HH:MM in Timezone), used by daemon mode
+	CacheFile         string // JSON file, or bbolt database for the bolt backend
+	CacheBackend      string // file, bolt or redis
+	CacheURL          string // redis:// or rediss:// URL for the redis backend
+	Timezone          string
+	TrackAllCommits   bool // Enable tracking commits from all repositories in daily digest
+	MaxPages          int  // Maximum pages followed per GitHub list/search call
+	HTTPCache         bool // Cache GitHub responses and send conditional requests
+	Cooldowns         map[string]time.Duration
+	Categories        map[string]bool
+	Policies          map[string]Policy
+	Filters           Filters
+	Rules             []Rule // Include/exclude rules applied to alerts and digests (see AlertRules)
+	QuietHours        QuietHours
+	ServeAddr         string   // Listen address of the serve command
+	WebhookSecret     string   // Secret GitHub signs webhook deliveries with, for the serve command
+	Announce          Announce // Settings of cmd/commit-notifier, the GitHub Action
+	File              string   // Path of the YAML file the config was loaded from, if any
+	DryRun            bool     // Preview messages instead of sending them and leave the cache untouched (--dry-run or DRY_RUN)
 }
 
-func Load() *Config {
-	checkInterval, _ := time.ParseDuration(getEnvOrDefault("CHECK_INTERVAL", "5m"))
-	// Real-time commit tracking is now handled by GitHub Actions
+// Destination is a notification backend and its webhook URL.
+type Destination struct {
+	Type string `yaml:"type"` // discord, slack, teams or webhook
+	URL  string `yaml:"url"`
+}
+
+// Announce configures cmd/commit-notifier, which announces pushes, pull
+// requests, releases, tags and failed workflow runs from a GitHub Action.
+type Announce struct {
+	Title         string // Replaces each event's default card title (NOTIFICATION_TITLE)
+	IncludeAvatar bool   // Show the sender's avatar on the card (INCLUDE_AVATAR)
+	Commit        Commit // Announced by runs without a push payload, such as workflow_dispatch
+}
+
+// Commit is the head commit the action passes in from its inputs. It is only
+// read from the environment, since it changes with every run.
+type Commit struct {
+	SHA        string // GITHUB_SHA
+	Message    string // COMMIT_MESSAGE
+	Author     string // COMMIT_AUTHOR
+	URL        string // COMMIT_URL
+	Branch     string // BRANCH_NAME, else GITHUB_REF_NAME
+	Repository string // GITHUB_REPOSITORY, as owner/repo
+	RepoURL    string // REPO_URL
+	Actor      string // GITHUB_ACTOR
+}
+
+// Filters drop alerts before they are deduplicated and formatted. They are
+// shorthand for exclude rules (see AlertRules).
+type Filters struct {
+	ExcludeRepos   []string `yaml:"exclude_repos"`   // "owner/repo" or "owner/*"
+	ExcludeAuthors []string `yaml:"exclude_authors"` // e.g. "dependabot[bot]"
+}
+
+// Rule actions
+const (
+	RuleInclude = "include"
+	RuleExclude = "exclude"
+)
+
+// CategoryActivity scopes a rule to the digest's activity sections (PRs and
+// issues opened, merged, reviewed or closed, and commits).
+const CategoryActivity = "activity"
+
+// Rule matches alerts and digest entries. Every condition set must match;
+// list conditions match if any entry does. A rule only applies to items that
+// have every attribute it tests, so a reasons rule never touches PRs.
+type Rule struct {
+	Action       string   `yaml:"action"`        // include or exclude
+	Categories   []string `yaml:"categories"`    // Alert categories, or activity; all if empty
+	Repos        []string `yaml:"repos"`         // "owner/repo" patterns with path.Match wildcards
+	Owners       []string `yaml:"owners"`        // Repository owners (users or orgs)
+	Labels       []string `yaml:"labels"`        // Issue and PR labels
+	Authors      []string `yaml:"authors"`       // PR and issue authors, inviters, commit authors and reviewers
+	Reasons      []string `yaml:"reasons"`       // Notification reasons, e.g. mention or review_requested
+	SubjectTypes []string `yaml:"subject_types"` // Notification subject types, e.g. PullRequest or Release
+	Draft        *bool    `yaml:"draft"`         // PR draft status
+	Title        string   `yaml:"title"`         // Regular expression matched against the title
+}
+
+// DefaultCooldown is how long a repeating alert is suppressed after being sent.
+const DefaultCooldown = 24 * time.Hour
+
+// Load builds the configuration from defaults, the YAML file named by
+// GH_NOTIFY_CONFIG (or ./gh-notify.yaml if present) and environment variables,
+// in increasing order of precedence, and validates the result.
+func Load() (*Config, error) {
+	return LoadFile(Path())
+}
+
+// LoadFile is like Load but reads the YAML file at path. An empty path skips
+// the file and uses defaults and environment variables only.
+func LoadFile(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Path returns the config file Load reads: GH_NOTIFY_CONFIG, or
+// ./gh-notify.yaml if it exists, or "" for none.
+func Path() string {
+	if path := os.Getenv("GH_NOTIFY_CONFIG"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultFile); err == nil {
+		return DefaultFile
+	}
+	return ""
+}
+
+// Read merges defaults, the YAML file at path (if any) and environment
+// variables without validating the result, for commands such as "cache show"
+// that don't need a token or destinations. Unparseable values still fail.
+func Read(path string) (*Config, error) {
+	cfg := defaults()
+
+	if path != "" {
+		if err := cfg.applyFile(path); err != nil {
+			return nil, err
+		}
+		cfg.File = path
+	}
+
+	if problems := cfg.applyEnv(); len(problems) > 0 {
+		return nil, &ValidationError{File: cfg.File, Problems: problems}
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the configuration and returns a *ValidationError listing
+// every problem found.
+func (c *Config) Validate() error {
+	if problems := c.validate(); len(problems) > 0 {
+		return &ValidationError{File: c.File, Problems: problems}
+	}
+	return nil
+}
+
+func defaults() *Config {
+	categories := make(map[string]bool, len(Categories))
+	for _, category := range Categories {
+		categories[category] = true
+	}
 
 	return &Config{
-		GitHubToken:     getEnvOrDefault("GITHUB_TOKEN", ""),
-		DiscordWebhook:  getEnvOrDefault("DISCORD_WEBHOOK", ""),
-		Username:        getEnvOrDefault("GITHUB_USERNAME", ""),
-		CheckInterval:   checkInterval,
-		DailyReportTime: getEnvOrDefault("DAILY_REPORT_TIME", "02:00"), // 9h sáng VN = 2h UTC
-		CacheFile:       getEnvOrDefault("CACHE_FILE", "cache.json"),
-		Timezone:        getEnvOrDefault("TIMEZONE", "Asia/Ho_Chi_Minh"),
-		TrackAllCommits: GetBoolEnv("TRACK_ALL_COMMITS", true), // Default enabled for daily digests
-		// Real-time commit tracking moved to GitHub Actions
+		CheckInterval:     5 * time.Minute,
+		DailyReportTime:   "07:00", // In Timezone, like the scheduled workflow (it was "02:00" UTC before times were local)
+		EveningReportTime: "21:00",
+		CacheFile:         "cache.json",
+		CacheBackend:      "file",
+		ServeAddr:         ":8080",
+		Announce:          Announce{IncludeAvatar: true},
+		Timezone:          "Asia/Ho_Chi_Minh",
+		TrackAllCommits:   true, // Default enabled for daily digests
+		MaxPages:          10,
+		HTTPCache:         true,
+		Cooldowns:         map[string]time.Duration{},
+		Categories:        categories,
+		Policies: map[string]Policy{
+			CategoryReviewRequests: {NotifyOn: []string{ChangeNewCommits, ChangeReopened}},
+			CategoryStalePRs:       {StaleAfter: DefaultStaleAfter, NotifyOn: []string{ChangeReviewState}},
+			CategoryAssignedIssues: {Once: true, NotifyOn: []string{ChangeReopened, ChangeTitle}},
+			CategoryNotifications:  {},
+			CategoryInvitations:    {},
+			CategoryWorkflows:      {Once: true},
+		},
+	}
+}
+
+// applyEnv overrides config values with any environment variables that are set
+// and returns problems for values that can't be parsed.
+func (c *Config) applyEnv() []string {
+	var problems []string
+
+	setString := func(key string, target *string) {
+		if value := os.Getenv(key); value != "" {
+			*target = value
+		}
+	}
+	setString("GH_TOKEN", &c.GitHubToken) // The action's github-token input
+	setString("GITHUB_TOKEN", &c.GitHubToken)
+	setString("GITHUB_USERNAME", &c.Username)
+	setString("DAILY_REPORT_TIME", &c.DailyReportTime)
+	setString("EVENING_REPORT_TIME", &c.EveningReportTime)
+	setString("CACHE_FILE", &c.CacheFile)
+	setString("CACHE_BACKEND", &c.CacheBackend)
+	setString("CACHE_URL", &c.CacheURL)
+	setString("TIMEZONE", &c.Timezone)
+	setString("SERVE_ADDR", &c.ServeAddr)
+	setString("GITHUB_WEBHOOK_SECRET", &c.WebhookSecret)
+	setString("NOTIFICATION_TITLE", &c.Announce.Title)
+	setString("GITHUB_SHA", &c.Announce.Commit.SHA)
+	setString("COMMIT_MESSAGE", &c.Announce.Commit.Message)
+	setString("COMMIT_AUTHOR", &c.Announce.Commit.Author)
+	setString("COMMIT_URL", &c.Announce.Commit.URL)
+	setString("GITHUB_REF_NAME", &c.Announce.Commit.Branch)
+	setString("BRANCH_NAME", &c.Announce.Commit.Branch)
+	setString("GITHUB_REPOSITORY", &c.Announce.Commit.Repository)
+	setString("REPO_URL", &c.Announce.Commit.RepoURL)
+	setString("GITHUB_ACTOR", &c.Announce.Commit.Actor)
+
+	if value := os.Getenv("CHECK_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("CHECK_INTERVAL: invalid duration %q", value))
+		} else {
+			c.CheckInterval = interval
+		}
+	}
+	if value := os.Getenv("GITHUB_MAX_PAGES"); value != "" {
+		maxPages, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("GITHUB_MAX_PAGES: invalid number %q", value))
+		} else {
+			c.MaxPages = maxPages
+		}
+	}
+	if value := os.Getenv("QUIET_HOURS"); value != "" {
+		windows, err := parseQuietWindows(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("QUIET_HOURS: %v", err))
+		} else {
+			c.QuietHours.Windows = windows
+		}
+	}
+	if value := os.Getenv("QUIET_URGENT"); value != "" {
+		c.QuietHours.Urgent = nil
+		for _, category := range strings.Split(value, ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				c.QuietHours.Urgent = append(c.QuietHours.Urgent, category)
+			}
+		}
+	}
+	setBool := func(key string, target *bool) {
+		if value := os.Getenv(key); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid boolean %q", key, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	setBool("TRACK_ALL_COMMITS", &c.TrackAllCommits)
+	setBool("HTTP_CACHE", &c.HTTPCache)
+	setBool("DRY_RUN", &c.DryRun)
+	setBool("INCLUDE_AVATAR", &c.Announce.IncludeAvatar)
+
+	// NOTIFIER + WEBHOOK_URL (or the backend-specific variable) replace the
+	// file's destination of the same type, or add one
+	notifier := getEnvOrDefault("NOTIFIER", "discord")
+	if url := getEnvOrDefault("WEBHOOK_URL", webhookURLFor(notifier)); url != "" {
+		c.setDestination(Destination{Type: notifier, URL: url})
+	}
+
+	return problems
+}
+
+// setDestination replaces the destination with the same type, or appends it.
+func (c *Config) setDestination(destination Destination) {
+	for i := range c.Destinations {
+		if c.Destinations[i].Type == destination.Type {
+			c.Destinations[i].URL = destination.URL
+			return
+		}
+	}
+	c.Destinations = append(c.Destinations, destination)
+}
+
+// DiscordWebhook returns the URL of the first Discord destination, or "".
+func (c *Config) DiscordWebhook() string {
+	for _, destination := range c.Destinations {
+		if destination.Type == "discord" {
+			return destination.URL
+		}
+	}
+	return ""
+}
+
+// CooldownFor returns the cooldown for a repeating alert category: its
+// policy's cooldown, else its entry in cooldowns, else the default.
+func (c *Config) CooldownFor(category string) time.Duration {
+	if cooldown := c.Policies[category].Cooldown; cooldown > 0 {
+		return cooldown
+	}
+	if cooldown, ok := c.Cooldowns[category]; ok {
+		return cooldown
+	}
+	if cooldown, ok := c.Cooldowns["default"]; ok {
+		return cooldown
+	}
+	return DefaultCooldown
+}
+
+// AlertRules returns the configured rules preceded by exclude rules for
+// Filters.
+func (c *Config) AlertRules() []Rule {
+	var rules []Rule
+	if len(c.Filters.ExcludeRepos) > 0 {
+		rules = append(rules, Rule{Action: RuleExclude, Repos: c.Filters.ExcludeRepos})
+	}
+	if len(c.Filters.ExcludeAuthors) > 0 {
+		rules = append(rules, Rule{Action: RuleExclude, Authors: c.Filters.ExcludeAuthors})
+	}
+	return append(rules, c.Rules...)
+}
+
+// PolicyFor returns the policy for an alert category.
+func (c *Config) PolicyFor(category string) Policy {
+	return c.Policies[category]
+}
+
+// NotifyOnChange reports whether the category re-alerts on the given change.
+func (c *Config) NotifyOnChange(category, change string) bool {
+	for _, kind := range c.PolicyFor(category).NotifyOn {
+		if kind == change {
+			return true
+		}
+	}
+	return false
+}
+
+// CategoryEnabled reports whether alerts of the given category should be sent.
+func (c *Config) CategoryEnabled(category string) bool {
+	enabled, ok := c.Categories[category]
+	return !ok || enabled
+}
+
+// Location returns the configured timezone, falling back to UTC if it can't be loaded.
+func (c *Config) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		slog.Warn("unknown timezone, using UTC", "timezone", c.Timezone, "error", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// webhookURLFor returns the backend-specific webhook env var for the notifier,
+// so existing DISCORD_WEBHOOK setups keep working without WEBHOOK_URL.
+func webhookURLFor(notifier string) string {
+	switch notifier {
+	case "slack":
+		return os.Getenv("SLACK_WEBHOOK")
+	case "teams":
+		return os.Getenv("TEAMS_WEBHOOK")
+	default:
+		return os.Getenv("DISCORD_WEBHOOK")
 	}
 }
 
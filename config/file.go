@@ -0,0 +1,369 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is the config file picked up from the working directory when
+// GH_NOTIFY_CONFIG is not set.
+const DefaultFile = "gh-notify.yaml"
+
+// fileConfig mirrors gh-notify.yaml. Pointer fields distinguish "not set"
+// from zero values so the file only overrides what it mentions.
+type fileConfig struct {
+	GitHub       githubSection            `yaml:"github"`
+	Destinations []Destination            `yaml:"destinations"`
+	Schedule     scheduleSection          `yaml:"schedule"`
+	Digest       digestSection            `yaml:"digest"`
+	Cache        cacheSection             `yaml:"cache"`
+	Cooldowns    map[string]time.Duration `yaml:"cooldowns"`
+	Categories   map[string]bool          `yaml:"categories"`
+	Policies     map[string]policySection `yaml:"policies"`
+	Filters      Filters                  `yaml:"filters"`
+	Rules        []Rule                   `yaml:"rules"`
+	QuietHours   *QuietHours              `yaml:"quiet_hours"`
+	Server       serverSection            `yaml:"server"`
+	Announce     announceSection          `yaml:"announce"`
+}
+
+type githubSection struct {
+	Token     *string `yaml:"token"`
+	Username  *string `yaml:"username"`
+	MaxPages  *int    `yaml:"max_pages"`
+	HTTPCache *bool   `yaml:"http_cache"`
+}
+
+type scheduleSection struct {
+	Timezone      *string        `yaml:"timezone"`
+	CheckInterval *time.Duration `yaml:"check_interval"`
+	Morning       *string        `yaml:"morning"`
+	Evening       *string        `yaml:"evening"`
+}
+
+type digestSection struct {
+	TrackAllCommits *bool `yaml:"track_all_commits"`
+}
+
+type cacheSection struct {
+	Backend *string `yaml:"backend"` // file, bolt or redis
+	File    *string `yaml:"file"`
+	URL     *string `yaml:"url"`
+}
+
+type serverSection struct {
+	Addr          *string `yaml:"addr"`
+	WebhookSecret *string `yaml:"webhook_secret"`
+}
+
+type announceSection struct {
+	Title         *string `yaml:"title"`
+	IncludeAvatar *bool   `yaml:"include_avatar"`
+}
+
+type policySection struct {
+	Cooldown   *time.Duration `yaml:"cooldown"`
+	Once       *bool          `yaml:"once"`
+	MaxRepeats *int           `yaml:"max_repeats"`
+	StaleAfter *time.Duration `yaml:"stale_after"`
+	NotifyOn   *[]string      `yaml:"notify_on"`
+}
+
+// ValidationError lists every problem found in the configuration.
+type ValidationError struct {
+	File     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	source := "configuration"
+	if e.File != "" {
+		source = e.File
+	}
+	return fmt.Sprintf("invalid %s:\n  - %s", source, strings.Join(e.Problems, "\n  - "))
+}
+
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// applyFile reads a YAML config file over c. ${VAR} references are expanded
+// from the environment so secrets don't have to live in the file. Unknown
+// keys are rejected.
+func (c *Config) applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	data = envReferencePattern.ReplaceAllFunc(data, func(ref []byte) []byte {
+		name := envReferencePattern.FindSubmatch(ref)[1]
+		return []byte(os.Getenv(string(name)))
+	})
+
+	var file fileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return &ValidationError{File: path, Problems: []string{err.Error()}}
+	}
+
+	setIf(&c.GitHubToken, file.GitHub.Token)
+	setIf(&c.Username, file.GitHub.Username)
+	setIf(&c.MaxPages, file.GitHub.MaxPages)
+	setIf(&c.HTTPCache, file.GitHub.HTTPCache)
+	setIf(&c.Timezone, file.Schedule.Timezone)
+	setIf(&c.CheckInterval, file.Schedule.CheckInterval)
+	setIf(&c.DailyReportTime, file.Schedule.Morning)
+	setIf(&c.EveningReportTime, file.Schedule.Evening)
+	setIf(&c.TrackAllCommits, file.Digest.TrackAllCommits)
+	setIf(&c.CacheBackend, file.Cache.Backend)
+	setIf(&c.CacheFile, file.Cache.File)
+	setIf(&c.CacheURL, file.Cache.URL)
+	setIf(&c.ServeAddr, file.Server.Addr)
+	setIf(&c.WebhookSecret, file.Server.WebhookSecret)
+	setIf(&c.Announce.Title, file.Announce.Title)
+	setIf(&c.Announce.IncludeAvatar, file.Announce.IncludeAvatar)
+
+	c.Destinations = append(c.Destinations, file.Destinations...)
+	for category, cooldown := range file.Cooldowns {
+		c.Cooldowns[category] = cooldown
+	}
+	for category, enabled := range file.Categories {
+		c.Categories[category] = enabled
+	}
+	// Policy settings override the category's defaults one by one
+	for category, section := range file.Policies {
+		policy := c.Policies[category]
+		setIf(&policy.Cooldown, section.Cooldown)
+		setIf(&policy.Once, section.Once)
+		setIf(&policy.MaxRepeats, section.MaxRepeats)
+		setIf(&policy.StaleAfter, section.StaleAfter)
+		setIf(&policy.NotifyOn, section.NotifyOn)
+		c.Policies[category] = policy
+	}
+	c.Filters = file.Filters
+	c.Rules = file.Rules
+	setIf(&c.QuietHours, file.QuietHours)
+
+	return nil
+}
+
+func setIf[T any](target *T, value *T) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+// validate checks the merged configuration and returns every problem found.
+func (c *Config) validate() []string {
+	var problems []string
+
+	if c.GitHubToken == "" {
+		problems = append(problems, "github.token is required (or set GITHUB_TOKEN)")
+	}
+
+	if len(c.Destinations) == 0 {
+		problems = append(problems, "at least one destination is required (or set DISCORD_WEBHOOK / WEBHOOK_URL)")
+	}
+	problems = append(problems, c.validateDestinations()...)
+
+	if c.CheckInterval <= 0 {
+		problems = append(problems, fmt.Sprintf("schedule.check_interval must be positive, got %v", c.CheckInterval))
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		problems = append(problems, fmt.Sprintf("schedule.timezone: unknown timezone %q", c.Timezone))
+	}
+	if _, err := ParseClock(c.DailyReportTime); err != nil {
+		problems = append(problems, fmt.Sprintf("schedule.morning: expected HH:MM, got %q", c.DailyReportTime))
+	}
+	if _, err := ParseClock(c.EveningReportTime); err != nil {
+		problems = append(problems, fmt.Sprintf("schedule.evening: expected HH:MM, got %q", c.EveningReportTime))
+	}
+	if c.MaxPages < 1 {
+		problems = append(problems, fmt.Sprintf("github.max_pages must be at least 1, got %d", c.MaxPages))
+	}
+	switch c.CacheBackend {
+	case "file", "bolt":
+		if c.CacheFile == "" {
+			problems = append(problems, "cache.file must not be empty")
+		}
+	case "redis":
+		if parsed, err := url.Parse(c.CacheURL); err != nil || (parsed.Scheme != "redis" && parsed.Scheme != "rediss") || parsed.Host == "" {
+			problems = append(problems, fmt.Sprintf("cache.url: %q is not a redis:// or rediss:// URL (or set CACHE_URL)", c.CacheURL))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("cache.backend: unknown backend %q (want file, bolt or redis)", c.CacheBackend))
+	}
+
+	for _, category := range sortedKeys(c.Cooldowns) {
+		cooldown := c.Cooldowns[category]
+		if category != "default" && !isCategory(category) {
+			problems = append(problems, fmt.Sprintf("cooldowns.%s: unknown category (want default or one of %s)", category, strings.Join(Categories, ", ")))
+		}
+		if cooldown < 0 {
+			problems = append(problems, fmt.Sprintf("cooldowns.%s must not be negative", category))
+		}
+	}
+	for _, category := range sortedKeys(c.Categories) {
+		if !isCategory(category) {
+			problems = append(problems, fmt.Sprintf("categories.%s: unknown category (want one of %s)", category, strings.Join(Categories, ", ")))
+		}
+	}
+	for _, category := range sortedKeys(c.Policies) {
+		policy := c.Policies[category]
+		if !isCategory(category) {
+			problems = append(problems, fmt.Sprintf("policies.%s: unknown category (want one of %s)", category, strings.Join(Categories, ", ")))
+			continue
+		}
+		if policy.Cooldown < 0 {
+			problems = append(problems, fmt.Sprintf("policies.%s.cooldown must not be negative", category))
+		}
+		if policy.MaxRepeats < 0 {
+			problems = append(problems, fmt.Sprintf("policies.%s.max_repeats must not be negative", category))
+		}
+		if category == CategoryStalePRs && policy.StaleAfter <= 0 {
+			problems = append(problems, fmt.Sprintf("policies.%s.stale_after must be positive, got %v", category, policy.StaleAfter))
+		}
+		if category != CategoryStalePRs && policy.StaleAfter != 0 {
+			problems = append(problems, fmt.Sprintf("policies.%s.stale_after only applies to %s", category, CategoryStalePRs))
+		}
+		if len(policy.NotifyOn) > 0 && !contains(ChangeCategories, category) {
+			problems = append(problems, fmt.Sprintf("policies.%s.notify_on: only %s track changes", category, strings.Join(ChangeCategories, ", ")))
+		}
+		for _, change := range policy.NotifyOn {
+			if !contains(Changes, change) {
+				problems = append(problems, fmt.Sprintf("policies.%s.notify_on: unknown change %q (want one of %s)", category, change, strings.Join(Changes, ", ")))
+			}
+		}
+	}
+	for _, repo := range c.Filters.ExcludeRepos {
+		if !strings.Contains(repo, "/") {
+			problems = append(problems, fmt.Sprintf("filters.exclude_repos: %q must be owner/repo or owner/*", repo))
+		}
+	}
+	for i, window := range c.QuietHours.Windows {
+		problems = append(problems, window.validate(fmt.Sprintf("quiet_hours.windows[%d]", i))...)
+	}
+	for _, category := range c.QuietHours.Urgent {
+		if !isCategory(category) {
+			problems = append(problems, fmt.Sprintf("quiet_hours.urgent: unknown category %q (want one of %s)", category, strings.Join(Categories, ", ")))
+		}
+	}
+	for i, rule := range c.Rules {
+		problems = append(problems, rule.validate(fmt.Sprintf("rules[%d]", i))...)
+	}
+	problems = append(problems, c.validateAnnounce()...)
+
+	return problems
+}
+
+// ValidateAnnounce checks what cmd/commit-notifier needs: a Discord
+// destination and the action's settings. Unlike Validate it needs no GitHub
+// token, which only adds avatars.
+func (c *Config) ValidateAnnounce() error {
+	var problems []string
+	if c.DiscordWebhook() == "" {
+		problems = append(problems, "a discord destination is required (or set DISCORD_WEBHOOK)")
+	}
+	problems = append(problems, c.validateDestinations()...)
+	problems = append(problems, c.validateAnnounce()...)
+	if len(problems) > 0 {
+		return &ValidationError{File: c.File, Problems: problems}
+	}
+	return nil
+}
+
+func (c *Config) validateDestinations() []string {
+	var problems []string
+	for i, destination := range c.Destinations {
+		switch destination.Type {
+		case "discord", "slack", "teams", "webhook":
+		default:
+			problems = append(problems, fmt.Sprintf("destinations[%d].type: unknown type %q (want discord, slack, teams or webhook)", i, destination.Type))
+		}
+		if !isHTTPURL(destination.URL) {
+			problems = append(problems, fmt.Sprintf("destinations[%d].url: %q is not an http(s) URL", i, destination.URL))
+		}
+	}
+	return problems
+}
+
+func (c *Config) validateAnnounce() []string {
+	var problems []string
+	commit := c.Announce.Commit
+	if commit.URL != "" && !isHTTPURL(commit.URL) {
+		problems = append(problems, fmt.Sprintf("COMMIT_URL: %q is not an http(s) URL", commit.URL))
+	}
+	if commit.RepoURL != "" && !isHTTPURL(commit.RepoURL) {
+		problems = append(problems, fmt.Sprintf("REPO_URL: %q is not an http(s) URL", commit.RepoURL))
+	}
+	if commit.Repository != "" && !strings.Contains(commit.Repository, "/") {
+		problems = append(problems, fmt.Sprintf("GITHUB_REPOSITORY: %q must be owner/repo", commit.Repository))
+	}
+	return problems
+}
+
+func isHTTPURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
+
+// validate checks a rule, naming it as name in problems.
+func (r Rule) validate(name string) []string {
+	var problems []string
+	if r.Action != RuleInclude && r.Action != RuleExclude {
+		problems = append(problems, fmt.Sprintf("%s.action: %q must be include or exclude", name, r.Action))
+	}
+	for _, category := range r.Categories {
+		if !isCategory(category) && category != CategoryActivity {
+			problems = append(problems, fmt.Sprintf("%s.categories: unknown category %q (want %s or %s)", name, category, strings.Join(Categories, ", "), CategoryActivity))
+		}
+	}
+	for _, repo := range r.Repos {
+		if _, err := path.Match(repo, ""); err != nil || !strings.Contains(repo, "/") {
+			problems = append(problems, fmt.Sprintf("%s.repos: %q must be owner/repo or a pattern such as owner/*", name, repo))
+		}
+	}
+	if r.Title != "" {
+		if _, err := regexp.Compile(r.Title); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.title: %v", name, err))
+		}
+	}
+	if len(r.Repos) == 0 && len(r.Owners) == 0 && len(r.Labels) == 0 && len(r.Authors) == 0 &&
+		len(r.Reasons) == 0 && len(r.SubjectTypes) == 0 && r.Draft == nil && r.Title == "" && len(r.Categories) == 0 {
+		problems = append(problems, fmt.Sprintf("%s has no conditions", name))
+	}
+	return problems
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isCategory(name string) bool {
+	return contains(Categories, name)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the variables applyEnv reads so the host environment
+// doesn't leak into a test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"GITHUB_TOKEN", "GITHUB_USERNAME", "DAILY_REPORT_TIME", "EVENING_REPORT_TIME",
+		"CACHE_FILE", "CACHE_BACKEND", "CACHE_URL", "TIMEZONE", "SERVE_ADDR",
+		"GITHUB_WEBHOOK_SECRET", "CHECK_INTERVAL", "GITHUB_MAX_PAGES", "QUIET_HOURS",
+		"QUIET_URGENT", "TRACK_ALL_COMMITS", "HTTP_CACHE", "DRY_RUN", "NOTIFIER",
+		"WEBHOOK_URL", "DISCORD_WEBHOOK", "SLACK_WEBHOOK", "TEAMS_WEBHOOK",
+		"GH_TOKEN", "NOTIFICATION_TITLE", "INCLUDE_AVATAR", "GITHUB_SHA", "COMMIT_MESSAGE",
+		"COMMIT_AUTHOR", "COMMIT_URL", "GITHUB_REF_NAME", "BRANCH_NAME", "GITHUB_REPOSITORY",
+		"REPO_URL", "GITHUB_ACTOR",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFile(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+	path := writeConfig(t, `
+github:
+  token: ${TEST_GITHUB_TOKEN}
+  max_pages: 3
+destinations:
+  - type: slack
+    url: https://hooks.slack.com/services/T/B/X
+schedule:
+  timezone: UTC
+  check_interval: 10m
+policies:
+  stale_prs:
+    stale_after: 48h
+`)
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if cfg.GitHubToken != "secret" {
+		t.Errorf("token = %q, want ${TEST_GITHUB_TOKEN} expanded to secret", cfg.GitHubToken)
+	}
+	if cfg.MaxPages != 3 || cfg.CheckInterval != 10*time.Minute || cfg.Timezone != "UTC" {
+		t.Errorf("max_pages, check_interval, timezone = %d, %v, %q", cfg.MaxPages, cfg.CheckInterval, cfg.Timezone)
+	}
+	if len(cfg.Destinations) != 1 || cfg.Destinations[0].Type != "slack" {
+		t.Errorf("destinations = %+v, want the file's slack destination", cfg.Destinations)
+	}
+	// Settings the file doesn't mention keep their defaults
+	stale := cfg.Policies[CategoryStalePRs]
+	if stale.StaleAfter != 48*time.Hour || len(stale.NotifyOn) != 1 || stale.NotifyOn[0] != ChangeReviewState {
+		t.Errorf("stale_prs policy = %+v, want stale_after overridden and notify_on kept", stale)
+	}
+	if cfg.DailyReportTime != "07:00" || cfg.CacheBackend != "file" {
+		t.Errorf("morning, cache backend = %q, %q, want defaults", cfg.DailyReportTime, cfg.CacheBackend)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestReadFileRejectsUnknownKeys(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+github:
+  token: secret
+  max_page: 3
+`)
+
+	_, err := Read(path)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Read error = %v, want *ValidationError", err)
+	}
+	if validationErr.File != path || len(validationErr.Problems) != 1 || !strings.Contains(validationErr.Problems[0], "max_page") {
+		t.Errorf("error = %+v, want the unknown key in %s", validationErr, path)
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GITHUB_TOKEN", "from-env")
+	t.Setenv("CHECK_INTERVAL", "1m")
+	t.Setenv("DISCORD_WEBHOOK", "https://discord.com/api/webhooks/env")
+	path := writeConfig(t, `
+github:
+  token: from-file
+schedule:
+  check_interval: 10m
+  evening: "20:00"
+destinations:
+  - type: discord
+    url: https://discord.com/api/webhooks/file
+  - type: teams
+    url: https://example.webhook.office.com/file
+`)
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if cfg.GitHubToken != "from-env" || cfg.CheckInterval != time.Minute {
+		t.Errorf("token, check_interval = %q, %v, want the environment's", cfg.GitHubToken, cfg.CheckInterval)
+	}
+	if cfg.EveningReportTime != "20:00" {
+		t.Errorf("evening = %q, want the file's 20:00", cfg.EveningReportTime)
+	}
+	// The environment replaces the file's destination of the same type only
+	want := []Destination{
+		{Type: "discord", URL: "https://discord.com/api/webhooks/env"},
+		{Type: "teams", URL: "https://example.webhook.office.com/file"},
+	}
+	if len(cfg.Destinations) != len(want) {
+		t.Fatalf("destinations = %+v, want %+v", cfg.Destinations, want)
+	}
+	for i := range want {
+		if cfg.Destinations[i].Type != want[i].Type || cfg.Destinations[i].URL != want[i].URL {
+			t.Errorf("destinations[%d] = %+v, want %+v", i, cfg.Destinations[i], want[i])
+		}
+	}
+}
+
+func TestReadInvalidEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CHECK_INTERVAL", "often")
+	t.Setenv("DRY_RUN", "maybe")
+
+	_, err := Read("")
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Read error = %v, want *ValidationError", err)
+	}
+	if len(validationErr.Problems) != 2 {
+		t.Errorf("problems = %q, want one each for CHECK_INTERVAL and DRY_RUN", validationErr.Problems)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		cfg := defaults()
+		cfg.GitHubToken = "secret"
+		cfg.Destinations = []Destination{{Type: "discord", URL: "https://discord.com/api/webhooks/1"}}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   []string // Substrings of the expected problems, in order
+	}{
+		{"valid", func(*Config) {}, nil},
+		{"missing token and destinations", func(c *Config) {
+			c.GitHubToken = ""
+			c.Destinations = nil
+		}, []string{"github.token", "at least one destination"}},
+		{"bad destination", func(c *Config) {
+			c.Destinations = []Destination{{Type: "email", URL: "mailto:me@example.com"}}
+		}, []string{"destinations[0].type", "destinations[0].url"}},
+		{"bad schedule", func(c *Config) {
+			c.CheckInterval = 0
+			c.Timezone = "Mars/Olympus"
+			c.DailyReportTime = "7am"
+		}, []string{"schedule.check_interval", "schedule.timezone", "schedule.morning"}},
+		{"redis without URL", func(c *Config) {
+			c.CacheBackend = "redis"
+		}, []string{"cache.url"}},
+		{"unknown categories", func(c *Config) {
+			c.Cooldowns = map[string]time.Duration{"default": time.Hour, "pushes": time.Hour}
+			c.Categories["pushes"] = true
+		}, []string{"cooldowns.pushes", "categories.pushes"}},
+		{"stale_after outside stale_prs", func(c *Config) {
+			c.Policies[CategoryWorkflows] = Policy{StaleAfter: time.Hour, NotifyOn: []string{ChangeTitle}}
+		}, []string{"policies.workflows.stale_after", "policies.workflows.notify_on"}},
+		{"bad rule", func(c *Config) {
+			c.Rules = []Rule{{Action: "drop", Title: "("}, {Action: RuleInclude}}
+		}, []string{"rules[0].action", "rules[0].title", "rules[1] has no conditions"}},
+		{"bad action inputs", func(c *Config) {
+			c.Announce.Commit = Commit{URL: "abc123", RepoURL: "github.com/o/r", Repository: "r"}
+		}, []string{"COMMIT_URL", "REPO_URL", "GITHUB_REPOSITORY"}},
+		{"bad quiet hours", func(c *Config) {
+			c.QuietHours = QuietHours{Windows: []QuietWindow{{Start: "22:00", End: "22:00"}}, Urgent: []string{"pushes"}}
+		}, []string{"quiet_hours.windows[0]", "quiet_hours.urgent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			problems := cfg.validate()
+			if len(problems) != len(tt.want) {
+				t.Fatalf("validate() = %q, want %d problems", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problems[%d] = %q, want it to mention %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadAnnounce(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GH_TOKEN", "from-action")
+	t.Setenv("NOTIFICATION_TITLE", "Shipped")
+	t.Setenv("GITHUB_REF_NAME", "refs-name")
+	t.Setenv("BRANCH_NAME", "main")
+	t.Setenv("GITHUB_SHA", "abc123")
+	t.Setenv("COMMIT_MESSAGE", "fix: it")
+	path := writeConfig(t, `
+announce:
+  title: From file
+  include_avatar: false
+`)
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if cfg.GitHubToken != "from-action" {
+		t.Errorf("token = %q, want GH_TOKEN when GITHUB_TOKEN is unset", cfg.GitHubToken)
+	}
+	if cfg.Announce.Title != "Shipped" || cfg.Announce.IncludeAvatar {
+		t.Errorf("announce = %q, %v, want the environment's title and the file's include_avatar", cfg.Announce.Title, cfg.Announce.IncludeAvatar)
+	}
+	commit := cfg.Announce.Commit
+	if commit.Branch != "main" || commit.SHA != "abc123" || commit.Message != "fix: it" {
+		t.Errorf("commit = %+v, want BRANCH_NAME over GITHUB_REF_NAME, GITHUB_SHA and COMMIT_MESSAGE", commit)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "from-env")
+	t.Setenv("INCLUDE_AVATAR", "yes please")
+	_, err = Read(path)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 1 || !strings.Contains(validationErr.Problems[0], "INCLUDE_AVATAR") {
+		t.Fatalf("Read error = %v, want an INCLUDE_AVATAR problem", err)
+	}
+}
+
+func TestValidateAnnounce(t *testing.T) {
+	cfg := defaults()
+	if err := cfg.ValidateAnnounce(); err == nil || !strings.Contains(err.Error(), "discord destination") {
+		t.Errorf("ValidateAnnounce() = %v, want a missing discord destination", err)
+	}
+
+	// No GitHub token needed: it only adds avatars
+	cfg.Destinations = []Destination{{Type: "discord", URL: "https://discord.com/api/webhooks/1"}}
+	if err := cfg.ValidateAnnounce(); err != nil {
+		t.Errorf("ValidateAnnounce() = %v, want nil", err)
+	}
+
+	cfg.Announce.Commit.URL = "not a url"
+	if err := cfg.ValidateAnnounce(); err == nil || !strings.Contains(err.Error(), "COMMIT_URL") {
+		t.Errorf("ValidateAnnounce() = %v, want a COMMIT_URL problem", err)
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuietHours holds back instant alerts during the configured windows. Alerts
+// found meanwhile are kept in the cache and sent as one batch by the first
+// check after the window ends.
+type QuietHours struct {
+	Windows []QuietWindow `yaml:"windows"`
+	Urgent  []string      `yaml:"urgent"` // Categories sent anyway; failed workflows only on the default branch
+}
+
+// QuietWindow is a daily time range in the configured timezone. A window
+// whose end is before its start runs past midnight and belongs to the day it
+// starts on.
+type QuietWindow struct {
+	Days  []string `yaml:"days"`  // mon..sun or monday..sunday; every day if empty
+	Start string   `yaml:"start"` // HH:MM
+	End   string   `yaml:"end"`   // HH:MM
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// InQuietHours reports whether t falls in a quiet window.
+func (c *Config) InQuietHours(t time.Time) bool {
+	t = t.In(c.Location())
+	for _, window := range c.QuietHours.Windows {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// UrgentCategory reports whether the category is sent during quiet hours.
+func (c *Config) UrgentCategory(category string) bool {
+	return contains(c.QuietHours.Urgent, category)
+}
+
+func (w QuietWindow) contains(t time.Time) bool {
+	start, errStart := ParseClock(w.Start)
+	end, errEnd := ParseClock(w.End)
+	if errStart != nil || errEnd != nil {
+		return false
+	}
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+
+	if start < end {
+		return now >= start && now < end && w.onDay(t.Weekday())
+	}
+	// Past midnight: the evening part is on the window's day, the morning
+	// part on the day after
+	if now >= start {
+		return w.onDay(t.Weekday())
+	}
+	return now < end && w.onDay((t.Weekday()+6)%7)
+}
+
+func (w QuietWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if parsed, ok := parseWeekday(name); ok && parsed == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (w QuietWindow) validate(name string) []string {
+	var problems []string
+	start, errStart := ParseClock(w.Start)
+	if errStart != nil {
+		problems = append(problems, fmt.Sprintf("%s.start: expected HH:MM, got %q", name, w.Start))
+	}
+	end, errEnd := ParseClock(w.End)
+	if errEnd != nil {
+		problems = append(problems, fmt.Sprintf("%s.end: expected HH:MM, got %q", name, w.End))
+	}
+	if errStart == nil && errEnd == nil && start == end {
+		problems = append(problems, fmt.Sprintf("%s: start and end must differ", name))
+	}
+	for _, day := range w.Days {
+		if _, ok := parseWeekday(day); !ok {
+			problems = append(problems, fmt.Sprintf("%s.days: unknown day %q (want mon, tue, wed, thu, fri, sat or sun)", name, day))
+		}
+	}
+	return problems
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	if len(name) < 3 {
+		return 0, false
+	}
+	day, ok := weekdays[name[:3]]
+	if ok && len(name) > 3 && name != strings.ToLower(day.String()) {
+		return 0, false
+	}
+	return day, ok
+}
+
+// ParseClock parses HH:MM into the time since midnight.
+func ParseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseQuietWindows parses QUIET_HOURS: comma-separated HH:MM-HH:MM ranges
+// that apply every day.
+func parseQuietWindows(value string) ([]QuietWindow, error) {
+	var windows []QuietWindow
+	for _, part := range strings.Split(value, ",") {
+		start, end, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("expected HH:MM-HH:MM, got %q", part)
+		}
+		windows = append(windows, QuietWindow{Start: strings.TrimSpace(start), End: strings.TrimSpace(end)})
+	}
+	return windows, nil
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// friday returns 2026-10-16, a Friday, at hh:mm UTC, shifted by days.
+func friday(days, hh, mm int) time.Time {
+	return time.Date(2026, 10, 16+days, hh, mm, 0, 0, time.UTC)
+}
+
+func TestQuietWindowContains(t *testing.T) {
+	overnight := QuietWindow{Start: "22:00", End: "07:00"}
+	weeknights := QuietWindow{Start: "22:00", End: "07:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}}
+	lunch := QuietWindow{Start: "12:00", End: "13:00", Days: []string{"Friday"}}
+
+	tests := []struct {
+		name   string
+		window QuietWindow
+		t      time.Time
+		want   bool
+	}{
+		{"same day, inside", lunch, friday(0, 12, 30), true},
+		{"same day, at start", lunch, friday(0, 12, 0), true},
+		{"same day, at end", lunch, friday(0, 13, 0), false},
+		{"same day, other weekday", lunch, friday(-1, 12, 30), false},
+		{"overnight, evening", overnight, friday(0, 23, 0), true},
+		{"overnight, after midnight", overnight, friday(1, 6, 59), true},
+		{"overnight, at end", overnight, friday(1, 7, 0), false},
+		{"overnight, daytime", overnight, friday(0, 15, 0), false},
+		// The morning part belongs to the day the window started on
+		{"weeknights, Friday evening", weeknights, friday(0, 23, 0), true},
+		{"weeknights, Saturday morning after Friday", weeknights, friday(1, 3, 0), true},
+		{"weeknights, Saturday evening", weeknights, friday(1, 23, 0), false},
+		{"weeknights, Sunday morning after Saturday", weeknights, friday(2, 3, 0), false},
+		{"weeknights, Monday morning after Sunday", weeknights, friday(3, 3, 0), false},
+		{"weeknights, Monday evening", weeknights, friday(3, 22, 0), true},
+		// validate rejects start == end; unchecked it covers the whole day
+		{"start equals end", QuietWindow{Start: "00:00", End: "00:00"}, friday(0, 15, 0), true},
+		{"invalid clock", QuietWindow{Start: "25:00", End: "07:00"}, friday(0, 23, 0), false},
+		{"invalid day name", QuietWindow{Start: "22:00", End: "07:00", Days: []string{"funday"}}, friday(0, 23, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.contains(tt.t); got != tt.want {
+				t.Errorf("contains(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInQuietHoursTimezone(t *testing.T) {
+	cfg := &Config{Timezone: "Asia/Ho_Chi_Minh", QuietHours: QuietHours{Windows: []QuietWindow{{Start: "22:00", End: "07:00"}}}}
+	// 16:00 UTC is 23:00 in Ho Chi Minh City
+	if !cfg.InQuietHours(friday(0, 16, 0)) {
+		t.Error("16:00 UTC not quiet, want quiet at 23:00 local time")
+	}
+	// 23:00 UTC is 06:00 the next morning there, 01:00 UTC is 08:00
+	if !cfg.InQuietHours(friday(0, 23, 0)) || cfg.InQuietHours(friday(1, 1, 0)) {
+		t.Error("quiet hours not applied in the configured timezone")
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		name string
+		want time.Weekday
+		ok   bool
+	}{
+		{"mon", time.Monday, true},
+		{"Monday", time.Monday, true},
+		{"SUN", time.Sunday, true},
+		{"thursday", time.Thursday, true},
+		{"thurs", 0, false},
+		{"mo", 0, false},
+		{"", 0, false},
+		{"funday", 0, false},
+		{"monkey", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseWeekday(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseWeekday(%q) = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestQuietWindowValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   QuietWindow
+		problems int
+	}{
+		{"valid", QuietWindow{Start: "22:00", End: "07:00", Days: []string{"mon", "Friday"}}, 0},
+		{"start equals end", QuietWindow{Start: "22:00", End: "22:00"}, 1},
+		{"bad clocks", QuietWindow{Start: "7am", End: "24:00"}, 2},
+		{"unknown day", QuietWindow{Start: "22:00", End: "07:00", Days: []string{"mon", "someday"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := tt.window.validate("quiet_hours.windows[0]"); len(problems) != tt.problems {
+				t.Errorf("validate = %q, want %d problems", problems, tt.problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+	"github.com/wilfierd/gh-notify/notify"
+	"github.com/wilfierd/gh-notify/rules"
+)
+
+// runDaemon keeps the notifier running: instant checks every CheckInterval and
+// the morning/evening digests at DailyReportTime/EveningReportTime in the
+// configured timezone. It returns once ctx is cancelled, after the run in
+// progress finishes and the cache has been flushed.
+func runDaemon(ctx context.Context, githubClient *github.Client, notifier notify.Notifier, store cache.Store, state *cache.State, username string, cfg *config.Config, engine *rules.Engine) error {
+	if cfg.CheckInterval <= 0 {
+		return fmt.Errorf("CHECK_INTERVAL must be positive, got %v", cfg.CheckInterval)
+	}
+
+	loc := cfg.Location()
+	morning, err := config.ParseClock(cfg.DailyReportTime)
+	if err != nil {
+		return fmt.Errorf("invalid DAILY_REPORT_TIME: expected HH:MM, got %q", cfg.DailyReportTime)
+	}
+	evening, err := config.ParseClock(cfg.EveningReportTime)
+	if err != nil {
+		return fmt.Errorf("invalid EVENING_REPORT_TIME: expected HH:MM, got %q", cfg.EveningReportTime)
+	}
+
+	nextMorning := nextClockTime(time.Now(), morning, loc)
+	nextEvening := nextClockTime(time.Now(), evening, loc)
+	morningTimer := time.NewTimer(time.Until(nextMorning))
+	eveningTimer := time.NewTimer(time.Until(nextEvening))
+	defer morningTimer.Stop()
+	defer eveningTimer.Stop()
+
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+
+	slog.Info("daemon started", "check_interval", cfg.CheckInterval, "next_morning", nextMorning, "next_evening", nextEvening)
+
+	// Run an instant check straight away rather than waiting a full interval
+	runChecks(githubClient, notifier, store, state, username, cfg, engine, "instant")
+
+	for {
+		select {
+		case <-ctx.Done():
+			if cfg.DryRun {
+				return nil
+			}
+			slog.Info("shutdown requested, flushing cache")
+			if err := store.Save(state); err != nil {
+				return fmt.Errorf("failed to flush cache state: %w", err)
+			}
+			return nil
+
+		case <-ticker.C:
+			runChecks(githubClient, notifier, store, state, username, cfg, engine, "instant")
+
+		case <-morningTimer.C:
+			runChecks(githubClient, notifier, store, state, username, cfg, engine, "morning")
+			nextMorning = nextClockTime(time.Now(), morning, loc)
+			morningTimer.Reset(time.Until(nextMorning))
+			slog.Info("scheduled next digest", "check_type", "morning", "at", nextMorning)
+
+		case <-eveningTimer.C:
+			runChecks(githubClient, notifier, store, state, username, cfg, engine, "evening")
+			nextEvening = nextClockTime(time.Now(), evening, loc)
+			eveningTimer.Reset(time.Until(nextEvening))
+			slog.Info("scheduled next digest", "check_type", "evening", "at", nextEvening)
+		}
+	}
+}
+
+// nextClockTime returns the next time after now at the given time of day
+// (see config.ParseClock) in loc.
+func nextClockTime(now time.Time, clock time.Duration, loc *time.Location) time.Time {
+	hour, minute := int(clock/time.Hour), int(clock%time.Hour/time.Minute)
+	now = now.In(loc)
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return next
+}
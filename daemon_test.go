@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextClockTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	at := func(loc *time.Location, year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		clock time.Duration
+		loc   *time.Location
+		want  time.Time
+	}{
+		{"before today's time", at(time.UTC, 2026, 10, 16, 6, 0), 7 * time.Hour, time.UTC, at(time.UTC, 2026, 10, 16, 7, 0)},
+		{"after today's time", at(time.UTC, 2026, 10, 16, 8, 0), 7 * time.Hour, time.UTC, at(time.UTC, 2026, 10, 17, 7, 0)},
+		{"exactly at the time", at(time.UTC, 2026, 10, 16, 7, 0), 7 * time.Hour, time.UTC, at(time.UTC, 2026, 10, 17, 7, 0)},
+		{"minutes", at(time.UTC, 2026, 10, 16, 18, 29), 18*time.Hour + 30*time.Minute, time.UTC, at(time.UTC, 2026, 10, 16, 18, 30)},
+		{"midnight from the evening", at(time.UTC, 2026, 10, 16, 23, 30), 0, time.UTC, at(time.UTC, 2026, 10, 17, 0, 0)},
+		{"midnight at midnight", at(time.UTC, 2026, 10, 16, 0, 0), 0, time.UTC, at(time.UTC, 2026, 10, 17, 0, 0)},
+		{"month end", at(time.UTC, 2026, 12, 31, 20, 0), 7 * time.Hour, time.UTC, at(time.UTC, 2027, 1, 1, 7, 0)},
+		// 23:00 UTC is already 08:00 the next day in Tokyo
+		{"now in another zone", at(time.UTC, 2026, 10, 16, 23, 0), 7 * time.Hour, tokyo, at(tokyo, 2026, 10, 18, 7, 0)},
+		// Clocks go forward on 8 March and back on 1 November 2026
+		{"into daylight saving time", at(newYork, 2026, 3, 7, 8, 0), 7 * time.Hour, newYork, at(newYork, 2026, 3, 8, 7, 0)},
+		{"out of daylight saving time", at(newYork, 2026, 10, 31, 8, 0), 7 * time.Hour, newYork, at(newYork, 2026, 11, 1, 7, 0)},
+		{"before the change on its day", at(newYork, 2026, 3, 8, 0, 30), 7 * time.Hour, newYork, at(newYork, 2026, 3, 8, 7, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextClockTime(tt.now, tt.clock, tt.loc)
+			if !got.Equal(tt.want) {
+				t.Errorf("nextClockTime = %s, want %s", got, tt.want)
+			}
+			if got.Location() != tt.loc {
+				t.Errorf("location = %s, want %s", got.Location(), tt.loc)
+			}
+		})
+	}
+
+	// The day clocks go forward is 23 hours long, the day they go back 25
+	spring := nextClockTime(at(newYork, 2026, 3, 7, 7, 0), 7*time.Hour, newYork)
+	if gap := spring.Sub(at(newYork, 2026, 3, 7, 7, 0)); gap != 23*time.Hour {
+		t.Errorf("spring forward gap = %s, want 23h", gap)
+	}
+	autumn := nextClockTime(at(newYork, 2026, 10, 31, 7, 0), 7*time.Hour, newYork)
+	if gap := autumn.Sub(at(newYork, 2026, 10, 31, 7, 0)); gap != 25*time.Hour {
+		t.Errorf("fall back gap = %s, want 25h", gap)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+	"github.com/wilfierd/gh-notify/rules"
+)
+
+// applyFilters drops alerts from disabled categories and alerts the
+// configured filters and rules, compiled into engine, don't allow.
+func applyFilters(result *github.CheckResult, cfg *config.Config, engine *rules.Engine) {
+	if !cfg.CategoryEnabled(config.CategoryReviewRequests) {
+		result.PRsNeedingReview = nil
+	}
+	if !cfg.CategoryEnabled(config.CategoryStalePRs) {
+		result.StaleOwnPRs = nil
+	}
+	if !cfg.CategoryEnabled(config.CategoryAssignedIssues) {
+		result.AssignedIssues = nil
+	}
+	if !cfg.CategoryEnabled(config.CategoryNotifications) {
+		result.UnreadNotifications = nil
+	}
+	if !cfg.CategoryEnabled(config.CategoryInvitations) {
+		result.RepositoryInvitations = nil
+		result.Memberships = nil
+	}
+	if !cfg.CategoryEnabled(config.CategoryWorkflows) {
+		result.FailedWorkflows = nil
+	}
+
+	if engine != nil {
+		engine.FilterResult(result)
+	}
+}
+
+// applyDigestFilters drops digest entries the configured filters and rules,
+// compiled into engine, don't allow.
+func applyDigestFilters(digest *github.DailyDigest, engine *rules.Engine) {
+	if engine != nil {
+		engine.FilterDigest(digest)
+	}
+}
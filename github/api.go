@@ -3,41 +3,151 @@ package github
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
+// DefaultMaxPages caps how many pages a single list or search call follows.
+// The search API never returns more than 1000 results (10 pages of 100).
+const DefaultMaxPages = 10
+
 type Client struct {
 	token      string
 	httpClient *http.Client
 	baseURL    string
+	maxPages   int
+
+	rateMu sync.Mutex // Protects rate, updated from concurrent checker goroutines
+	rate   RateLimit
+	sleep  func(time.Duration) // Waits for retries and quota resets; replaced in tests
+
+	responseCache ResponseCache // Optional ETag/Last-Modified cache for GETs
+
+	detailsMu sync.Mutex                    // Protects details, filled from concurrent checker goroutines
+	details   map[string]pullRequestDetails // By "owner/repo#number"; see AddPullRequestDetails
+}
+
+// pullRequestDetails is what AddPullRequestDetails fetched for a pull request.
+type pullRequestDetails struct {
+	UpdatedAt time.Time // The pull request's updated_at when fetched
+	Head      *Branch
+	Reviews   []Review
+}
+
+// APIError is returned when GitHub responds with a non-success status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("GitHub API error: %s (status: %d)", e.Message, e.StatusCode)
+	}
+	return fmt.Sprintf("GitHub API error: status %d", e.StatusCode)
 }
 
 type PullRequest struct {
-	Number    int       `json:"number"`
-	Title     string    `json:"title"`
-	State     string    `json:"state"`
-	User      User      `json:"user"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	HTMLURL   string    `json:"html_url"`
-	Draft     bool      `json:"draft"`
-	Reviews   []Review  `json:"reviews,omitempty"`
+	Number        int       `json:"number"`
+	Title         string    `json:"title"`
+	State         string    `json:"state"`
+	User          User      `json:"user"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	HTMLURL       string    `json:"html_url"`
+	RepositoryURL string    `json:"repository_url"` // API URL of the repository, as returned by search
+	Labels        []Label   `json:"labels"`
+	Draft         bool      `json:"draft"`
+	Head          *Branch   `json:"head,omitempty"`    // Not in search results; see AddPullRequestDetails
+	Reviews       []Review  `json:"reviews,omitempty"` // Not in search results; see AddPullRequestDetails
 }
 
 type Issue struct {
-	Number    int       `json:"number"`
-	Title     string    `json:"title"`
-	State     string    `json:"state"`
-	User      User      `json:"user"`
-	Assignee  *User     `json:"assignee"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	HTMLURL   string    `json:"html_url"`
-	Comments  int       `json:"comments"`
+	Number        int       `json:"number"`
+	Title         string    `json:"title"`
+	State         string    `json:"state"`
+	User          User      `json:"user"`
+	Assignee      *User     `json:"assignee"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	HTMLURL       string    `json:"html_url"`
+	RepositoryURL string    `json:"repository_url"` // API URL of the repository, as returned by search
+	Labels        []Label   `json:"labels"`
+	Comments      int       `json:"comments"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+// Branch is the head or base of a pull request.
+type Branch struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+// HeadSHA returns the SHA of the pull request's head commit, if known.
+func (pr PullRequest) HeadSHA() string {
+	if pr.Head == nil {
+		return ""
+	}
+	return pr.Head.SHA
+}
+
+// ReviewState returns the state of the latest review that approved or
+// requested changes, or "" if there is none.
+func (pr PullRequest) ReviewState() string {
+	var latest Review
+	for _, review := range pr.Reviews {
+		if review.State != "APPROVED" && review.State != "CHANGES_REQUESTED" {
+			continue
+		}
+		if review.SubmittedAt.After(latest.SubmittedAt) {
+			latest = review
+		}
+	}
+	return latest.State
+}
+
+// RepoFullName returns the "owner/repo" the pull request belongs to.
+func (pr PullRequest) RepoFullName() string {
+	return repoFullName(pr.RepositoryURL, pr.HTMLURL)
+}
+
+// RepoFullName returns the "owner/repo" the issue belongs to.
+func (issue Issue) RepoFullName() string {
+	return repoFullName(issue.RepositoryURL, issue.HTMLURL)
+}
+
+// repoFullName returns "owner/repo" from a repository API URL such as
+// https://api.github.com/repos/owner/repo, falling back to the HTML URL.
+func repoFullName(repositoryURL, htmlURL string) string {
+	if _, rest, ok := strings.Cut(repositoryURL, "/repos/"); ok {
+		if owner, repo, ok := strings.Cut(rest, "/"); ok && owner != "" && repo != "" {
+			return owner + "/" + repo
+		}
+	}
+	return repoFromHTMLURL(htmlURL)
+}
+
+// repoFromHTMLURL extracts "owner/repo" from a github.com HTML URL such as
+// https://github.com/owner/repo/pull/1.
+func repoFromHTMLURL(htmlURL string) string {
+	path := htmlURL
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1] + "/" + parts[2]
 }
 
 type Review struct {
@@ -68,11 +178,12 @@ type Subject struct {
 }
 
 type Repo struct {
-	Name     string `json:"name"`
-	FullName string `json:"full_name"`
-	Private  bool   `json:"private"`
-	Fork     bool   `json:"fork"`
-	Archived bool   `json:"archived"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Private       bool   `json:"private"`
+	Fork          bool   `json:"fork"`
+	Archived      bool   `json:"archived"`
+	DefaultBranch string `json:"default_branch,omitempty"`
 }
 
 type Invitation struct {
@@ -111,9 +222,16 @@ type WorkflowRun struct {
 	CreatedAt  time.Time `json:"created_at"`
 	HTMLURL    string    `json:"html_url"`
 	Name       string    `json:"name"`
+	HeadBranch string    `json:"head_branch"`
 	Repository Repo      `json:"repository"`
 }
 
+// OnDefaultBranch reports whether the run is for the repository's default
+// branch.
+func (w WorkflowRun) OnDefaultBranch() bool {
+	return w.HeadBranch != "" && w.HeadBranch == w.Repository.DefaultBranch
+}
+
 type Commit struct {
 	SHA        string    `json:"sha"`
 	Message    string    `json:"message"`
@@ -142,136 +260,352 @@ func NewClient(token string) *Client {
 		token:      token,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 		baseURL:    "https://api.github.com",
+		maxPages:   DefaultMaxPages,
+		details:    make(map[string]pullRequestDetails),
+		sleep:      time.Sleep,
 	}
 }
 
+// SetMaxPages sets how many pages list and search calls follow before stopping.
+// Values below 1 are treated as 1.
+func (c *Client) SetMaxPages(maxPages int) {
+	if maxPages < 1 {
+		maxPages = 1
+	}
+	c.maxPages = maxPages
+}
+
+// makeRequest sends a request, retrying with backoff on 5xx responses,
+// network errors and rate limits whose reset is within maxWait. Requests that
+// stay rate limited fail with a *RateLimitError.
 func (c *Client) makeRequest(method, url string, body interface{}) (*http.Response, error) {
-	var reqBody io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+	cached, hasCached := c.cachedResponse(method, url)
 
-	req.Header.Set("Authorization", "token "+c.token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+	for attempt := 0; ; attempt++ {
+		// Honor X-Poll-Interval: don't hit the endpoint again before it allows
+		if hasCached && time.Now().Before(cached.PollAfter) {
+			req, err := http.NewRequest(method, url, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			return serveCached(req, cached), nil
+		}
+
+		if err := c.waitForQuota(); err != nil {
+			return nil, err
+		}
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(jsonBody)
+		}
+
+		req, err := http.NewRequest(method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Authorization", "token "+c.token)
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if hasCached {
+			setConditionalHeaders(req, cached)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if attempt < maxRetries {
+				c.sleep(backoff(attempt))
+				continue
+			}
+			return nil, err
+		}
+
+		c.updateRateLimit(resp.Header)
+
+		switch {
+		case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+			respBody, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response body: %w", err)
+			}
+
+			rateLimitErr := rateLimitFromResponse(resp, respBody)
+			if rateLimitErr == nil {
+				// Plain permission error: hand the body back to the caller
+				resp.Body = io.NopCloser(bytes.NewReader(respBody))
+				return resp, nil
+			}
+			if attempt >= maxRetries || rateLimitErr.RetryAfter > maxWait {
+				return nil, rateLimitErr
+			}
+
+			slog.Warn("GitHub rate limited, retrying", "url", url, "retry_after", rateLimitErr.RetryAfter.Round(time.Second))
+			c.sleep(rateLimitErr.RetryAfter)
+
+		case resp.StatusCode >= 500 && attempt < maxRetries:
+			resp.Body.Close()
+			slog.Warn("GitHub server error, retrying", "url", url, "status", resp.StatusCode)
+			c.sleep(backoff(attempt))
+
+		default:
+			if c.responseCache != nil && method == http.MethodGet {
+				return c.cacheResponse(url, resp, cached, hasCached)
+			}
+			return resp, nil
+		}
 	}
+}
 
-	return c.httpClient.Do(req)
+// getPaginated GETs url and follows the Link header's rel="next" URLs, passing
+// each page body to decode. It stops after c.maxPages pages.
+func (c *Client) getPaginated(url string, decode func(body []byte) error) error {
+	_, err := c.getPages(url, decode)
+	return err
 }
 
-func (c *Client) GetUserPullRequests(username string) ([]PullRequest, error) {
-	url := fmt.Sprintf("%s/search/issues?q=type:pr+author:%s+state:open", c.baseURL, username)
+// getPages is getPaginated, also reporting whether every page was read
+// rather than stopping at c.maxPages.
+func (c *Client) getPages(url string, decode func(body []byte) error) (complete bool, err error) {
+	for page := 1; url != ""; page++ {
+		if page > c.maxPages {
+			slog.Warn("stopped paginating, results are truncated", "url", url, "max_pages", c.maxPages)
+			return false, nil
+		}
 
-	resp, err := c.makeRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pull requests: %w", err)
+		resp, err := c.makeRequest("GET", url, nil)
+		if err != nil {
+			return false, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return false, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return false, newAPIError(resp.StatusCode, body)
+		}
+
+		if err := decode(body); err != nil {
+			return false, err
+		}
+
+		url = nextPageURL(resp.Header.Get("Link"))
 	}
-	defer resp.Body.Close()
 
-	var result struct {
-		Items []PullRequest `json:"items"`
+	return true, nil
+}
+
+// nextPageURL extracts the rel="next" URL from a GitHub Link header.
+func nextPageURL(linkHeader string) string {
+	for _, link := range strings.Split(linkHeader, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
 	}
+	return ""
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+func newAPIError(statusCode int, body []byte) *APIError {
+	var errorResp struct {
+		Message string `json:"message"`
 	}
+	_ = json.Unmarshal(body, &errorResp)
+	return &APIError{StatusCode: statusCode, Message: errorResp.Message}
+}
 
-	return result.Items, nil
+// searchIssues runs an issue/PR search query across all result pages.
+func searchIssues[T any](c *Client, query string) ([]T, error) {
+	items, _, err := search[T](c, query)
+	return items, err
 }
 
-func (c *Client) GetReviewRequests(username string) ([]PullRequest, error) {
-	url := fmt.Sprintf("%s/search/issues?q=type:pr+review-requested:%s+state:open", c.baseURL, username)
+// search is searchIssues, also reporting whether the results are complete:
+// not cut off by max_pages, and not partial because the search timed out.
+func search[T any](c *Client, query string) (items []T, complete bool, err error) {
+	url := fmt.Sprintf("%s/search/issues?q=%s&per_page=100", c.baseURL, query)
 
-	resp, err := c.makeRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get review requests: %w", err)
-	}
-	defer resp.Body.Close()
+	timedOut := false
+	complete, err = c.getPages(url, func(body []byte) error {
+		var result struct {
+			IncompleteResults bool `json:"incomplete_results"`
+			Items             []T  `json:"items"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		timedOut = timedOut || result.IncompleteResults
+		items = append(items, result.Items...)
+		return nil
+	})
 
-	var result struct {
-		Items []PullRequest `json:"items"`
-	}
+	return items, complete && !timedOut, err
+}
+
+func (c *Client) GetUserPullRequests(username string) ([]PullRequest, error) {
+	prs, _, err := c.getUserPullRequests(username)
+	return prs, err
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// getUserPullRequests is GetUserPullRequests, also reporting whether the
+// list is complete (see search).
+func (c *Client) getUserPullRequests(username string) ([]PullRequest, bool, error) {
+	prs, complete, err := search[PullRequest](c, fmt.Sprintf("type:pr+author:%s+state:open", username))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get pull requests: %w", err)
 	}
 
-	return result.Items, nil
+	return prs, complete, nil
 }
 
-func (c *Client) GetAssignedIssues(username string) ([]Issue, error) {
-	url := fmt.Sprintf("%s/search/issues?q=type:issue+assignee:%s+state:open", c.baseURL, username)
-	fmt.Printf("DEBUG: GetAssignedIssues API call: %s\n", url)
+func (c *Client) GetReviewRequests(username string) ([]PullRequest, error) {
+	prs, _, err := c.getReviewRequests(username)
+	return prs, err
+}
 
-	resp, err := c.makeRequest("GET", url, nil)
+// getReviewRequests is GetReviewRequests, also reporting whether the list is
+// complete (see search).
+func (c *Client) getReviewRequests(username string) ([]PullRequest, bool, error) {
+	prs, complete, err := search[PullRequest](c, fmt.Sprintf("type:pr+review-requested:%s+state:open", username))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get assigned issues: %w", err)
+		return nil, false, fmt.Errorf("failed to get review requests: %w", err)
 	}
-	defer resp.Body.Close()
 
-	var result struct {
-		Items []Issue `json:"items"`
-	}
+	return prs, complete, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+// AddPullRequestDetails fills in each pull request's head commit and reviews,
+// which search results don't include. They are kept with the head SHA and
+// reused until the pull request's updated_at moves, which a push or review
+// does, so the two extra requests are only made for pull requests that
+// changed. It stops early, returning a *RateLimitError, rather than spend the
+// last of the quota.
+func (c *Client) AddPullRequestDetails(prs []PullRequest) error {
+	for i := range prs {
+		pr := &prs[i]
+		repo := pr.RepoFullName()
+		key := fmt.Sprintf("%s#%d", repo, pr.Number)
+
+		c.detailsMu.Lock()
+		cached, ok := c.details[key]
+		c.detailsMu.Unlock()
+		if ok && cached.UpdatedAt.Equal(pr.UpdatedAt) {
+			pr.Head, pr.Reviews = cached.Head, cached.Reviews
+			continue
+		}
 
-	fmt.Printf("DEBUG: GetAssignedIssues returned %d issues\n", len(result.Items))
-	for i, issue := range result.Items {
-		assigneeInfo := "no assignee"
-		if issue.Assignee != nil {
-			assigneeInfo = fmt.Sprintf("assigned to %s", issue.Assignee.Login)
+		if c.quotaLow() {
+			return c.quotaError()
 		}
-		fmt.Printf("  Issue %d: #%d - %s (State: %s, %s)\n", i+1, issue.Number, issue.Title, issue.State, assigneeInfo)
-	}
 
-	return result.Items, nil
-}
+		var details struct {
+			Head *Branch `json:"head"`
+		}
+		if err := c.getJSON(fmt.Sprintf("%s/repos/%s/pulls/%d", c.baseURL, repo, pr.Number), &details); err != nil {
+			if IsRateLimited(err) {
+				return err
+			}
+			slog.Warn("failed to get pull request", "repo", repo, "number", pr.Number, "error", err)
+			continue
+		}
+		pr.Head = details.Head
 
-func (c *Client) GetNotifications() ([]Notification, error) {
-	url := fmt.Sprintf("%s/notifications?all=false&participating=false", c.baseURL)
+		if err := c.getJSON(fmt.Sprintf("%s/repos/%s/pulls/%d/reviews?per_page=100", c.baseURL, repo, pr.Number), &pr.Reviews); err != nil {
+			if IsRateLimited(err) {
+				return err
+			}
+			slog.Warn("failed to get pull request reviews", "repo", repo, "number", pr.Number, "error", err)
+			continue
+		}
 
+		c.detailsMu.Lock()
+		c.details[key] = pullRequestDetails{UpdatedAt: pr.UpdatedAt, Head: pr.Head, Reviews: pr.Reviews}
+		c.detailsMu.Unlock()
+	}
+	return nil
+}
+
+// getJSON GETs url and decodes a 200 response into v.
+func (c *Client) getJSON(url string, v interface{}) error {
 	resp, err := c.makeRequest("GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get notifications: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
-	// Read the response body first
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
-
-	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		var errorResp struct {
-			Message string `json:"message"`
-		}
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			// Handle specific permission errors
-			if resp.StatusCode == http.StatusForbidden && errorResp.Message == "Resource not accessible by personal access token" {
-				return nil, fmt.Errorf("GitHub token missing 'notifications' permission. Please regenerate token with proper permissions")
-			}
-			return nil, fmt.Errorf("GitHub API error: %s (status: %d)", errorResp.Message, resp.StatusCode)
-		}
-		return nil, fmt.Errorf("GitHub API error: status %d", resp.StatusCode)
+		return newAPIError(resp.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetAssignedIssues(username string) ([]Issue, error) {
+	issues, _, err := c.getAssignedIssues(username)
+	return issues, err
+}
+
+// getAssignedIssues is GetAssignedIssues, also reporting whether the list is
+// complete (see search).
+func (c *Client) getAssignedIssues(username string) ([]Issue, bool, error) {
+	query := fmt.Sprintf("type:issue+assignee:%s+state:open", username)
+
+	issues, complete, err := search[Issue](c, query)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get assigned issues: %w", err)
 	}
 
+	slog.Debug("searched assigned issues", "query", query, "count", len(issues), "complete", complete)
+
+	return issues, complete, nil
+}
+
+func (c *Client) GetNotifications() ([]Notification, error) {
+	url := fmt.Sprintf("%s/notifications?all=false&participating=false&per_page=50", c.baseURL)
+
 	var notifications []Notification
-	if err := json.Unmarshal(body, &notifications); err != nil {
-		return nil, fmt.Errorf("failed to decode notifications: %w", err)
+	err := c.getPaginated(url, func(body []byte) error {
+		var page []Notification
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to decode notifications: %w", err)
+		}
+		notifications = append(notifications, page...)
+		return nil
+	})
+	if err != nil {
+		// Handle specific permission errors
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden &&
+			apiErr.Message == "Resource not accessible by personal access token" {
+			return nil, fmt.Errorf("GitHub token missing 'notifications' permission. Please regenerate token with proper permissions")
+		}
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
 	}
 
 	return notifications, nil
@@ -298,6 +632,9 @@ func (c *Client) GetRepositoryInvitations() ([]Invitation, error) {
 	return invitations, nil
 }
 
+// GetRecentWorkflowRuns returns recent failed workflow runs from the user's
+// public repositories. When rate limited it returns the runs gathered so far
+// together with a *RateLimitError.
 func (c *Client) GetRecentWorkflowRuns(username string) ([]WorkflowRun, error) {
 	// Get user repositories first
 	repos, err := c.GetUserRepositories(username)
@@ -321,6 +658,11 @@ func (c *Client) GetRecentWorkflowRuns(username string) ([]WorkflowRun, error) {
 			continue
 		}
 
+		// Stop early rather than spend the last of the quota on optional data
+		if c.quotaLow() {
+			return allFailedWorkflows, c.quotaError()
+		}
+
 		repoCount++
 
 		// Get workflow runs for this repository (only failed ones, last 3 results)
@@ -329,8 +671,11 @@ func (c *Client) GetRecentWorkflowRuns(username string) ([]WorkflowRun, error) {
 
 		resp, err := c.makeRequest("GET", url, nil)
 		if err != nil {
+			if IsRateLimited(err) {
+				return allFailedWorkflows, err
+			}
 			// Log warning but continue with other repos
-			fmt.Printf("Warning: failed to get workflow runs for %s: %v\n", repo.FullName, err)
+			slog.Warn("failed to get workflow runs", "repo", repo.FullName, "error", err)
 			continue
 		}
 		defer resp.Body.Close()
@@ -348,12 +693,13 @@ func (c *Client) GetRecentWorkflowRuns(username string) ([]WorkflowRun, error) {
 				Conclusion string    `json:"conclusion"`
 				CreatedAt  time.Time `json:"created_at"`
 				HTMLURL    string    `json:"html_url"`
+				HeadBranch string    `json:"head_branch"`
 			} `json:"workflow_runs"`
 		}
 
 		if err := json.NewDecoder(resp.Body).Decode(&workflowResponse); err != nil {
 			// Log warning but continue
-			fmt.Printf("Warning: failed to decode workflow runs for %s: %v\n", repo.FullName, err)
+			slog.Warn("failed to decode workflow runs", "repo", repo.FullName, "error", err)
 			continue
 		}
 
@@ -368,6 +714,7 @@ func (c *Client) GetRecentWorkflowRuns(username string) ([]WorkflowRun, error) {
 					Conclusion: run.Conclusion,
 					CreatedAt:  run.CreatedAt,
 					HTMLURL:    run.HTMLURL,
+					HeadBranch: run.HeadBranch,
 					Repository: repo,
 				}
 				allFailedWorkflows = append(allFailedWorkflows, workflowRun)
@@ -396,23 +743,22 @@ func (c *Client) GetUser() (*User, error) {
 }
 
 func (c *Client) GetRecentCommits(repo string, since time.Time) ([]Commit, error) {
-	url := fmt.Sprintf("%s/repos/%s/commits?since=%s",
-		c.baseURL, repo, since.Format(time.RFC3339))
+	// In UTC, so no "+07:00" offset ends up unescaped in the query
+	url := fmt.Sprintf("%s/repos/%s/commits?since=%s&per_page=100",
+		c.baseURL, repo, since.UTC().Format(time.RFC3339))
 
-	resp, err := c.makeRequest("GET", url, nil)
+	var commitResponses []CommitResponse
+	err := c.getPaginated(url, func(body []byte) error {
+		var page []CommitResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to decode commits: %w", err)
+		}
+		commitResponses = append(commitResponses, page...)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commits: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API error: status %d", resp.StatusCode)
-	}
-
-	var commitResponses []CommitResponse
-	if err := json.NewDecoder(resp.Body).Decode(&commitResponses); err != nil {
-		return nil, fmt.Errorf("failed to decode commits: %w", err)
-	}
 
 	commits := make([]Commit, len(commitResponses))
 	for i, cr := range commitResponses {
@@ -454,46 +800,36 @@ func (c *Client) GetUserByUsername(username string) (*User, error) {
 }
 
 func (c *Client) GetUserIssues(username string) ([]Issue, error) {
-	url := fmt.Sprintf("%s/search/issues?q=type:issue+author:%s", c.baseURL, username)
-
-	resp, err := c.makeRequest("GET", url, nil)
+	issues, err := searchIssues[Issue](c, fmt.Sprintf("type:issue+author:%s", username))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user issues: %w", err)
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Items []Issue `json:"items"`
-	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	return result.Items, nil
+	return issues, nil
 }
 
 func (c *Client) GetUserRepositories(username string) ([]Repo, error) {
 	url := fmt.Sprintf("%s/users/%s/repos?type=all&sort=updated&per_page=100", c.baseURL, username)
 
-	resp, err := c.makeRequest("GET", url, nil)
+	var repos []Repo
+	err := c.getPaginated(url, func(body []byte) error {
+		var page []Repo
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to decode repositories: %w", err)
+		}
+		repos = append(repos, page...)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user repositories: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API error: status %d", resp.StatusCode)
-	}
-
-	var repos []Repo
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return nil, fmt.Errorf("failed to decode repositories: %w", err)
-	}
 
 	return repos, nil
 }
 
+// GetRecentCommitsFromAllRepos gets the user's commits since the given time across
+// their repositories. When rate limited it returns the commits gathered so far
+// together with a *RateLimitError.
 func (c *Client) GetRecentCommitsFromAllRepos(username string, since time.Time) ([]Commit, error) {
 	// Get all user repositories
 	repos, err := c.GetUserRepositories(username)
@@ -510,10 +846,19 @@ func (c *Client) GetRecentCommitsFromAllRepos(username string, since time.Time)
 			continue
 		}
 
+		// Stop early rather than spend the last of the quota on optional data
+		if c.quotaLow() {
+			return allCommits, c.quotaError()
+		}
+
 		commits, err := c.GetRecentCommits(repo.FullName, since)
 		if err != nil {
+			// Return what we have so far once rate limited
+			if IsRateLimited(err) {
+				return allCommits, err
+			}
 			// Log error but continue with other repos
-			fmt.Printf("Warning: failed to get commits for repo %s: %v\n", repo.FullName, err)
+			slog.Warn("failed to get commits", "repo", repo.FullName, "error", err)
 			continue
 		}
 
@@ -542,24 +887,24 @@ func (c *Client) GetRecentCommitsFromSelectedRepos(username string, since time.T
 			if !strings.Contains(repoName, "/") {
 				repoName = fmt.Sprintf("%s/%s", username, repoName)
 			}
-			
+
 			// Get repository info
 			url := fmt.Sprintf("%s/repos/%s", c.baseURL, repoName)
 			resp, err := c.makeRequest("GET", url, nil)
 			if err != nil {
-				fmt.Printf("Warning: failed to get repo %s: %v\n", repoName, err)
+				slog.Warn("failed to get repository", "repo", repoName, "error", err)
 				continue
 			}
 			defer resp.Body.Close()
-			
+
 			if resp.StatusCode == http.StatusNotFound {
-				fmt.Printf("Warning: repository %s not found\n", repoName)
+				slog.Warn("repository not found", "repo", repoName)
 				continue
 			}
-			
+
 			var repo Repo
 			if err := json.NewDecoder(resp.Body).Decode(&repo); err != nil {
-				fmt.Printf("Warning: failed to decode repo %s: %v\n", repoName, err)
+				slog.Warn("failed to decode repository", "repo", repoName, "error", err)
 				continue
 			}
 			repos = append(repos, repo)
@@ -580,10 +925,19 @@ func (c *Client) GetRecentCommitsFromSelectedRepos(username string, since time.T
 			continue
 		}
 
+		// Stop early rather than spend the last of the quota on optional data
+		if c.quotaLow() {
+			return allCommits, c.quotaError()
+		}
+
 		commits, err := c.GetRecentCommits(repo.FullName, since)
 		if err != nil {
+			// Return what we have so far once rate limited
+			if IsRateLimited(err) {
+				return allCommits, err
+			}
 			// Log error but continue with other repos
-			fmt.Printf("Warning: failed to get commits for repo %s: %v\n", repo.FullName, err)
+			slog.Warn("failed to get commits", "repo", repo.FullName, "error", err)
 			continue
 		}
 
@@ -0,0 +1,217 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSearchComplete(t *testing.T) {
+	tests := []struct {
+		name         string
+		pages        int
+		maxPages     int
+		timedOut     bool
+		wantItems    int
+		wantComplete bool
+	}{
+		{"single page", 1, 10, false, 1, true},
+		{"every page read", 3, 3, false, 3, true},
+		{"cut off by max_pages", 3, 2, false, 2, false},
+		{"search timed out", 1, 10, true, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				page := 1
+				fmt.Sscan(r.URL.Query().Get("page"), &page)
+				if page < tt.pages {
+					w.Header().Set("Link", fmt.Sprintf(`<%s/search/issues?page=%d>; rel="next"`, server.URL, page+1))
+				}
+				fmt.Fprintf(w, `{"incomplete_results": %v, "items": [{"number": %d}]}`, tt.timedOut, page)
+			}))
+			defer server.Close()
+
+			client := NewClient("token")
+			client.baseURL = server.URL
+			client.SetMaxPages(tt.maxPages)
+
+			items, complete, err := search[Issue](client, "type:issue")
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if len(items) != tt.wantItems || complete != tt.wantComplete {
+				t.Errorf("got %d items, complete %v; want %d, %v", len(items), complete, tt.wantItems, tt.wantComplete)
+			}
+		})
+	}
+}
+
+func TestAddPullRequestDetailsCached(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	headSHA := "aaa"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if strings.HasSuffix(r.URL.Path, "/reviews") {
+			w.Write([]byte(`[{"state": "APPROVED", "submitted_at": "2026-10-16T08:00:00Z"}]`))
+			return
+		}
+		fmt.Fprintf(w, `{"head": {"ref": "main", "sha": %q}}`, headSHA)
+	}))
+	defer server.Close()
+
+	client := NewClient("token")
+	client.baseURL = server.URL
+	updated := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	prs := func() []PullRequest {
+		return []PullRequest{{Number: 7, UpdatedAt: updated, RepositoryURL: "https://api.github.com/repos/owner/repo"}}
+	}
+
+	first := prs()
+	if err := client.AddPullRequestDetails(first); err != nil {
+		t.Fatalf("AddPullRequestDetails: %v", err)
+	}
+	if first[0].HeadSHA() != "aaa" || first[0].ReviewState() != "APPROVED" || requests != 2 {
+		t.Fatalf("head %q, review %q after %d requests; want aaa, APPROVED after 2",
+			first[0].HeadSHA(), first[0].ReviewState(), requests)
+	}
+
+	// Unchanged since: served from the cache
+	second := prs()
+	if err := client.AddPullRequestDetails(second); err != nil {
+		t.Fatalf("AddPullRequestDetails: %v", err)
+	}
+	if second[0].HeadSHA() != "aaa" || requests != 2 {
+		t.Errorf("head %q after %d requests, want aaa without new requests", second[0].HeadSHA(), requests)
+	}
+
+	// A push moves updated_at and the head
+	headSHA = "bbb"
+	updated = updated.Add(time.Hour)
+	third := prs()
+	if err := client.AddPullRequestDetails(third); err != nil {
+		t.Fatalf("AddPullRequestDetails: %v", err)
+	}
+	if third[0].HeadSHA() != "bbb" || requests != 4 {
+		t.Errorf("head %q after %d requests, want bbb after 4", third[0].HeadSHA(), requests)
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"multi-page",
+			`<https://api.github.com/user/repos?page=3>; rel="next", <https://api.github.com/user/repos?page=50>; rel="last", ` +
+				`<https://api.github.com/user/repos?page=1>; rel="first", <https://api.github.com/user/repos?page=1>; rel="prev"`,
+			"https://api.github.com/user/repos?page=3"},
+		{"next not first", `<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=3>; rel="next"`,
+			"https://api.github.com/x?page=3"},
+		{"extra parameters", `<https://api.github.com/x?page=2>; type="json"; rel="next"`, "https://api.github.com/x?page=2"},
+		{"last page", `<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=4>; rel="prev"`, ""},
+		{"no header", "", ""},
+		{"no parameters", `<https://api.github.com/x?page=2>`, ""},
+		{"unquoted rel", `<https://api.github.com/x?page=2>; rel=next`, ""},
+		{"garbage", `;;,,; rel="nope"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPageURL(tt.header); got != tt.want {
+				t.Errorf("nextPageURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPages(t *testing.T) {
+	tests := []struct {
+		name         string
+		pages        int
+		link         func(server string, page int) string
+		maxPages     int
+		wantPages    []int
+		wantComplete bool
+	}{
+		{"follows every page", 3, nextLink, 10, []int{1, 2, 3}, true},
+		{"stops at the page cap", 5, nextLink, 2, []int{1, 2}, false},
+		{"cap equals the pages", 3, nextLink, 3, []int{1, 2, 3}, true},
+		{"no rel next", 3, func(server string, page int) string {
+			return fmt.Sprintf(`<%s/items?page=%d>; rel="last"`, server, 3)
+		}, 10, []int{1}, true},
+		{"malformed header", 3, func(server string, page int) string {
+			return fmt.Sprintf(`%s/items?page=%d; rel=next`, server, page+1)
+		}, 10, []int{1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				page := 1
+				fmt.Sscan(r.URL.Query().Get("page"), &page)
+				if page < tt.pages {
+					w.Header().Set("Link", tt.link(server.URL, page))
+				}
+				fmt.Fprintf(w, "%d", page)
+			}))
+			defer server.Close()
+
+			client, _ := newTestClient(server.URL)
+			client.SetMaxPages(tt.maxPages)
+
+			var pages []int
+			complete, err := client.getPages(server.URL+"/items", func(body []byte) error {
+				var page int
+				_, err := fmt.Sscan(string(body), &page)
+				pages = append(pages, page)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("getPages: %v", err)
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.wantPages) || complete != tt.wantComplete {
+				t.Errorf("read pages %v, complete %v; want %v, %v", pages, complete, tt.wantPages, tt.wantComplete)
+			}
+		})
+	}
+}
+
+func nextLink(server string, page int) string {
+	return fmt.Sprintf(`<%s/items?page=%d>; rel="next", <%s/items?page=99>; rel="last"`, server, page+1, server)
+}
+
+func TestGetPagesStopsAtErrors(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=2>; rel="next"`, server.URL))
+		w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server.URL)
+	decoded := 0
+	complete, err := client.getPages(server.URL+"/items", func(body []byte) error {
+		decoded++
+		return nil
+	})
+	if err == nil || complete {
+		t.Errorf("getPages = %v, %v; want an error for the missing page", complete, err)
+	}
+	if decoded != 1 {
+		t.Errorf("decoded %d pages, want 1", decoded)
+	}
+}
@@ -3,9 +3,11 @@ package github
 import (
 	"context"
 	"fmt"
-	"os"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/wilfierd/gh-notify/config"
 )
 
 type CheckResult struct {
@@ -15,7 +17,26 @@ type CheckResult struct {
 	UnreadNotifications   []Notification
 	FailedWorkflows       []WorkflowRun
 	RepositoryInvitations []Invitation
-	RecentCommits         []Commit // New field for real-time commit tracking
+	RecentCommits         []Commit          // New field for real-time commit tracking
+	Reviews               []ReviewedPR      // Approvals and change requests on the user's PRs, from webhooks
+	Memberships           []Membership      // Repositories the user was added to, from webhooks
+	SkippedSections       []string          // Optional sections skipped or truncated by rate limits
+	Changes               map[string]string // Why an item is alerted again, keyed by its HTMLURL
+	Incomplete            []string          // Alert categories whose list may be missing items (see Complete)
+}
+
+// ReviewedPR is one of the user's pull requests with the review just
+// submitted on it.
+type ReviewedPR struct {
+	PullRequest
+	Review Review
+}
+
+// Membership is a repository the user was added to as a collaborator, and
+// who added them.
+type Membership struct {
+	Repository EventRepository
+	AddedBy    User
 }
 
 type DailyDigest struct {
@@ -30,15 +51,20 @@ type DailyDigest struct {
 	AssignedIssues        []Issue
 	RepositoryInvitations []Invitation // Add invitations to daily digest
 	Date                  time.Time
-	IsEvening             bool // true for evening digest, false for morning
+	IsEvening             bool      // true for evening digest, false for morning
+	IsWeekly              bool      // true for the weekly summary (an evening-style digest over 7 days)
+	Since                 time.Time // Start of the period an evening or weekly digest covers
+	SkippedSections       []string  // Optional sections skipped or truncated by rate limits
 }
 
-func (c *Client) CheckForAlerts(username string) (*CheckResult, error) {
-	return c.CheckForAlertsWithCommits(username, false, nil, 0)
+// CheckForAlerts reports the user's open PRs without activity for staleAfter
+// as stale, along with review requests, assigned issues and notifications.
+func (c *Client) CheckForAlerts(username string, staleAfter time.Duration) (*CheckResult, error) {
+	return c.CheckForAlertsWithCommits(username, staleAfter, false, nil, 0)
 }
 
 // CheckForAlertsWithCommits includes optional commit tracking based on configuration
-func (c *Client) CheckForAlertsWithCommits(username string, trackCommits bool, trackedRepos []string, lookbackMinutes int) (*CheckResult, error) {
+func (c *Client) CheckForAlertsWithCommits(username string, staleAfter time.Duration, trackCommits bool, trackedRepos []string, lookbackMinutes int) (*CheckResult, error) {
 	result := &CheckResult{}
 
 	// Create context with timeout for all API calls
@@ -55,62 +81,80 @@ func (c *Client) CheckForAlertsWithCommits(username string, trackCommits bool, t
 	// Channel to collect errors from goroutines
 	errChan := make(chan error, 6) // Buffer for 6 potential errors
 
-	fmt.Println("DEBUG: Starting parallel API calls...")
+	slog.Debug("checking for alerts")
 	startTime := time.Now()
 
 	// 1. Get PRs that need review
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		reviewRequests, err := c.GetReviewRequests(username)
+		reviewRequests, complete, err := c.getReviewRequests(username)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to get review requests: %w", err)
 			return
 		}
+		// Head commits let new pushes re-alert reviewers
+		detailsErr := c.AddPullRequestDetails(reviewRequests)
 		mu.Lock()
+		if detailsErr != nil {
+			result.SkippedSections = append(result.SkippedSections, "review request details")
+		}
+		if !complete {
+			result.Incomplete = append(result.Incomplete, config.CategoryReviewRequests)
+		}
 		result.PRsNeedingReview = reviewRequests
 		mu.Unlock()
-		fmt.Println("DEBUG: Completed review requests")
+		slog.Debug("fetched review requests", "count", len(reviewRequests))
 	}()
 
 	// 2. Get user's own PRs that might be stale
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		ownPRs, err := c.GetUserPullRequests(username)
+		ownPRs, complete, err := c.getUserPullRequests(username)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to get user PRs: %w", err)
 			return
 		}
 
-		// Filter for stale PRs (older than 2 days, no recent activity)
-		staleDuration := 48 * time.Hour
+		// Filter for stale PRs (no activity for staleAfter)
 		var stalePRs []PullRequest
 		for _, pr := range ownPRs {
-			if time.Since(pr.UpdatedAt) > staleDuration && !pr.Draft {
+			if time.Since(pr.UpdatedAt) > staleAfter && !pr.Draft {
 				stalePRs = append(stalePRs, pr)
 			}
 		}
 
+		// Reviews let a new approval or change request re-alert the author
+		detailsErr := c.AddPullRequestDetails(stalePRs)
 		mu.Lock()
+		if detailsErr != nil {
+			result.SkippedSections = append(result.SkippedSections, "stale pull request details")
+		}
+		if !complete {
+			result.Incomplete = append(result.Incomplete, config.CategoryStalePRs)
+		}
 		result.StaleOwnPRs = stalePRs
 		mu.Unlock()
-		fmt.Println("DEBUG: Completed user PRs and stale filtering")
+		slog.Debug("fetched own pull requests", "count", len(ownPRs), "stale", len(stalePRs))
 	}()
 
 	// 3. Get assigned issues
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		assignedIssues, err := c.GetAssignedIssues(username)
+		assignedIssues, complete, err := c.getAssignedIssues(username)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to get assigned issues: %w", err)
 			return
 		}
 		mu.Lock()
+		if !complete {
+			result.Incomplete = append(result.Incomplete, config.CategoryAssignedIssues)
+		}
 		result.AssignedIssues = assignedIssues
 		mu.Unlock()
-		fmt.Println("DEBUG: Completed assigned issues")
+		slog.Debug("fetched assigned issues", "count", len(assignedIssues))
 	}()
 
 	// 4. Get unread notifications
@@ -120,8 +164,13 @@ func (c *Client) CheckForAlertsWithCommits(username string, trackCommits bool, t
 		notifications, err := c.GetNotifications()
 		if err != nil {
 			// Don't fail the whole check if notifications fail due to permissions
-			fmt.Printf("Warning: failed to get notifications: %v\n", err)
+			slog.Warn("failed to get notifications", "error", err)
 			notifications = []Notification{}
+			if IsRateLimited(err) {
+				mu.Lock()
+				result.SkippedSections = append(result.SkippedSections, "notifications")
+				mu.Unlock()
+			}
 		}
 
 		var unreadNotifications []Notification
@@ -134,7 +183,7 @@ func (c *Client) CheckForAlertsWithCommits(username string, trackCommits bool, t
 		mu.Lock()
 		result.UnreadNotifications = unreadNotifications
 		mu.Unlock()
-		fmt.Println("DEBUG: Completed notifications")
+		slog.Debug("fetched notifications", "unread", len(unreadNotifications))
 	}()
 
 	// 5. Get repository invitations
@@ -144,13 +193,16 @@ func (c *Client) CheckForAlertsWithCommits(username string, trackCommits bool, t
 		invitations, err := c.GetRepositoryInvitations()
 		if err != nil {
 			// Don't fail the whole check if invitations fail
-			fmt.Printf("Warning: failed to get repository invitations: %v\n", err)
+			slog.Warn("failed to get repository invitations", "error", err)
 			invitations = []Invitation{}
 		}
 		mu.Lock()
+		if IsRateLimited(err) {
+			result.SkippedSections = append(result.SkippedSections, "repository invitations")
+		}
 		result.RepositoryInvitations = invitations
 		mu.Unlock()
-		fmt.Println("DEBUG: Completed repository invitations")
+		slog.Debug("fetched repository invitations", "count", len(invitations))
 	}()
 
 	// 6. Get recent workflow failures
@@ -159,14 +211,20 @@ func (c *Client) CheckForAlertsWithCommits(username string, trackCommits bool, t
 		defer wg.Done()
 		failedWorkflows, err := c.GetRecentWorkflowRuns(username)
 		if err != nil {
-			// Don't fail the whole check if workflows fail
-			fmt.Printf("Warning: failed to get workflow runs: %v\n", err)
-			failedWorkflows = []WorkflowRun{}
+			// Don't fail the whole check if workflows fail; keep partial
+			// results when we were stopped by the rate limit
+			slog.Warn("failed to get workflow runs", "error", err)
+			if !IsRateLimited(err) {
+				failedWorkflows = []WorkflowRun{}
+			}
 		}
 		mu.Lock()
+		if IsRateLimited(err) {
+			result.SkippedSections = append(result.SkippedSections, "failed workflows")
+		}
 		result.FailedWorkflows = failedWorkflows
 		mu.Unlock()
-		fmt.Println("DEBUG: Completed workflow runs")
+		slog.Debug("fetched failed workflow runs", "count", len(failedWorkflows))
 	}()
 
 	// Wait for all goroutines to complete
@@ -197,36 +255,43 @@ func (c *Client) CheckForAlertsWithCommits(username string, trackCommits bool, t
 	// Get recent commits if tracking is enabled (this runs after parallel calls)
 	if trackCommits && lookbackMinutes > 0 {
 		since := time.Now().Add(-time.Duration(lookbackMinutes) * time.Minute)
-		fmt.Printf("DEBUG: Checking for commits since %s (last %d minutes)\n", since.Format(time.RFC3339), lookbackMinutes)
+		slog.Debug("checking for recent commits", "since", since)
 
 		recentCommits, err := c.GetRecentCommitsFromSelectedRepos(username, since, trackedRepos)
 		if err != nil {
 			// Don't fail the whole check if commit fetching fails
-			fmt.Printf("Warning: failed to get recent commits: %v\n", err)
-			recentCommits = []Commit{}
+			slog.Warn("failed to get recent commits", "error", err)
+			if IsRateLimited(err) {
+				result.SkippedSections = append(result.SkippedSections, "recent commits")
+			} else {
+				recentCommits = []Commit{}
+			}
 		}
 
-		fmt.Printf("DEBUG: Found %d recent commits\n", len(recentCommits))
+		slog.Debug("fetched recent commits", "count", len(recentCommits))
 		result.RecentCommits = recentCommits
 	}
 
-	elapsed := time.Since(startTime)
-	fmt.Printf("DEBUG: Parallel API calls completed in %v\n", elapsed)
+	slog.Debug("alert check completed", "elapsed", time.Since(startTime))
 
 	return result, nil
 }
 
-func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool) (*DailyDigest, error) {
-	now := time.Now()
+// IsEveningAt reports whether a digest generated at now should be the evening
+// summary, i.e. whether it is past noon in now's location.
+func IsEveningAt(now time.Time) bool {
+	return now.Hour() >= 12
+}
 
-	// Determine if this is evening digest (after 12 PM UTC = 7 PM Vietnam)
-	// For manual testing, force morning mode when CHECK_TYPE=morning
-	isEvening := now.Hour() >= 12
-	if os.Getenv("CHECK_TYPE") == "morning" {
-		isEvening = false
-		fmt.Printf("DEBUG: Forced to morning mode for testing (CHECK_TYPE=morning)\n")
-	}
+// StartOfDay returns midnight of t's calendar day in t's location.
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
 
+// GenerateDailyDigest collects the morning briefing or evening summary. now
+// should already be in the user's timezone: "today" and "yesterday" are the
+// calendar days of that location, not of the machine running the check.
+func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool, isEvening bool, now time.Time) (*DailyDigest, error) {
 	digest := &DailyDigest{
 		Date:      now,
 		IsEvening: isEvening,
@@ -239,109 +304,14 @@ func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool) (*Da
 	// Note: Context is available for future use if we need to pass it to API calls
 	_ = ctx
 
-	fmt.Printf("DEBUG: Starting parallel daily digest generation (%s)...\n",
-		map[bool]string{true: "evening", false: "morning"}[isEvening])
+	slog.Debug("generating daily digest", "evening", isEvening)
 	startTime := time.Now()
 
 	if isEvening {
 		// Evening digest: Show what was accomplished today
-		// Get start of today (midnight) instead of last 24 hours
-		startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-
-		// Use WaitGroup for parallel API calls
-		var wg sync.WaitGroup
-		var mu sync.Mutex              // Protect shared digest struct
-		errChan := make(chan error, 3) // Buffer for 3 potential errors
-
-		// 1. Get PRs opened today
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			ownPRs, err := c.GetUserPullRequests(username)
-			if err != nil {
-				errChan <- fmt.Errorf("failed to get user PRs: %w", err)
-				return
-			}
-
-			var prsOpened, prsMerged []PullRequest
-			for _, pr := range ownPRs {
-				if pr.CreatedAt.After(startOfToday) {
-					prsOpened = append(prsOpened, pr)
-				}
-				// Check if PR was merged today
-				if pr.State == "closed" && pr.UpdatedAt.After(startOfToday) {
-					prsMerged = append(prsMerged, pr)
-				}
-			}
-
-			mu.Lock()
-			digest.PRsOpened = prsOpened
-			digest.PRsMerged = prsMerged
-			mu.Unlock()
-			fmt.Println("DEBUG: Completed PRs processing for evening digest")
-		}()
-
-		// 2. Get issues worked on today
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			issues, err := c.GetUserIssues(username)
-			if err != nil {
-				errChan <- fmt.Errorf("failed to get user issues: %w", err)
-				return
-			}
-
-			var issuesOpened, issuesClosed []Issue
-			for _, issue := range issues {
-				if issue.CreatedAt.After(startOfToday) {
-					issuesOpened = append(issuesOpened, issue)
-				}
-				if issue.State == "closed" && issue.UpdatedAt.After(startOfToday) {
-					issuesClosed = append(issuesClosed, issue)
-				}
-			}
-
-			mu.Lock()
-			digest.IssuesOpened = issuesOpened
-			digest.IssuesClosed = issuesClosed
-			mu.Unlock()
-			fmt.Println("DEBUG: Completed issues processing for evening digest")
-		}()
-
-		// 3. Get commits from all repositories for today (if enabled)
-		if trackAllCommits {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				commits, err := c.GetRecentCommitsFromAllRepos(username, startOfToday)
-				if err != nil {
-					fmt.Printf("Warning: failed to get commits from all repos: %v\n", err)
-					commits = []Commit{} // Empty slice on error
-				}
-
-				mu.Lock()
-				digest.CommitsToday = commits
-				mu.Unlock()
-				fmt.Println("DEBUG: Completed commits processing for evening digest")
-			}()
-		} else {
-			mu.Lock()
-			digest.CommitsToday = []Commit{} // Empty if feature disabled
-			mu.Unlock()
-		}
-
-		// Wait for all goroutines to complete
-		wg.Wait()
-		close(errChan)
-
-		// Check for errors from goroutines
-		var errors []error
-		for err := range errChan {
-			errors = append(errors, err)
-		}
-
-		// If we have critical errors, return them
-		if len(errors) > 0 {
+		// Get start of today (local midnight) instead of last 24 hours
+		digest.Since = StartOfDay(now)
+		if errors := c.collectAccomplishments(digest, username, digest.Since, trackAllCommits); len(errors) > 0 {
 			return nil, fmt.Errorf("critical errors in evening digest: %v", errors)
 		}
 
@@ -365,7 +335,7 @@ func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool) (*Da
 			mu.Lock()
 			digest.PendingReviews = reviewRequests
 			mu.Unlock()
-			fmt.Println("DEBUG: Completed review requests for morning digest")
+			slog.Debug("fetched review requests", "count", len(reviewRequests))
 		}()
 
 		// 2. Get assigned issues
@@ -381,7 +351,7 @@ func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool) (*Da
 			mu.Lock()
 			digest.AssignedIssues = assignedIssues
 			mu.Unlock()
-			fmt.Println("DEBUG: Completed assigned issues for morning digest")
+			slog.Debug("fetched assigned issues", "count", len(assignedIssues))
 		}()
 
 		// 3. Get repository invitations for morning digest
@@ -391,14 +361,17 @@ func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool) (*Da
 			invitations, err := c.GetRepositoryInvitations()
 			if err != nil {
 				// Don't fail the whole digest if invitations fail
-				fmt.Printf("Warning: failed to get repository invitations for daily digest: %v\n", err)
+				slog.Warn("failed to get repository invitations", "error", err)
 				invitations = []Invitation{}
 			}
 
 			mu.Lock()
+			if IsRateLimited(err) {
+				digest.SkippedSections = append(digest.SkippedSections, "repository invitations")
+			}
 			digest.RepositoryInvitations = invitations
 			mu.Unlock()
-			fmt.Println("DEBUG: Completed repository invitations for morning digest")
+			slog.Debug("fetched repository invitations", "count", len(invitations))
 		}()
 
 		// Wait for the main API calls to complete
@@ -416,13 +389,19 @@ func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool) (*Da
 			return nil, fmt.Errorf("critical errors in morning digest: %v", errors)
 		}
 
-		// Get recent commits for context (previous day for morning digest, if enabled)
+		// Get recent commits for context (since the start of yesterday, if enabled)
 		// This runs after the main parallel calls since it's optional
 		if trackAllCommits {
-			oneDaysAgo := now.AddDate(0, 0, -1)
-			commits, err := c.GetRecentCommitsFromAllRepos(username, oneDaysAgo)
-			if err != nil {
-				fmt.Printf("Warning: failed to get commits from all repos for morning digest: %v\n", err)
+			startOfToday := StartOfDay(now)
+			startOfYesterday := time.Date(startOfToday.Year(), startOfToday.Month(), startOfToday.Day()-1, 0, 0, 0, 0, now.Location())
+			commits, err := c.GetRecentCommitsFromAllRepos(username, startOfYesterday)
+			if IsRateLimited(err) {
+				// Keep the commits fetched before the limit was hit
+				slog.Warn("rate limited while getting commits", "error", err)
+				digest.CommitsToday = commits
+				digest.SkippedSections = append(digest.SkippedSections, "commits")
+			} else if err != nil {
+				slog.Warn("failed to get commits from all repos", "error", err)
 				digest.CommitsToday = []Commit{} // Empty slice on error
 			} else {
 				digest.CommitsToday = commits
@@ -432,12 +411,149 @@ func (c *Client) GenerateDailyDigest(username string, trackAllCommits bool) (*Da
 		}
 	}
 
-	elapsed := time.Since(startTime)
-	fmt.Printf("DEBUG: Parallel daily digest generation completed in %v\n", elapsed)
+	slog.Debug("daily digest generated", "elapsed", time.Since(startTime))
+
+	return digest, nil
+}
+
+// collectAccomplishments fills in the PRs, issues and (optionally) commits
+// the user opened, merged or closed since the given time. It returns the
+// errors of any critical section that failed.
+func (c *Client) collectAccomplishments(digest *DailyDigest, username string, since time.Time, trackAllCommits bool) []error {
+	// Use WaitGroup for parallel API calls
+	var wg sync.WaitGroup
+	var mu sync.Mutex              // Protect shared digest struct
+	errChan := make(chan error, 3) // Buffer for 3 potential errors
+
+	// 1. Get PRs opened in the period
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ownPRs, err := c.GetUserPullRequests(username)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to get user PRs: %w", err)
+			return
+		}
+
+		var prsOpened, prsMerged []PullRequest
+		for _, pr := range ownPRs {
+			if pr.CreatedAt.After(since) {
+				prsOpened = append(prsOpened, pr)
+			}
+			// Check if PR was merged in the period
+			if pr.State == "closed" && pr.UpdatedAt.After(since) {
+				prsMerged = append(prsMerged, pr)
+			}
+		}
+
+		mu.Lock()
+		digest.PRsOpened = prsOpened
+		digest.PRsMerged = prsMerged
+		mu.Unlock()
+		slog.Debug("fetched own pull requests", "opened", len(prsOpened), "merged", len(prsMerged))
+	}()
+
+	// 2. Get issues worked on in the period
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		issues, err := c.GetUserIssues(username)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to get user issues: %w", err)
+			return
+		}
+
+		var issuesOpened, issuesClosed []Issue
+		for _, issue := range issues {
+			if issue.CreatedAt.After(since) {
+				issuesOpened = append(issuesOpened, issue)
+			}
+			if issue.State == "closed" && issue.UpdatedAt.After(since) {
+				issuesClosed = append(issuesClosed, issue)
+			}
+		}
+
+		mu.Lock()
+		digest.IssuesOpened = issuesOpened
+		digest.IssuesClosed = issuesClosed
+		mu.Unlock()
+		slog.Debug("fetched own issues", "opened", len(issuesOpened), "closed", len(issuesClosed))
+	}()
+
+	// 3. Get commits from all repositories for the period (if enabled)
+	if trackAllCommits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			commits, err := c.GetRecentCommitsFromAllRepos(username, since)
+			if err != nil {
+				slog.Warn("failed to get commits from all repos", "error", err)
+				if !IsRateLimited(err) {
+					commits = []Commit{} // Empty slice on error
+				}
+			}
+
+			mu.Lock()
+			if IsRateLimited(err) {
+				digest.SkippedSections = append(digest.SkippedSections, "commits")
+			}
+			digest.CommitsToday = commits
+			mu.Unlock()
+			slog.Debug("fetched commits", "count", len(commits))
+		}()
+	} else {
+		mu.Lock()
+		digest.CommitsToday = []Commit{} // Empty if feature disabled
+		mu.Unlock()
+	}
+
+	// Wait for all goroutines to complete
+	wg.Wait()
+	close(errChan)
 
+	// Check for errors from goroutines
+	var errors []error
+	for err := range errChan {
+		errors = append(errors, err)
+	}
+
+	return errors
+}
+
+// GenerateWeeklyDigest collects what the user accomplished over the last seven
+// calendar days (today included) in now's location.
+func (c *Client) GenerateWeeklyDigest(username string, trackAllCommits bool, now time.Time) (*DailyDigest, error) {
+	startOfToday := StartOfDay(now)
+	digest := &DailyDigest{
+		Date:      now,
+		Since:     time.Date(startOfToday.Year(), startOfToday.Month(), startOfToday.Day()-6, 0, 0, 0, 0, now.Location()),
+		IsEvening: true,
+		IsWeekly:  true,
+	}
+
+	slog.Debug("generating weekly digest", "since", digest.Since)
+	startTime := time.Now()
+
+	if errors := c.collectAccomplishments(digest, username, digest.Since, trackAllCommits); len(errors) > 0 {
+		return nil, fmt.Errorf("critical errors in weekly digest: %v", errors)
+	}
+
+	slog.Debug("weekly digest generated", "elapsed", time.Since(startTime))
 	return digest, nil
 }
 
+// Complete reports whether the result lists every item of an alert category
+// GitHub has, so one missing from it is really gone. Lists cut off by
+// max_pages, or by a search that timed out, are not complete.
+func (r *CheckResult) Complete(category string) bool {
+	for _, incomplete := range r.Incomplete {
+		if incomplete == category {
+			return false
+		}
+	}
+	return true
+}
+
 func (r *CheckResult) HasAlerts() bool {
 	return len(r.PRsNeedingReview) > 0 ||
 		len(r.StaleOwnPRs) > 0 ||
@@ -445,7 +561,9 @@ func (r *CheckResult) HasAlerts() bool {
 		len(r.UnreadNotifications) > 0 ||
 		len(r.FailedWorkflows) > 0 ||
 		len(r.RepositoryInvitations) > 0 ||
-		len(r.RecentCommits) > 0
+		len(r.RecentCommits) > 0 ||
+		len(r.Reviews) > 0 ||
+		len(r.Memberships) > 0
 }
 
 func (r *CheckResult) GetAlertCount() int {
@@ -455,5 +573,7 @@ func (r *CheckResult) GetAlertCount() int {
 		len(r.UnreadNotifications) +
 		len(r.FailedWorkflows) +
 		len(r.RepositoryInvitations) +
-		len(r.RecentCommits)
+		len(r.RecentCommits) +
+		len(r.Reviews) +
+		len(r.Memberships)
 }
@@ -0,0 +1,98 @@
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestIsEveningAt(t *testing.T) {
+	hcm := mustLoadLocation(t, "Asia/Ho_Chi_Minh")
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"midnight", time.Date(2026, 10, 16, 0, 0, 0, 0, hcm), false},
+		{"just before noon", time.Date(2026, 10, 16, 11, 59, 59, 0, hcm), false},
+		{"noon", time.Date(2026, 10, 16, 12, 0, 0, 0, hcm), true},
+		{"just before midnight", time.Date(2026, 10, 16, 23, 59, 59, 0, hcm), true},
+		// 06:00 UTC is morning on the runner but 13:00 in Ho Chi Minh City
+		{"afternoon locally, morning in UTC", time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC).In(hcm), true},
+		// 20:00 UTC is evening on the runner but 03:00 the next day locally
+		{"night locally, evening in UTC", time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC).In(hcm), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEveningAt(tt.now); got != tt.want {
+				t.Errorf("IsEveningAt(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	hcm := mustLoadLocation(t, "Asia/Ho_Chi_Minh")
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"midnight is its own start", time.Date(2026, 10, 16, 0, 0, 0, 0, hcm), time.Date(2026, 10, 16, 0, 0, 0, 0, hcm)},
+		{"last instant of the day", time.Date(2026, 10, 16, 23, 59, 59, 999, hcm), time.Date(2026, 10, 16, 0, 0, 0, 0, hcm)},
+		// 18:30 UTC on the 16th is already the 17th in Ho Chi Minh City
+		{"local day ahead of UTC", time.Date(2026, 10, 16, 18, 30, 0, 0, time.UTC).In(hcm), time.Date(2026, 10, 17, 0, 0, 0, 0, hcm)},
+		// 02:00 UTC on the 17th is still the 16th in New York
+		{"local day behind UTC", time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC).In(newYork), time.Date(2026, 10, 16, 0, 0, 0, 0, newYork)},
+		{"new year", time.Date(2026, 12, 31, 17, 0, 0, 0, time.UTC).In(hcm), time.Date(2027, 1, 1, 0, 0, 0, 0, hcm)},
+		// Clocks go back on November 1st, so that day is 25 hours long
+		{"daylight saving ends", time.Date(2026, 11, 1, 23, 0, 0, 0, newYork), time.Date(2026, 11, 1, 0, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := StartOfDay(tt.t)
+			if !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+				t.Errorf("StartOfDay(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRecentCommitsSinceInUTC(t *testing.T) {
+	hcm := mustLoadLocation(t, "Asia/Ho_Chi_Minh")
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, hcm)
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query().Get("since")
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	client := NewClient("token")
+	client.baseURL = server.URL
+	if _, err := client.GetRecentCommits("owner/repo", since); err != nil {
+		t.Fatalf("GetRecentCommits: %v", err)
+	}
+
+	parsed, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		t.Fatalf("since = %q, not RFC 3339: %v", got, err)
+	}
+	if !parsed.Equal(since) {
+		t.Errorf("since = %v, want %v", parsed, since)
+	}
+}
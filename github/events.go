@@ -0,0 +1,165 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// EventRepository is the repository object sent with webhook and Actions
+// event payloads.
+type EventRepository struct {
+	Repo
+	URL     string `json:"url"`
+	HTMLURL string `json:"html_url"`
+}
+
+// PushEvent is the payload of a push event.
+type PushEvent struct {
+	Ref        string          `json:"ref"`
+	Before     string          `json:"before"`
+	After      string          `json:"after"`
+	Created    bool            `json:"created"`
+	Deleted    bool            `json:"deleted"`
+	Forced     bool            `json:"forced"`
+	Compare    string          `json:"compare"`
+	Commits    []PushCommit    `json:"commits"` // Oldest first, at most 20
+	HeadCommit *PushCommit     `json:"head_commit"`
+	Pusher     PushAuthor      `json:"pusher"`
+	Sender     User            `json:"sender"`
+	Repository EventRepository `json:"repository"`
+}
+
+// PushCommit is a commit in a push event.
+type PushCommit struct {
+	ID        string     `json:"id"`
+	Message   string     `json:"message"`
+	Timestamp time.Time  `json:"timestamp"`
+	URL       string     `json:"url"`
+	Author    PushAuthor `json:"author"`
+}
+
+// PushAuthor is a commit author or pusher. Username is only set for authors
+// with a GitHub account.
+type PushAuthor struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// Login returns the author's GitHub username, or their name without one.
+func (a PushAuthor) Login() string {
+	if a.Username != "" {
+		return a.Username
+	}
+	return a.Name
+}
+
+// RefName returns the branch or tag name of the pushed ref.
+func (e *PushEvent) RefName() string {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if name, ok := strings.CutPrefix(e.Ref, prefix); ok {
+			return name
+		}
+	}
+	return e.Ref
+}
+
+// IsTag reports whether a tag rather than a branch was pushed.
+func (e *PushEvent) IsTag() bool {
+	return strings.HasPrefix(e.Ref, "refs/tags/")
+}
+
+// ReadEvent decodes the event payload at path, such as GITHUB_EVENT_PATH in
+// GitHub Actions, into v.
+func ReadEvent(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read event payload: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse event payload: %w", err)
+	}
+	return nil
+}
+
+// PullRequestEvent is the payload of a pull_request event.
+type PullRequestEvent struct {
+	Action            string           `json:"action"`
+	PullRequest       EventPullRequest `json:"pull_request"`
+	RequestedReviewer *User            `json:"requested_reviewer"` // review_requested only
+	Sender            User             `json:"sender"`
+	Repository        EventRepository  `json:"repository"`
+}
+
+// EventPullRequest is a pull request as sent in events, with more detail
+// than search results carry.
+type EventPullRequest struct {
+	PullRequest
+	Body         string `json:"body"`
+	Merged       bool   `json:"merged"`
+	MergedBy     *User  `json:"merged_by"`
+	Base         Branch `json:"base"`
+	Commits      int    `json:"commits"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	ChangedFiles int    `json:"changed_files"`
+}
+
+// ReleaseEvent is the payload of a release event.
+type ReleaseEvent struct {
+	Action     string          `json:"action"`
+	Release    Release         `json:"release"`
+	Sender     User            `json:"sender"`
+	Repository EventRepository `json:"repository"`
+}
+
+// Release is a release as sent in release events.
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"` // Release notes, in Markdown
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	Author      User      `json:"author"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// CreateEvent is the payload of a create event: a new branch or tag.
+type CreateEvent struct {
+	Ref        string          `json:"ref"`      // Branch or tag name
+	RefType    string          `json:"ref_type"` // branch or tag
+	Sender     User            `json:"sender"`
+	Repository EventRepository `json:"repository"`
+}
+
+// WorkflowRunEvent is the payload of a workflow_run event.
+type WorkflowRunEvent struct {
+	Action      string           `json:"action"`
+	WorkflowRun EventWorkflowRun `json:"workflow_run"`
+	Sender      User             `json:"sender"`
+	Repository  EventRepository  `json:"repository"`
+}
+
+// EventWorkflowRun is a workflow run as sent in events.
+type EventWorkflowRun struct {
+	WorkflowRun
+	HeadSHA      string `json:"head_sha"`
+	RunNumber    int    `json:"run_number"`
+	Event        string `json:"event"` // What triggered the run, e.g. push
+	DisplayTitle string `json:"display_title"`
+	Actor        User   `json:"actor"`
+}
+
+// Failed reports whether the run concluded without succeeding: it failed,
+// timed out or couldn't start.
+func (r EventWorkflowRun) Failed() bool {
+	switch r.Conclusion {
+	case "failure", "timed_out", "startup_failure":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package github
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCachedBody is the largest response body kept in the response cache.
+// Larger responses are fetched in full every time rather than bloating the
+// stored state.
+const maxCachedBody = 512 << 10
+
+// cachedPaths matches the endpoints whose responses are cached: the ones polled
+// on every check that rarely change in between. Per-repository commits, pull
+// request details and the like are left out so the state doesn't grow with
+// every repository's payloads.
+var cachedPaths = regexp.MustCompile(`^/(notifications|search/issues|users/[^/]+/repos)$`)
+
+// CachedResponse is a stored GET response used to make conditional requests.
+type CachedResponse struct {
+	ETag         string
+	LastModified string
+	Link         string // Link header, so pagination still works on 304s
+	Body         []byte
+	StoredAt     time.Time
+	PollAfter    time.Time // From X-Poll-Interval; no request is made before this
+}
+
+// ResponseCache stores GET responses keyed by URL. Implementations must be
+// safe for concurrent use since the checker fetches sections in parallel.
+type ResponseCache interface {
+	LoadResponse(url string) (CachedResponse, bool)
+	StoreResponse(url string, resp CachedResponse)
+}
+
+// SetResponseCache enables conditional requests: GETs send If-None-Match /
+// If-Modified-Since for cached URLs and 304 responses, which don't count
+// against the rate limit, are served from the cached body.
+func (c *Client) SetResponseCache(cache ResponseCache) {
+	c.responseCache = cache
+}
+
+// cacheable reports whether GET responses for url are kept in the response
+// cache (see cachedPaths).
+func (c *Client) cacheable(url string) bool {
+	path, ok := strings.CutPrefix(url, c.baseURL)
+	if !ok {
+		return false
+	}
+	path, _, _ = strings.Cut(path, "?")
+	return cachedPaths.MatchString(path)
+}
+
+// cachedResponse looks up url in the response cache.
+func (c *Client) cachedResponse(method, url string) (CachedResponse, bool) {
+	if c.responseCache == nil || method != http.MethodGet || !c.cacheable(url) {
+		return CachedResponse{}, false
+	}
+	return c.responseCache.LoadResponse(url)
+}
+
+// setConditionalHeaders adds validators from a cached response to req.
+func setConditionalHeaders(req *http.Request, cached CachedResponse) {
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+}
+
+// serveCached builds a 200 response from a cached entry.
+func serveCached(req *http.Request, cached CachedResponse) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	if cached.Link != "" {
+		header.Set("Link", cached.Link)
+	}
+	return &http.Response{
+		Status:        "200 OK (cached)",
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
+
+// cacheResponse handles a GET response when the response cache is enabled:
+// 304s are replaced by the cached body, and 200s from cacheable endpoints
+// carrying validators are stored unless their body exceeds maxCachedBody.
+// The returned response always has an unread body.
+func (c *Client) cacheResponse(url string, resp *http.Response, cached CachedResponse, hasCached bool) (*http.Response, error) {
+	pollAfter := pollAfter(resp.Header)
+
+	if resp.StatusCode == http.StatusNotModified && hasCached {
+		resp.Body.Close()
+		cached.StoredAt = time.Now()
+		cached.PollAfter = pollAfter
+		c.responseCache.StoreResponse(url, cached)
+		return serveCached(resp.Request, cached), nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") || !c.cacheable(url) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) > maxCachedBody {
+		return resp, nil
+	}
+
+	c.responseCache.StoreResponse(url, CachedResponse{
+		ETag:         etag,
+		LastModified: lastModified,
+		Link:         resp.Header.Get("Link"),
+		Body:         body,
+		StoredAt:     time.Now(),
+		PollAfter:    pollAfter,
+	})
+
+	return resp, nil
+}
+
+// pollAfter converts an X-Poll-Interval header (seconds) into the earliest
+// time the endpoint should be polled again.
+func pollAfter(header http.Header) time.Time {
+	seconds, err := strconv.Atoi(header.Get("X-Poll-Interval"))
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,173 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryCache is a ResponseCache kept in a map.
+type memoryCache struct {
+	mu        sync.Mutex
+	responses map[string]CachedResponse
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{responses: make(map[string]CachedResponse)}
+}
+
+func (m *memoryCache) LoadResponse(url string) (CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resp, ok := m.responses[url]
+	return resp, ok
+}
+
+func (m *memoryCache) StoreResponse(url string, resp CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses[url] = resp
+}
+
+// conditionalServer serves body with an ETag, answering 304 to requests that
+// send it back, and records the If-None-Match headers it gets.
+type conditionalServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	body        string
+	header      map[string]string
+	conditional []string
+}
+
+func newConditionalServer(t *testing.T, body string, header map[string]string) *conditionalServer {
+	s := &conditionalServer{body: body, header: header}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.conditional = append(s.conditional, r.Header.Get("If-None-Match"))
+		for name, value := range s.header {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, s.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *conditionalServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.conditional...)
+}
+
+func getBody(t *testing.T, client *Client, url string) string {
+	t.Helper()
+	resp, err := client.makeRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("makeRequest: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestResponseCacheReplaysNotModified(t *testing.T) {
+	server := newConditionalServer(t, `{"total_count": 1, "items": [{"number": 7}]}`,
+		map[string]string{"Link": `<https://api.github.com/search/issues?page=2>; rel="next"`})
+	client, _ := newTestClient(server.URL)
+	cache := newMemoryCache()
+	client.SetResponseCache(cache)
+	url := server.URL + "/search/issues?q=is:open"
+
+	first := getBody(t, client, url)
+	second := getBody(t, client, url)
+	if second != first {
+		t.Errorf("304 replayed %q, want the cached %q", second, first)
+	}
+	if got := server.requests(); len(got) != 2 || got[0] != "" || got[1] != `"v1"` {
+		t.Errorf("If-None-Match headers = %q, want none then the stored ETag", got)
+	}
+
+	resp, err := client.makeRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("makeRequest: %v", err)
+	}
+	resp.Body.Close()
+	if next := nextPageURL(resp.Header.Get("Link")); next == "" {
+		t.Error("replayed response lost the Link header")
+	}
+}
+
+func TestResponseCacheHonorsPollInterval(t *testing.T) {
+	server := newConditionalServer(t, `[{"id": "1"}]`, map[string]string{"X-Poll-Interval": "60"})
+	client, _ := newTestClient(server.URL)
+	client.SetResponseCache(newMemoryCache())
+	url := server.URL + "/notifications?all=false"
+
+	first := getBody(t, client, url)
+	if second := getBody(t, client, url); second != first {
+		t.Errorf("served %q within the poll interval, want the cached %q", second, first)
+	}
+	if got := len(server.requests()); got != 1 {
+		t.Errorf("sent %d requests within the poll interval, want 1", got)
+	}
+}
+
+func TestResponseCacheSkipsUncachedResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"per-repository commits", "/repos/acme/widgets/commits?since=2026-10-16T00:00:00Z", `[]`},
+		{"pull request details", "/repos/acme/widgets/pulls/7", `{}`},
+		{"oversized body", "/users/octocat/repos", `[` + strings.Repeat(" ", maxCachedBody) + `]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newConditionalServer(t, tt.body, nil)
+			client, _ := newTestClient(server.URL)
+			cache := newMemoryCache()
+			client.SetResponseCache(cache)
+
+			if body := getBody(t, client, server.URL+tt.path); body != tt.body {
+				t.Errorf("got a body of %d bytes, want %d", len(body), len(tt.body))
+			}
+			if len(cache.responses) != 0 {
+				t.Errorf("stored %d responses, want none", len(cache.responses))
+			}
+		})
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	client := NewClient("token")
+	for url, want := range map[string]bool{
+		"https://api.github.com/notifications?all=false":         true,
+		"https://api.github.com/search/issues?q=is:pr&page=2":    true,
+		"https://api.github.com/users/octocat/repos?type=all":    true,
+		"https://api.github.com/repos/acme/widgets/commits":      false,
+		"https://api.github.com/users/octocat":                   false,
+		"https://api.github.com/user/repository_invitations":     false,
+		"https://example.com/notifications":                      false,
+		"https://api.github.com/users/octocat/repos/extra/paths": false,
+	} {
+		if got := client.cacheable(url); got != want {
+			t.Errorf("cacheable(%s) = %v, want %v", url, got, want)
+		}
+	}
+}
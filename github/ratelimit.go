@@ -0,0 +1,164 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRetries = 3                // Retries for rate-limited and 5xx responses
+	maxWait    = 60 * time.Second // Longest we sleep for a reset before giving up
+	// quotaReserve is the remaining-request floor below which optional
+	// fan-out calls (per-repo commits and workflows) stop early.
+	quotaReserve = 50
+)
+
+// RateLimit is the client's latest view of the primary REST API quota.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitError is returned when GitHub's primary or secondary rate limit is
+// hit and waiting it out would take longer than the client is willing to.
+type RateLimitError struct {
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+	Secondary  bool // true for abuse/secondary limits, false for the hourly quota
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Secondary {
+		return fmt.Sprintf("GitHub secondary rate limit exceeded, retry after %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("GitHub rate limit exceeded (%d/%d remaining), resets at %s",
+		e.Remaining, e.Limit, e.Reset.Format(time.RFC3339))
+}
+
+// IsRateLimited reports whether err (or any error it wraps) is a RateLimitError.
+func IsRateLimited(err error) bool {
+	var rateLimitErr *RateLimitError
+	return errors.As(err, &rateLimitErr)
+}
+
+// RateLimit returns the most recently observed rate limit headers.
+func (c *Client) RateLimit() RateLimit {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	return c.rate
+}
+
+// quotaLow reports whether the known remaining quota has dropped below the reserve.
+func (c *Client) quotaLow() bool {
+	rate := c.RateLimit()
+	return rate.Limit > 0 && rate.Remaining < quotaReserve && time.Now().Before(rate.Reset)
+}
+
+// quotaError returns a RateLimitError for the current quota.
+func (c *Client) quotaError() *RateLimitError {
+	rate := c.RateLimit()
+	return &RateLimitError{
+		Limit:      rate.Limit,
+		Remaining:  rate.Remaining,
+		Reset:      rate.Reset,
+		RetryAfter: time.Until(rate.Reset),
+	}
+}
+
+// waitForQuota blocks until the primary quota resets when it is known to be
+// exhausted, or fails fast with a RateLimitError if the reset is too far away.
+func (c *Client) waitForQuota() error {
+	rate := c.RateLimit()
+	if rate.Limit == 0 || rate.Remaining > 0 {
+		return nil
+	}
+
+	wait := time.Until(rate.Reset)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > maxWait {
+		return c.quotaError()
+	}
+
+	slog.Warn("GitHub rate limit exhausted, waiting for reset", "wait", wait.Round(time.Second))
+	c.sleep(wait)
+	return nil
+}
+
+// updateRateLimit records the X-RateLimit-* headers of a response.
+func (c *Client) updateRateLimit(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	c.rateMu.Lock()
+	c.rate = RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+	c.rateMu.Unlock()
+}
+
+// rateLimitFromResponse returns a RateLimitError if resp is a 403/429 caused by
+// the primary or secondary rate limit. body is the already-read response body.
+func rateLimitFromResponse(resp *http.Response, body []byte) *RateLimitError {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	resetUnix, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	reset := time.Unix(resetUnix, 0)
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return &RateLimitError{
+			Limit:      limit,
+			Remaining:  remaining,
+			Reset:      reset,
+			RetryAfter: time.Duration(seconds) * time.Second,
+			Secondary:  true,
+		}
+	}
+
+	if remainingErr == nil && remaining == 0 {
+		return &RateLimitError{
+			Limit:      limit,
+			Remaining:  0,
+			Reset:      reset,
+			RetryAfter: time.Until(reset),
+		}
+	}
+
+	if strings.Contains(strings.ToLower(string(body)), "rate limit") {
+		// GitHub asks clients to wait at least a minute on secondary limits
+		// that don't come with a Retry-After header.
+		return &RateLimitError{
+			Limit:      limit,
+			Remaining:  remaining,
+			Reset:      reset,
+			RetryAfter: time.Minute,
+			Secondary:  true,
+		}
+	}
+
+	return nil
+}
+
+// backoff returns the exponential delay before retry attempt n (0-based).
+func backoff(attempt int) time.Duration {
+	return time.Second << attempt
+}
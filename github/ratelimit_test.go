@@ -0,0 +1,237 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// apiResponse is one canned API response.
+type apiResponse struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// newAPIServer answers each request with the next response, repeating the
+// last one, and counts the requests.
+func newAPIServer(t *testing.T, responses ...apiResponse) (*httptest.Server, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		response := responses[min(requests, len(responses)-1)]
+		requests++
+		mu.Unlock()
+		for name, value := range response.header {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(response.status)
+		w.Write([]byte(response.body))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// newTestClient returns a client for url that records its waits instead of
+// sleeping.
+func newTestClient(url string) (*Client, *[]time.Duration) {
+	var waits []time.Duration
+	client := NewClient("token")
+	client.baseURL = url
+	client.sleep = func(wait time.Duration) { waits = append(waits, wait) }
+	return client, &waits
+}
+
+func quotaHeaders(remaining int, reset time.Time) map[string]string {
+	return map[string]string{
+		"X-RateLimit-Limit":     "5000",
+		"X-RateLimit-Remaining": strconv.Itoa(remaining),
+		"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+	}
+}
+
+var apiOK = apiResponse{status: http.StatusOK, body: `{}`}
+
+func TestMakeRequestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []apiResponse
+		wantRequests int
+		wantWaits    []time.Duration
+	}{
+		{"secondary limit with Retry-After",
+			[]apiResponse{{status: http.StatusForbidden, header: map[string]string{"Retry-After": "5"},
+				body: `{"message": "You have exceeded a secondary rate limit"}`}, apiOK},
+			2, []time.Duration{5 * time.Second}},
+		{"429 with Retry-After",
+			[]apiResponse{{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "3"}}, apiOK},
+			2, []time.Duration{3 * time.Second}},
+		{"secondary limit without Retry-After",
+			[]apiResponse{{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit."}`}, apiOK},
+			2, []time.Duration{time.Minute}},
+		{"5xx then success",
+			[]apiResponse{{status: http.StatusBadGateway}, {status: http.StatusServiceUnavailable}, apiOK},
+			3, []time.Duration{time.Second, 2 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newAPIServer(t, tt.responses...)
+			client, waits := newTestClient(server.URL)
+
+			resp, err := client.makeRequest("GET", server.URL+"/user", nil)
+			if err != nil {
+				t.Fatalf("makeRequest: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want 200", resp.StatusCode)
+			}
+			if got := requests(); got != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", got, tt.wantRequests)
+			}
+			if fmt.Sprint(*waits) != fmt.Sprint(tt.wantWaits) {
+				t.Errorf("waited %v, want %v", *waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestMakeRequestPrimaryLimit(t *testing.T) {
+	// The quota resets within maxWait: wait for it, then retry
+	soon := time.Now().Add(10 * time.Second)
+	server, requests := newAPIServer(t,
+		apiResponse{status: http.StatusForbidden, header: quotaHeaders(0, soon), body: `{"message": "API rate limit exceeded"}`},
+		apiResponse{status: http.StatusOK, header: quotaHeaders(4999, soon.Add(time.Hour)), body: `{}`})
+	client, waits := newTestClient(server.URL)
+
+	resp, err := client.makeRequest("GET", server.URL+"/user", nil)
+	if err != nil {
+		t.Fatalf("makeRequest: %v", err)
+	}
+	resp.Body.Close()
+	if requests() != 2 {
+		t.Errorf("sent %d requests, want 2", requests())
+	}
+	if len(*waits) == 0 || (*waits)[0] > 10*time.Second || (*waits)[0] < 8*time.Second {
+		t.Errorf("waited %v, want about 10s for the reset", *waits)
+	}
+
+	// The quota resets in an hour: fail at once, and fail the next request
+	// without sending it
+	later := time.Now().Add(time.Hour)
+	server, requests = newAPIServer(t,
+		apiResponse{status: http.StatusForbidden, header: quotaHeaders(0, later), body: `{"message": "API rate limit exceeded"}`})
+	client, waits = newTestClient(server.URL)
+
+	for i := 0; i < 2; i++ {
+		_, err = client.makeRequest("GET", server.URL+"/user", nil)
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("makeRequest error = %v, want a *RateLimitError", err)
+		}
+		if rateLimitErr.Secondary || rateLimitErr.Remaining != 0 || rateLimitErr.Limit != 5000 {
+			t.Errorf("rate limit error = %+v, want the exhausted primary quota", rateLimitErr)
+		}
+	}
+	if requests() != 1 {
+		t.Errorf("sent %d requests, want 1", requests())
+	}
+	if len(*waits) != 0 {
+		t.Errorf("waited %v for a reset an hour away", *waits)
+	}
+}
+
+func TestMakeRequestGivesUp(t *testing.T) {
+	// Still failing after maxRetries: the last response is handed back
+	server, requests := newAPIServer(t, apiResponse{status: http.StatusInternalServerError})
+	client, _ := newTestClient(server.URL)
+	resp, err := client.makeRequest("GET", server.URL+"/user", nil)
+	if err != nil {
+		t.Fatalf("makeRequest: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || requests() != maxRetries+1 {
+		t.Errorf("status %d after %d requests, want 500 after %d", resp.StatusCode, requests(), maxRetries+1)
+	}
+
+	// Still limited after maxRetries: a secondary RateLimitError
+	server, requests = newAPIServer(t, apiResponse{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "1"}})
+	client, _ = newTestClient(server.URL)
+	_, err = client.makeRequest("GET", server.URL+"/user", nil)
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || !rateLimitErr.Secondary {
+		t.Fatalf("makeRequest error = %v, want a secondary *RateLimitError", err)
+	}
+	if requests() != maxRetries+1 {
+		t.Errorf("sent %d requests, want %d", requests(), maxRetries+1)
+	}
+
+	// A plain permission error isn't retried
+	server, requests = newAPIServer(t, apiResponse{status: http.StatusForbidden, body: `{"message": "Resource not accessible"}`})
+	client, _ = newTestClient(server.URL)
+	resp, err = client.makeRequest("GET", server.URL+"/user", nil)
+	if err != nil {
+		t.Fatalf("makeRequest: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || requests() != 1 {
+		t.Errorf("status %d after %d requests, want 403 after 1", resp.StatusCode, requests())
+	}
+}
+
+func TestRateLimitFromResponse(t *testing.T) {
+	reset := time.Now().Add(30 * time.Minute)
+	tests := []struct {
+		name          string
+		status        int
+		header        map[string]string
+		body          string
+		wantLimited   bool
+		wantSecondary bool
+		wantRetry     time.Duration // Checked when non-zero
+	}{
+		{"primary quota exhausted", http.StatusForbidden, quotaHeaders(0, reset), `{"message": "API rate limit exceeded"}`, true, false, 0},
+		{"Retry-After", http.StatusForbidden, map[string]string{"Retry-After": "30"}, "", true, true, 30 * time.Second},
+		{"429 Retry-After with quota left", http.StatusTooManyRequests,
+			map[string]string{"Retry-After": "7", "X-RateLimit-Remaining": "100"}, "", true, true, 7 * time.Second},
+		{"secondary limit message", http.StatusForbidden, quotaHeaders(100, reset),
+			`{"message": "You have exceeded a secondary rate limit"}`, true, true, time.Minute},
+		{"permission denied", http.StatusForbidden, quotaHeaders(100, reset), `{"message": "Must have admin rights"}`, false, false, 0},
+		{"not a limit status", http.StatusNotFound, map[string]string{"Retry-After": "30"}, "", false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for name, value := range tt.header {
+				resp.Header.Set(name, value)
+			}
+			got := rateLimitFromResponse(resp, []byte(tt.body))
+			if (got != nil) != tt.wantLimited {
+				t.Fatalf("rate limited = %v, want %v", got != nil, tt.wantLimited)
+			}
+			if got == nil {
+				return
+			}
+			if got.Secondary != tt.wantSecondary {
+				t.Errorf("secondary = %v, want %v", got.Secondary, tt.wantSecondary)
+			}
+			if tt.wantRetry != 0 && got.RetryAfter != tt.wantRetry {
+				t.Errorf("retry after = %s, want %s", got.RetryAfter, tt.wantRetry)
+			}
+			if !tt.wantSecondary && got.Reset.Unix() != reset.Unix() {
+				t.Errorf("reset = %s, want %s", got.Reset, reset)
+			}
+		})
+	}
+}
@@ -3,3 +3,10 @@ module github.com/wilfierd/gh-notify
 go 1.22
 
 require github.com/joho/godotenv v1.5.1
+
+require (
+	go.etcd.io/bbolt v1.3.11
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.4.0 // indirect
@@ -0,0 +1,58 @@
+// Package logging sets up the slog logger shared by gh-notify and
+// commit-notifier.
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// RunID identifies this run in the logs: the GitHub Actions run id when
+// available, otherwise a random id.
+var RunID = newRunID()
+
+// Setup installs the default slog logger: LOG_LEVEL (debug, info, warn or
+// error; info by default) and format, or LOG_FORMAT when format is empty
+// (text or json), on stderr, with a run_id attribute on every record.
+func Setup(format string) error {
+	var level slog.Level
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %q", value)
+		}
+	}
+
+	if format == "" {
+		format = os.Getenv("LOG_FORMAT")
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+
+	slog.SetDefault(slog.New(handler).With("run_id", RunID))
+	return nil
+}
+
+func newRunID() string {
+	if id := os.Getenv("GITHUB_RUN_ID"); id != "" {
+		if attempt := os.Getenv("GITHUB_RUN_ATTEMPT"); attempt != "" && attempt != "1" {
+			return id + "." + attempt
+		}
+		return id
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/wilfierd/gh-notify/internal/logging"
+)
+
+// logFormat is set by the -log-format flag and overrides LOG_FORMAT.
+var logFormat string
+
+// setupLogging installs the default slog logger; see logging.Setup.
+func setupLogging() error {
+	return logging.Setup(logFormat)
+}
+
+// logAlert records whether an alert is new or was already sent.
+func logAlert(logger *slog.Logger, category, repo, key string, alreadySent bool) {
+	logger.Debug("alert", "category", category, "repo", repo, "key", key, "already_sent", alreadySent)
+}
@@ -1,373 +1,375 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"strings"
 	"time"
+	_ "time/tzdata" // Embed zone data; the Alpine image ships without it
 
 	"github.com/joho/godotenv"
 	"github.com/wilfierd/gh-notify/cache"
 	"github.com/wilfierd/gh-notify/config"
 	"github.com/wilfierd/gh-notify/github"
 	"github.com/wilfierd/gh-notify/notify"
+	"github.com/wilfierd/gh-notify/rules"
 )
 
 func main() {
 	// Load .env file if exists (silent fail for production)
-	err := godotenv.Load()
-	if err != nil {
-		fmt.Printf("DEBUG: No .env file found: %v\n", err)
+	envErr := godotenv.Load()
+
+	// LOG_LEVEL and LOG_FORMAT may come from .env; -log-format is applied once flags are parsed
+	if err := setupLogging(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if envErr != nil {
+		slog.Debug("no .env file loaded", "error", envErr)
 	} else {
-		fmt.Println("DEBUG: .env file loaded successfully")
+		slog.Debug(".env file loaded")
 	}
 
-	// Debug environment variables
-	fmt.Printf("DEBUG: CHECK_TYPE = '%s'\n", os.Getenv("CHECK_TYPE"))
-	fmt.Printf("DEBUG: SCHEDULE_TYPE = '%s'\n", os.Getenv("SCHEDULE_TYPE"))
-	fmt.Printf("DEBUG: CHECK_INTERVAL = '%s'\n", os.Getenv("CHECK_INTERVAL"))
-	fmt.Printf("DEBUG: DAILY_REPORT_TIME = '%s'\n", os.Getenv("DAILY_REPORT_TIME"))
-	fmt.Printf("DEBUG: Current time = %s\n", time.Now().Format("2006-01-02 15:04:05"))
-
-	// Load configuration
-	cfg := config.Load()
+	os.Exit(runCLI(os.Args[1:]))
+}
 
-	// Debug config values
-	fmt.Printf("DEBUG: cfg.CheckInterval = %v\n", cfg.CheckInterval)
-	fmt.Printf("DEBUG: cfg.DailyReportTime = '%s'\n", cfg.DailyReportTime)
+// app holds everything a check, digest or daemon run needs.
+type app struct {
+	cfg          *config.Config
+	store        cache.Store
+	state        *cache.State
+	githubClient *github.Client
+	notifier     notify.Notifier
+	rules        *rules.Engine // Compiled from cfg.AlertRules
+	username     string
+}
 
-	// Validate required configuration
-	if cfg.GitHubToken == "" {
-		log.Fatal("GITHUB_TOKEN environment variable is required")
-	}
-	if cfg.DiscordWebhook == "" {
-		log.Fatal("DISCORD_WEBHOOK environment variable is required")
+// newApp loads the configuration from configPath (see configFile), the cache
+// state and the GitHub client and notifier, and resolves the username. With
+// dryRun, messages are previewed on stdout and the cache is never written.
+func newApp(configPath string, dryRun bool) (*app, error) {
+	// Load configuration
+	cfg, err := config.LoadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	// Determine what to run based on time and environment
-	now := time.Now()
-	checkType := os.Getenv("CHECK_TYPE")
-	scheduleType := os.Getenv("SCHEDULE_TYPE")
-
-	// Auto-detect based on schedule trigger
-	if checkType == "auto" {
-		switch scheduleType {
-		case "0 0 * * *": // 7 AM Vietnam time - Morning digest
-			checkType = "morning"
-		case "0 14 * * *": // 9 PM Vietnam time - Evening digest
-			checkType = "evening"
-		default: // Every 30 minutes - Instant checks
-			checkType = "instant"
-		}
-	}
+	cfg.DryRun = cfg.DryRun || dryRun
 
-	fmt.Printf("DEBUG: Determined check type: %s (from schedule: %s)\n", checkType, scheduleType)
+	slog.Debug("configuration loaded", "file", cfg.File, "check_interval", cfg.CheckInterval,
+		"timezone", cfg.Timezone, "destinations", len(cfg.Destinations), "dry_run", cfg.DryRun)
 
 	// Load or create cache state
-	state, err := cache.LoadState(cfg.CacheFile)
+	store, err := newStore(cfg)
 	if err != nil {
-		log.Fatalf("Failed to load cache state: %v", err)
+		return nil, err
 	}
-
-	// Debug cache state
-	fmt.Printf("DEBUG: Cache file path = %s\n", cfg.CacheFile)
-	fmt.Printf("DEBUG: Last check time = %s\n", state.LastCheck.Format("2006-01-02 15:04:05"))
-	fmt.Printf("DEBUG: Last daily report = %s\n", state.LastDailyReport.Format("2006-01-02 15:04:05"))
-	fmt.Printf("DEBUG: Time since last check = %v\n", time.Since(state.LastCheck))
-	fmt.Printf("DEBUG: Number of cached notifications = %d\n", len(state.SentNotifications))
-
-	// Debug all cached notifications
-	fmt.Println("DEBUG: Current cached notifications:")
-	for key, timestamp := range state.SentNotifications {
-		hoursSince := time.Since(timestamp).Hours()
-		fmt.Printf("  - %s: %.2f hours ago\n", key, hoursSince)
+	state, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cache state: %w", err)
 	}
 
-	// Determine what to run based on check type
-	shouldRunMorningDigest := checkType == "morning" || checkType == "both"
-	shouldRunEveningDigest := checkType == "evening" || checkType == "both"
-	shouldRunDailyReport := shouldRunMorningDigest || shouldRunEveningDigest
-	shouldRunInstantCheck := checkType == "instant" || checkType == "both" || (checkType == "auto" && scheduleType == "")
-
-	fmt.Printf("DEBUG: shouldRun conditions:\n")
-	fmt.Printf("  - Check type: %s\n", checkType)
-	fmt.Printf("  - Should run morning digest: %t\n", shouldRunMorningDigest)
-	fmt.Printf("  - Should run evening digest: %t\n", shouldRunEveningDigest)
-	fmt.Printf("  - Should run daily report: %t\n", shouldRunDailyReport)
-	fmt.Printf("  - Should run instant: %t\n", shouldRunInstantCheck)
+	slog.Debug("cache state loaded", "store", store, "last_check", state.LastCheck,
+		"last_daily_report", state.LastDailyReport, "sent_notifications", len(state.SentNotifications))
 
 	// Initialize clients
 	githubClient := github.NewClient(cfg.GitHubToken)
-	discordNotifier := notify.NewDiscordNotifier(cfg.DiscordWebhook)
+	githubClient.SetMaxPages(cfg.MaxPages)
+	if cfg.HTTPCache {
+		githubClient.SetResponseCache(stateResponseCache{state: state, readOnly: cfg.DryRun})
+	}
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifier: %w", err)
+	}
+	engine, err := rules.New(cfg.AlertRules())
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert rules: %w", err)
+	}
 
 	// Get current user if username not provided
 	username := cfg.Username
 	if username == "" {
 		user, err := githubClient.GetUser()
 		if err != nil {
-			log.Fatalf("Failed to get current user: %v", err)
+			return nil, fmt.Errorf("failed to get current user: %w", err)
 		}
 		username = user.Login
 	}
 
-	fmt.Printf("Running GitHub Notifier for user: %s\n", username)
-	fmt.Printf("Daily report: %t, Instant check: %t\n", shouldRunDailyReport, shouldRunInstantCheck)
+	slog.Info("running GitHub Notifier", "user", username)
+
+	return &app{
+		cfg:          cfg,
+		store:        store,
+		state:        state,
+		githubClient: githubClient,
+		notifier:     notifier,
+		rules:        engine,
+		username:     username,
+	}, nil
+}
+
+// run performs a single pass of the given check type (see runChecks).
+func (a *app) run(checkType string) {
+	runChecks(a.githubClient, a.notifier, a.store, a.state, a.username, a.cfg, a.rules, checkType)
+}
+
+// newStore returns the cache store selected by the cache.backend setting.
+func newStore(cfg *config.Config) (cache.Store, error) {
+	switch cfg.CacheBackend {
+	case "bolt":
+		return cache.NewBoltStore(cfg.CacheFile), nil
+	case "redis":
+		store, err := cache.NewRedisStore(cfg.CacheURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache store: %w", err)
+		}
+		return store, nil
+	default:
+		return cache.NewFileStore(cfg.CacheFile), nil
+	}
+}
+
+// newNotifier creates a notifier that delivers to every configured destination,
+// or previews what each would receive on a dry run.
+func newNotifier(cfg *config.Config) (notify.Notifier, error) {
+	var notifiers notify.MultiNotifier
+	for _, destination := range cfg.Destinations {
+		if cfg.DryRun {
+			notifiers = append(notifiers, notify.NewPreviewNotifier(os.Stdout, destination.Type))
+			continue
+		}
+		notifier, err := notify.NewNotifier(destination.Type, destination.URL)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+	return notifiers, nil
+}
+
+// historyRetention is how long sent alerts and webhook deliveries are
+// remembered.
+const historyRetention = 7 * 24 * time.Hour
+
+// runChecks performs a single pass of the given check type ("instant",
+// "morning", "evening", "weekly", "digest" or "both") and saves the cache if
+// anything changed.
+func runChecks(githubClient *github.Client, notifier notify.Notifier, store cache.Store, state *cache.State, username string, cfg *config.Config, engine *rules.Engine, checkType string) {
+	now := time.Now()
+
+	// "digest" picks morning or evening based on the local time in the configured timezone
+	if checkType == "digest" {
+		checkType = "morning"
+		if github.IsEveningAt(now.In(cfg.Location())) {
+			checkType = "evening"
+		}
+	}
+
+	// Determine what to run based on check type
+	shouldRunMorningDigest := checkType == "morning" || checkType == "both"
+	shouldRunEveningDigest := checkType == "evening" || checkType == "both"
+	shouldRunWeeklyDigest := checkType == "weekly"
+	shouldRunDailyReport := shouldRunMorningDigest || shouldRunEveningDigest || shouldRunWeeklyDigest
+	shouldRunInstantCheck := checkType == "instant" || checkType == "both"
+
+	logger := slog.With("check_type", checkType)
+	logger.Debug("starting run", "instant", shouldRunInstantCheck, "digest", shouldRunDailyReport)
 
 	// Track whether we made any changes that require saving the cache
 	hasChanges := false
 
+	// Retry messages that couldn't be delivered on earlier runs first
+	drainOutbox(notifier, state, cfg)
+
 	// Run instant checks
 	var hasNewAlerts bool
 	if shouldRunInstantCheck {
 		var err error
-		hasNewAlerts, err = runInstantChecks(githubClient, discordNotifier, state, username, cfg)
+		hasNewAlerts, err = runInstantChecks(githubClient, notifier, state, username, cfg, engine)
 		if err != nil {
-			log.Printf("Error running instant checks: %v", err)
+			logger.Error("instant checks failed", "error", err)
 			// Send error notification
 			errorMsg := notify.FormatErrorMessage(err)
-			discordNotifier.SendSimpleMessage(errorMsg)
+			notifier.SendMessage(notify.TextMessage(errorMsg))
+		}
+	}
+
+	// Run daily report (morning, evening or weekly)
+	if shouldRunDailyReport {
+		kind := "morning"
+		if shouldRunEveningDigest {
+			kind = "evening"
+		} else if shouldRunWeeklyDigest {
+			kind = "weekly"
 		}
+		if err := runDailyReport(githubClient, notifier, state, username, kind, cfg, engine); err != nil {
+			logger.Error("digest failed", "digest", kind, "error", err)
+			// Send error notification
+			errorMsg := notify.FormatErrorMessage(err)
+			notifier.SendMessage(notify.TextMessage(errorMsg))
+		}
+	}
+
+	// A dry run only previews messages; leave the cache exactly as it was
+	if cfg.DryRun {
+		logger.Info("dry run, cache left unchanged")
+		return
+	}
 
-		// Only update LastCheck if we found new alerts (to avoid unnecessary cache changes)
+	// Only update LastCheck if we found new alerts (to avoid unnecessary cache changes)
+	if shouldRunInstantCheck {
 		if hasNewAlerts {
 			state.LastCheck = now
 			hasChanges = true
-			fmt.Printf("DEBUG: Found new alerts, LastCheck updated and cache will be saved\n")
+			logger.Debug("new alerts found, LastCheck updated")
 		} else {
-			fmt.Printf("DEBUG: No new alerts found, LastCheck NOT updated to avoid cache changes\n")
+			logger.Debug("no new alerts, LastCheck not updated")
 		}
 	}
 
-	// Run daily report (morning or evening)
+	// Daily reports always update LastDailyReport, so we need to save
 	if shouldRunDailyReport {
-		isEvening := shouldRunEveningDigest
-		if err := runDailyReport(githubClient, discordNotifier, state, username, isEvening, cfg); err != nil {
-			log.Printf("Error running daily report: %v", err)
-			// Send error notification
-			errorMsg := notify.FormatErrorMessage(err)
-			discordNotifier.SendSimpleMessage(errorMsg)
-		}
-
-		// Daily reports always update LastDailyReport, so we need to save
 		state.LastDailyReport = now
 		hasChanges = true
-		fmt.Printf("DEBUG: Daily report sent, cache will be saved\n")
+		logger.Debug("digest sent, LastDailyReport updated")
 	}
 
 	// Clean up old entries to keep cache size manageable
-	cleanupRemovedEntries := state.CleanupOldEntries(7 * 24 * time.Hour) // Keep 7 days of history
+	cleanupRemovedEntries := state.CleanupOldEntries(historyRetention)
 	if cleanupRemovedEntries {
 		hasChanges = true
-		fmt.Printf("DEBUG: Cleanup removed old entries, cache will be saved\n")
+		logger.Debug("cleanup removed old cache entries")
+	}
+
+	// Persist fingerprints of alerted items and items that dropped out
+	if state.FingerprintsChanged() {
+		hasChanges = true
+		logger.Debug("item fingerprints updated", "fingerprints", len(state.Fingerprints))
+	}
+
+	// Persist alerts held back by max_repeats, so their counts outlive cleanup
+	if state.CappedChanged() {
+		hasChanges = true
+		logger.Debug("capped alerts updated", "capped", len(state.Capped))
+	}
+
+	// Persist alerts deferred during quiet hours, or sent after them
+	if state.DeferredChanged() {
+		hasChanges = true
+		logger.Debug("deferred alerts updated", "deferred", len(state.Deferred))
+	}
+
+	// Persist queued, delivered or dropped outbox messages
+	if state.OutboxChanged() {
+		hasChanges = true
+		logger.Debug("outbox updated", "pending", len(state.Outbox))
+	}
+
+	// Persist new ETags so the next run can make conditional requests
+	if state.HTTPCacheChanged() {
+		hasChanges = true
+		logger.Debug("HTTP response cache updated")
 	}
 
 	// Only save state if there were actual changes
 	if hasChanges {
-		fmt.Printf("DEBUG: Saving cache with %d notification entries to %s\n", len(state.SentNotifications), cfg.CacheFile)
-		if err := state.Save(cfg.CacheFile); err != nil {
-			log.Printf("Warning: Failed to save cache state: %v", err)
+		if err := store.Save(state); err != nil {
+			logger.Warn("failed to save cache state", "store", store, "error", err)
 		} else {
-			fmt.Println("Cache state updated successfully")
+			logger.Info("cache state saved", "store", store, "sent_notifications", len(state.SentNotifications))
 		}
 	} else {
-		fmt.Println("No changes detected, cache save skipped")
+		logger.Debug("no cache changes, save skipped")
 	}
-
-	fmt.Println("GitHub Notifier completed successfully")
 }
 
-func runInstantChecks(githubClient *github.Client, discordNotifier *notify.DiscordNotifier, state *cache.State, username string, cfg *config.Config) (bool, error) {
-	fmt.Println("Running instant checks...")
+func runInstantChecks(githubClient *github.Client, notifier notify.Notifier, state *cache.State, username string, cfg *config.Config, engine *rules.Engine) (bool, error) {
+	logger := slog.With("check_type", "instant")
+	logger.Info("running instant checks")
 
 	// Get current alerts (no commit tracking - handled by real-time action)
-	result, err := githubClient.CheckForAlerts(username)
+	result, err := githubClient.CheckForAlerts(username, cfg.PolicyFor(config.CategoryStalePRs).StaleAfter)
 	if err != nil {
 		return false, fmt.Errorf("failed to check for alerts: %w", err)
 	}
 
-	if !result.HasAlerts() {
-		fmt.Println("No alerts found")
-		return false, nil
-	}
-
-	// Filter for NEW alerts only - don't spam duplicates
-	// Different cooldown strategies for different alert types
-	standardCooldown := 24 * time.Hour // Standard 24-hour cooldown for most alerts
-	fmt.Printf("DEBUG: Using standard cooldown duration: %.2f hours\n", standardCooldown.Hours())
-	hasNewAlerts := false
+	// Everything GitHub listed, before filters drop any of it: only items
+	// missing from a complete list have really gone
+	listed := listedKeys(result)
 
-	// Collect keys to mark as sent ONLY after successful Discord delivery
-	var keysToMark []string
+	// Drop disabled categories and excluded repositories/authors
+	applyFilters(result, cfg, engine)
 
-	// Check PR reviews - only NEW ones (24-hour cooldown)
-	var newPRsNeedingReview []interface{}
-	for _, pr := range result.PRsNeedingReview {
-		key := fmt.Sprintf("review_request_%d", pr.Number)
-		isSent := state.IsNotificationSent(key, standardCooldown)
-		timeSinceLastSent := "never"
-		if lastSent, exists := state.SentNotifications[key]; exists {
-			timeSinceLastSent = fmt.Sprintf("%.2f hours ago", time.Since(lastSent).Hours())
-		}
-		fmt.Printf("DEBUG: PR #%d - key: %s, already sent: %t, last sent: %s\n", pr.Number, key, isSent, timeSinceLastSent)
-
-		if !isSent {
-			newPRsNeedingReview = append(newPRsNeedingReview, pr)
-			keysToMark = append(keysToMark, key) // Don't mark yet, collect keys
-			hasNewAlerts = true
+	// Quiet hours are over: send what was held back during them in one batch.
+	// If that fails the batch stays deferred or queued for the next run, and
+	// this run's own alerts still go out.
+	quiet := cfg.InQuietHours(time.Now())
+	sentDeferred := false
+	if !quiet && len(state.Deferred) > 0 {
+		if sentDeferred, err = sendDeferredAlerts(githubClient, notifier, state, username, cfg, result); err != nil {
+			logger.Error("failed to send deferred alerts, retrying next run", "error", err)
 		}
 	}
 
-	// Check stale PRs - only NEW ones (24-hour cooldown)
-	var newStaleOwnPRs []interface{}
-	for _, pr := range result.StaleOwnPRs {
-		key := fmt.Sprintf("stale_pr_%d", pr.Number)
-		isSent := state.IsNotificationSent(key, standardCooldown)
-		timeSinceLastSent := "never"
-		if lastSent, exists := state.SentNotifications[key]; exists {
-			timeSinceLastSent = fmt.Sprintf("%.2f hours ago", time.Since(lastSent).Hours())
-		}
-		fmt.Printf("DEBUG: Stale PR #%d - key: %s, already sent: %t, last sent: %s\n", pr.Number, key, isSent, timeSinceLastSent)
-
-		if !isSent {
-			newStaleOwnPRs = append(newStaleOwnPRs, pr)
-			keysToMark = append(keysToMark, key) // Don't mark yet, collect keys
-			hasNewAlerts = true
-		}
+	if !result.HasAlerts() {
+		logger.Info("no alerts found")
+		return sentDeferred, nil
 	}
 
-	// Check assigned issues - SPECIAL HANDLING: Mark as sent IMMEDIATELY to prevent cache race condition
-	var newAssignedIssues []interface{}
-	for _, issue := range result.AssignedIssues {
-		key := fmt.Sprintf("assigned_issue_%d", issue.Number)
-		// Use zero cooldown to check if this issue was EVER sent before (send only once forever)
-		isSent := state.IsNotificationSent(key, 0) // 0 means check if exists at all
-		timeSinceLastSent := "never"
-		if lastSent, exists := state.SentNotifications[key]; exists {
-			timeSinceLastSent = fmt.Sprintf("%.2f hours ago", time.Since(lastSent).Hours())
-		}
-		fmt.Printf("DEBUG: Assigned Issue #%d - key: %s, already sent: %t, last sent: %s (send once only)\n", issue.Number, key, isSent, timeSinceLastSent)
-
-		if !isSent {
-			// CRITICAL: Mark as sent IMMEDIATELY to prevent GitHub Actions cache race condition
-			state.MarkNotificationSent(key)
-			fmt.Printf("DEBUG: IMMEDIATELY marked assigned issue as sent to prevent race condition: %s\n", key)
-
-			// Save cache immediately to prevent race condition between workflow runs
-			if err := state.Save(cfg.CacheFile); err != nil {
-				fmt.Printf("WARNING: Failed to save cache immediately after marking assigned issue: %v\n", err)
-			} else {
-				fmt.Printf("DEBUG: Cache saved immediately after marking assigned issue\n")
-			}
+	// Commit tracking is now handled by real-time GitHub Actions
+	// No need to check for commits in scheduled runs
+	result.RecentCommits = nil
 
-			newAssignedIssues = append(newAssignedIssues, issue)
-			hasNewAlerts = true
-		}
-	}
+	// Filter for NEW alerts only - don't spam duplicates
+	// Different cooldown strategies for different alert types (configurable per category)
+	filteredResult, fingerprints := newAlerts(state, cfg, result, logger)
 
-	// Check repository invitations - only NEW and NON-EXPIRED ones (24-hour cooldown)
-	var newRepositoryInvitations []interface{}
-	for _, invitation := range result.RepositoryInvitations {
-		key := fmt.Sprintf("invitation_%d", invitation.ID)
-		if !state.IsNotificationSent(key, standardCooldown) {
-			newRepositoryInvitations = append(newRepositoryInvitations, invitation)
-			// Only mark as sent if invitation is not expired (will actually be sent)
-			if !invitation.IsExpired() {
-				keysToMark = append(keysToMark, key)
-			}
-			hasNewAlerts = true
-		}
+	// Review requests that dropped out were reviewed, withdrawn or closed
+	if cfg.CategoryEnabled(config.CategoryReviewRequests) && result.Complete(config.CategoryReviewRequests) {
+		state.MarkGone(cache.ReviewRequestPrefix, listed)
 	}
-
-	// Check unread notifications - only NEW ones (24-hour cooldown)
-	var newUnreadNotifications []interface{}
-	for _, notification := range result.UnreadNotifications {
-		key := fmt.Sprintf("notification_%s", notification.ID)
-		if !state.IsNotificationSent(key, standardCooldown) {
-			newUnreadNotifications = append(newUnreadNotifications, notification)
-			keysToMark = append(keysToMark, key) // Don't mark yet, collect keys
-			hasNewAlerts = true
-		}
+	// Stale PRs that dropped out had new activity, or were merged or closed
+	if cfg.CategoryEnabled(config.CategoryStalePRs) && result.Complete(config.CategoryStalePRs) {
+		state.MarkGone(cache.StalePRPrefix, listed)
 	}
-
-	// Check failed workflows - SPECIAL HANDLING: Mark as sent IMMEDIATELY to prevent cache race condition
-	var newFailedWorkflows []interface{}
-	for _, workflow := range result.FailedWorkflows {
-		key := fmt.Sprintf("workflow_%d", workflow.ID)
-		// Check if we've already sent this workflow failure (no cooldown, but track to prevent repeats)
-		if !state.IsNotificationSent(key, 0) { // 0 duration means check if exists at all
-			// CRITICAL: Mark as sent IMMEDIATELY to prevent GitHub Actions cache race condition
-			state.MarkNotificationSent(key)
-			fmt.Printf("DEBUG: IMMEDIATELY marked workflow failure as sent to prevent race condition: %s\n", key)
-
-			// Save cache immediately to prevent race condition between workflow runs
-			if err := state.Save(cfg.CacheFile); err != nil {
-				fmt.Printf("WARNING: Failed to save cache immediately after marking workflow failure: %v\n", err)
-			} else {
-				fmt.Printf("DEBUG: Cache saved immediately after marking workflow failure\n")
-			}
-
-			newFailedWorkflows = append(newFailedWorkflows, workflow)
-			hasNewAlerts = true
-		} else {
-			fmt.Printf("DEBUG: Failed Workflow %d - already sent, skipping\n", workflow.ID)
-		}
+	// Assigned issues that dropped out were closed or unassigned
+	if cfg.CategoryEnabled(config.CategoryAssignedIssues) && result.Complete(config.CategoryAssignedIssues) {
+		state.MarkGone(cache.AssignedIssuePrefix, listed)
 	}
 
-	// Commit tracking is now handled by real-time GitHub Actions
-	// No need to check for commits in scheduled runs
-
 	// Only send notification if there are NEW alerts
-	if !hasNewAlerts {
-		fmt.Println("No new alerts found (all previously notified)")
-		return false, nil
+	if !filteredResult.HasAlerts() {
+		logger.Info("no new alerts (all previously notified)")
+		return sentDeferred, nil
 	}
 
-	// Create filtered result with only new alerts
-	filteredResult := &github.CheckResult{
-		PRsNeedingReview:      []github.PullRequest{},
-		StaleOwnPRs:           []github.PullRequest{},
-		AssignedIssues:        []github.Issue{},
-		UnreadNotifications:   []github.Notification{},
-		RepositoryInvitations: []github.Invitation{},
-		FailedWorkflows:       []github.WorkflowRun{},
-		// RecentCommits removed - handled by real-time action
-	}
+	// Collect keys to mark as sent ONLY after successful delivery
+	keysToMark := alertKeys(filteredResult)
 
-	// Convert filtered items back to their original types
-	for _, pr := range newPRsNeedingReview {
-		filteredResult.PRsNeedingReview = append(filteredResult.PRsNeedingReview, pr.(github.PullRequest))
-	}
-	for _, pr := range newStaleOwnPRs {
-		filteredResult.StaleOwnPRs = append(filteredResult.StaleOwnPRs, pr.(github.PullRequest))
-	}
-	for _, issue := range newAssignedIssues {
-		filteredResult.AssignedIssues = append(filteredResult.AssignedIssues, issue.(github.Issue))
-	}
-	for _, invitation := range newRepositoryInvitations {
-		filteredResult.RepositoryInvitations = append(filteredResult.RepositoryInvitations, invitation.(github.Invitation))
-	}
-	for _, notification := range newUnreadNotifications {
-		filteredResult.UnreadNotifications = append(filteredResult.UnreadNotifications, notification.(github.Notification))
-	}
-	for _, workflow := range newFailedWorkflows {
-		filteredResult.FailedWorkflows = append(filteredResult.FailedWorkflows, workflow.(github.WorkflowRun))
+	// During quiet hours only urgent alerts go out; the rest wait in the cache
+	if quiet {
+		deferred := deferAlerts(state, cfg, filteredResult, fingerprints)
+		keysToMark = alertKeys(filteredResult)
+		logger.Info("quiet hours, alerts deferred", "deferred", deferred, "urgent", filteredResult.GetAlertCount())
+		if !filteredResult.HasAlerts() {
+			return false, nil
+		}
 	}
-	// Commit processing removed - handled by real-time GitHub Action
 
-	// Debug: Show what's in the filtered result
-	fmt.Printf("DEBUG: Filtered result contains:\n")
-	fmt.Printf("  - PRs needing review: %d\n", len(filteredResult.PRsNeedingReview))
-	fmt.Printf("  - Stale own PRs: %d\n", len(filteredResult.StaleOwnPRs))
-	fmt.Printf("  - Assigned issues: %d\n", len(filteredResult.AssignedIssues))
-	fmt.Printf("  - Unread notifications: %d\n", len(filteredResult.UnreadNotifications))
-	fmt.Printf("  - Failed workflows: %d\n", len(filteredResult.FailedWorkflows))
-	fmt.Printf("  - Repository invitations: %d\n", len(filteredResult.RepositoryInvitations))
-	fmt.Printf("  - Recent commits: 0 (handled by real-time action)\n")
-
-	for i, issue := range filteredResult.AssignedIssues {
-		fmt.Printf("    Assigned Issue %d: #%d - %s\n", i+1, issue.Number, issue.Title)
-	}
+	logger.Debug("new alerts",
+		config.CategoryReviewRequests, len(filteredResult.PRsNeedingReview),
+		config.CategoryStalePRs, len(filteredResult.StaleOwnPRs),
+		config.CategoryAssignedIssues, len(filteredResult.AssignedIssues),
+		config.CategoryNotifications, len(filteredResult.UnreadNotifications),
+		config.CategoryWorkflows, len(filteredResult.FailedWorkflows),
+		config.CategoryInvitations, len(filteredResult.RepositoryInvitations))
 
 	// Get user avatar for consistent formatting
 	var avatarURL string
@@ -382,27 +384,27 @@ func runInstantChecks(githubClient *github.Client, discordNotifier *notify.Disco
 	}
 
 	if message != nil {
-		if err := discordNotifier.SendMessage(message); err != nil {
-			return false, fmt.Errorf("failed to send Discord message: %w", err)
+		if err := notifier.SendMessage(message); err != nil {
+			// Keep the message (and its keys) in the outbox for the destinations that failed
+			handled := queueMessage(state, cfg, notifier, message, keysToMark, err)
+			if handled {
+				storeFingerprints(state, fingerprints)
+			}
+			return handled, fmt.Errorf("failed to send notification: %w", err)
 		}
 
-		// Only mark notifications as sent AFTER successful Discord delivery
-		// (Skip assigned issues and workflows as they were marked immediately to prevent race conditions)
-		remainingKeys := []string{}
-		for _, key := range keysToMark {
-			// Skip keys that were already marked immediately to prevent race conditions
-			if !strings.HasPrefix(key, "assigned_issue_") && !strings.HasPrefix(key, "workflow_") {
-				remainingKeys = append(remainingKeys, key)
-			}
+		if cfg.DryRun {
+			logger.Info("dry run, alerts not marked as sent", "keys", len(keysToMark))
+			return true, nil
 		}
 
-		fmt.Printf("DEBUG: Marking %d notifications as sent (excluding %d already marked):\n", len(remainingKeys), len(keysToMark)-len(remainingKeys))
-		for _, key := range remainingKeys {
-			fmt.Printf("  - Marking as sent: %s\n", key)
+		// Only mark notifications as sent AFTER successful delivery
+		for _, key := range keysToMark {
 			state.MarkNotificationSent(key)
 		}
+		storeFingerprints(state, fingerprints)
 
-		// Calculate actual count of items being sent to Discord
+		// Calculate actual count of items being sent
 		actualItemCount := len(filteredResult.PRsNeedingReview) +
 			len(filteredResult.StaleOwnPRs) +
 			len(filteredResult.AssignedIssues) +
@@ -411,44 +413,42 @@ func runInstantChecks(githubClient *github.Client, discordNotifier *notify.Disco
 			len(filteredResult.RepositoryInvitations)
 			// RecentCommits excluded - handled by real-time action
 
-		fmt.Printf("Sent instant alert with %d NEW items (filtered duplicates & expired invitations)\n", actualItemCount)
-		fmt.Printf("Marked %d keys as sent in cache (including %d marked immediately for race prevention)\n", len(keysToMark), len(keysToMark)-len(remainingKeys))
+		logger.Info("sent instant alert", "items", actualItemCount, "marked_keys", len(keysToMark))
 	}
 
 	return true, nil
 }
 
-func runDailyReport(githubClient *github.Client, discordNotifier *notify.DiscordNotifier, state *cache.State, username string, isEvening bool, cfg *config.Config) error {
-	if isEvening {
-		fmt.Println("Running evening digest...")
+// runDailyReport sends the "morning", "evening" or "weekly" digest.
+func runDailyReport(githubClient *github.Client, notifier notify.Notifier, state *cache.State, username string, kind string, cfg *config.Config, engine *rules.Engine) error {
+	logger := slog.With("check_type", kind)
+	logger.Info("running digest")
+
+	// Generate the digest for "today" (or the week up to today) in the configured timezone
+	now := time.Now().In(cfg.Location())
+	var digest *github.DailyDigest
+	var err error
+	if kind == "weekly" {
+		digest, err = githubClient.GenerateWeeklyDigest(username, cfg.TrackAllCommits, now)
 	} else {
-		fmt.Println("Running morning digest...")
+		digest, err = githubClient.GenerateDailyDigest(username, cfg.TrackAllCommits, kind == "evening", now)
 	}
-
-	// Generate daily digest with evening flag and commit tracking setting
-	digest, err := githubClient.GenerateDailyDigest(username, cfg.TrackAllCommits)
 	if err != nil {
-		return fmt.Errorf("failed to generate daily digest: %w", err)
+		return fmt.Errorf("failed to generate %s digest: %w", kind, err)
 	}
 
-	// Set the evening flag manually since we can't pass it through API
-	digest.IsEvening = isEvening
-
-	// Debug: Show what's in the digest
-	fmt.Printf("DEBUG: Daily digest contains:\n")
-	fmt.Printf("  - PRs opened today: %d\n", len(digest.PRsOpened))
-	fmt.Printf("  - PRs merged today: %d\n", len(digest.PRsMerged))
-	fmt.Printf("  - Issues opened today: %d\n", len(digest.IssuesOpened))
-	fmt.Printf("  - Issues closed today: %d\n", len(digest.IssuesClosed))
-	fmt.Printf("  - Commits today: %d\n", len(digest.CommitsToday))
-	fmt.Printf("  - Failed workflows: %d\n", len(digest.FailedWorkflows))
-	fmt.Printf("  - Pending reviews: %d\n", len(digest.PendingReviews))
-	fmt.Printf("  - Assigned issues: %d\n", len(digest.AssignedIssues))
-	fmt.Printf("  - Repository invitations: %d\n", len(digest.RepositoryInvitations))
+	// Drop excluded repositories/authors
+	applyDigestFilters(digest, engine)
 
-	for i, issue := range digest.AssignedIssues {
-		fmt.Printf("    Assigned Issue %d: #%d - %s (State: %s)\n", i+1, issue.Number, issue.Title, issue.State)
-	}
+	logger.Debug("digest generated",
+		"prs_opened", len(digest.PRsOpened),
+		"prs_merged", len(digest.PRsMerged),
+		"issues_opened", len(digest.IssuesOpened),
+		"issues_closed", len(digest.IssuesClosed),
+		"commits", len(digest.CommitsToday),
+		"pending_reviews", len(digest.PendingReviews),
+		"assigned_issues", len(digest.AssignedIssues),
+		"invitations", len(digest.RepositoryInvitations))
 
 	// Get user avatar for consistent formatting
 	var avatarURL string
@@ -462,24 +462,248 @@ func runDailyReport(githubClient *github.Client, discordNotifier *notify.Discord
 		return fmt.Errorf("failed to format daily digest: %w", err)
 	}
 
-	if err := discordNotifier.SendMessage(message); err != nil {
-		return fmt.Errorf("failed to send Discord message: %w", err)
+	if err := notifier.SendMessage(message); err != nil {
+		queueMessage(state, cfg, notifier, message, nil, err)
+		return fmt.Errorf("failed to send notification: %w", err)
 	}
 
-	if isEvening {
-		fmt.Println("Sent evening digest")
-	} else {
-		fmt.Println("Sent morning digest")
-	}
+	logger.Info("sent digest")
 	return nil
 }
 
-func init() {
-	// Set up logging
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+// queueMessage handles a message that notifier failed to deliver with
+// sendErr. Each destination that failed gets its own outbox entry, resuming
+// from the first part it didn't receive, unless it rejected the message's
+// content. If some destination did receive the message, keys are marked sent
+// now; otherwise the first queued copy to go through marks them. It reports
+// whether keys were marked or queued.
+func queueMessage(state *cache.State, cfg *config.Config, notifier notify.Notifier, message *notify.Message, keys []string, sendErr error) bool {
+	if cfg.DryRun {
+		return false
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		slog.Error("failed to queue message", "error", err)
+		return false
+	}
+
+	failures := notify.DestinationErrors(sendErr)
+	delivered := len(failures) < len(notify.Destinations(notifier))
+	queued := false
+	for _, failure := range failures {
+		logger := slog.With("destination", failure.Destination+1, "error", failure.Err)
+		if rejected(failure.Err) {
+			logger.Error("destination rejected the message, not queued")
+			continue
+		}
+		entry := cache.OutboxEntry{
+			Message:     data,
+			Destination: failure.Destination,
+			Part:        notify.SentParts(failure.Err),
+		}
+		if failure.Destination < len(cfg.Destinations) {
+			entry.DestinationType = cfg.Destinations[failure.Destination].Type
+		}
+		if !delivered {
+			entry.Keys = keys
+		}
+		state.Enqueue(entry, failure.Err)
+		queued = true
+		logger.Warn("delivery failed, message queued for the next run", "keys", len(entry.Keys), "part", entry.Part)
+	}
+
+	if delivered {
+		for _, key := range keys {
+			state.MarkNotificationSent(key)
+		}
+		return true
+	}
+	return queued
+}
+
+// rejected reports whether a destination refused the message's content, so
+// sending it again won't help.
+func rejected(err error) bool {
+	var discordErr *notify.DiscordError
+	return errors.As(err, &discordErr) && discordErr.Rejected()
+}
+
+// storeFingerprints records what the alerted items looked like, so only
+// later changes re-alert them.
+// newAlerts returns the alerts in result that the categories' policies let
+// through: items not alerted within their cooldown, items that changed in a
+// way their policy re-alerts on (with why, in Changes), and no item already
+// waiting in the outbox. It also returns the fingerprints to store once the
+// alerts are delivered. Reviews and pushes are events of their own and are
+// all let through.
+func newAlerts(state *cache.State, cfg *config.Config, result *github.CheckResult, logger *slog.Logger) (*github.CheckResult, map[string]cache.Fingerprint) {
+	fresh := &github.CheckResult{
+		Reviews:       result.Reviews,
+		RecentCommits: result.RecentCommits,
+		Changes:       make(map[string]string),
+	}
+	fingerprints := make(map[string]cache.Fingerprint)
+
+	isNew := func(category, repo, key string) bool {
+		isSent := alreadyAlerted(state, cfg, category, key) || state.IsNotificationQueued(key)
+		logAlert(logger, category, repo, key, isSent)
+		return !isSent
+	}
+	// Already sent, unless it changed in a way the policy re-alerts on
+	isNewOrChanged := func(category, repo, key, htmlURL string, fingerprint cache.Fingerprint) bool {
+		isSent := alreadyAlerted(state, cfg, category, key)
+		change := trackChange(state, cfg, category, key, fingerprint)
+		isSent = (isSent && change == "") || state.IsNotificationQueued(key)
+		logAlert(logger, category, repo, key, isSent)
+		if isSent {
+			return false
+		}
+		fingerprints[key] = fingerprint
+		if change != "" {
+			fresh.Changes[htmlURL] = change
+		}
+		return true
+	}
 
-	// Check if running in GitHub Actions
-	if os.Getenv("GITHUB_ACTIONS") == "true" {
-		fmt.Println("Running in GitHub Actions environment")
+	for _, pr := range result.PRsNeedingReview {
+		key := cache.ReviewRequestKey(pr.RepoFullName(), pr.Number)
+		if isNewOrChanged(config.CategoryReviewRequests, pr.RepoFullName(), key, pr.HTMLURL, pullRequestFingerprint(pr)) {
+			fresh.PRsNeedingReview = append(fresh.PRsNeedingReview, pr)
+		}
+	}
+	for _, pr := range result.StaleOwnPRs {
+		key := cache.StalePRKey(pr.RepoFullName(), pr.Number)
+		if isNewOrChanged(config.CategoryStalePRs, pr.RepoFullName(), key, pr.HTMLURL, pullRequestFingerprint(pr)) {
+			fresh.StaleOwnPRs = append(fresh.StaleOwnPRs, pr)
+		}
+	}
+	for _, issue := range result.AssignedIssues {
+		key := cache.AssignedIssueKey(issue.RepoFullName(), issue.Number)
+		if isNewOrChanged(config.CategoryAssignedIssues, issue.RepoFullName(), key, issue.HTMLURL, issueFingerprint(issue)) {
+			fresh.AssignedIssues = append(fresh.AssignedIssues, issue)
+		}
+	}
+	for _, invitation := range result.RepositoryInvitations {
+		if isNew(config.CategoryInvitations, invitation.Repository.FullName, cache.InvitationKey(invitation.ID)) {
+			fresh.RepositoryInvitations = append(fresh.RepositoryInvitations, invitation)
+		}
+	}
+	for _, membership := range result.Memberships {
+		if isNew(config.CategoryInvitations, membership.Repository.FullName, cache.MembershipKey(membership.Repository.FullName)) {
+			fresh.Memberships = append(fresh.Memberships, membership)
+		}
+	}
+	for _, notification := range result.UnreadNotifications {
+		if isNew(config.CategoryNotifications, notification.Repository.FullName, cache.NotificationKey(notification.ID)) {
+			fresh.UnreadNotifications = append(fresh.UnreadNotifications, notification)
+		}
+	}
+	for _, workflow := range result.FailedWorkflows {
+		if isNew(config.CategoryWorkflows, workflow.Repository.FullName, cache.WorkflowKey(workflow.ID)) {
+			fresh.FailedWorkflows = append(fresh.FailedWorkflows, workflow)
+		}
 	}
+	return fresh, fingerprints
+}
+
+func storeFingerprints(state *cache.State, fingerprints map[string]cache.Fingerprint) {
+	for key, fingerprint := range fingerprints {
+		state.SetFingerprint(key, fingerprint)
+	}
+}
+
+// drainOutbox retries the messages queued by earlier runs, each to the
+// destination it failed for and from the part it got to, marking their keys
+// sent once delivered and dropping those that keep failing.
+func drainOutbox(notifier notify.Notifier, state *cache.State, cfg *config.Config) {
+	pending := state.PendingMessages()
+	if len(pending) == 0 {
+		return
+	}
+	if cfg.DryRun {
+		slog.Info("dry run, outbox not drained", "pending", len(pending))
+		return
+	}
+	for _, entry := range pending {
+		retryQueued(notifier, cfg, entry).apply(state)
+	}
+}
+
+// outboxAttempt is the outcome of one attempt to deliver a queued message.
+// Sending and recording it are separate so the state can be locked only for
+// the latter.
+type outboxAttempt struct {
+	entry cache.OutboxEntry
+	err   error
+	drop  bool // The message can never be delivered, whatever the attempts left
+}
+
+// retryQueued sends a queued message to the destination it failed for. It
+// doesn't touch the state.
+func retryQueued(notifier notify.Notifier, cfg *config.Config, entry cache.OutboxEntry) outboxAttempt {
+	var message notify.Message
+	if err := json.Unmarshal(entry.Message, &message); err != nil {
+		return outboxAttempt{entry: entry, err: fmt.Errorf("unreadable queued message: %w", err), drop: true}
+	}
+	destinations := notify.Destinations(notifier)
+	if entry.Destination >= len(destinations) ||
+		(entry.DestinationType != "" && entry.DestinationType != cfg.Destinations[entry.Destination].Type) {
+		return outboxAttempt{entry: entry, err: errors.New("destination no longer configured"), drop: true}
+	}
+
+	err := notify.SendFrom(destinations[entry.Destination], &message, entry.Part)
+	return outboxAttempt{entry: entry, err: err, drop: rejected(err)}
+}
+
+// apply records the attempt: the keys are marked sent once delivered, and
+// the message is dropped if it can't ever be or has run out of attempts.
+func (a outboxAttempt) apply(state *cache.State) {
+	entry := a.entry
+	logger := slog.With("outbox_id", entry.ID, "destination", entry.Destination+1, "attempts", entry.Attempts+1)
+	if a.err == nil {
+		state.Delivered(entry.ID)
+		logger.Info("delivered queued message", "keys", len(entry.Keys))
+		return
+	}
+	if state.DeliveryFailed(entry.ID, notify.SentParts(a.err), a.err, a.drop) {
+		logger.Error("dropped queued message after failed delivery", "error", a.err)
+	} else {
+		logger.Warn("queued message still undeliverable, will retry", "error", a.err)
+	}
+}
+
+// stateResponseCache persists GitHub API responses (and their ETags) in the
+// notification cache so conditional requests survive between runs.
+type stateResponseCache struct {
+	state    *cache.State
+	readOnly bool // Serve cached responses but don't record new ones (dry runs)
+}
+
+func (c stateResponseCache) LoadResponse(url string) (github.CachedResponse, bool) {
+	entry, ok := c.state.GetHTTPEntry(url)
+	if !ok {
+		return github.CachedResponse{}, false
+	}
+	return github.CachedResponse{
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+		Link:         entry.Link,
+		Body:         entry.Body,
+		StoredAt:     entry.StoredAt,
+		PollAfter:    entry.PollAfter,
+	}, true
+}
+
+func (c stateResponseCache) StoreResponse(url string, resp github.CachedResponse) {
+	if c.readOnly {
+		return
+	}
+	c.state.PutHTTPEntry(url, cache.HTTPEntry{
+		ETag:         resp.ETag,
+		LastModified: resp.LastModified,
+		Link:         resp.Link,
+		Body:         resp.Body,
+		StoredAt:     resp.StoredAt,
+		PollAfter:    resp.PollAfter,
+	})
 }
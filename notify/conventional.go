@@ -0,0 +1,314 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wilfierd/gh-notify/github"
+)
+
+// conventionalCommit is a commit message split up per the Conventional
+// Commits spec (https://www.conventionalcommits.org). Type is empty for
+// messages that don't follow it, and Description then holds the subject line.
+type conventionalCommit struct {
+	Type           string // Lowercased, e.g. feat or fix
+	Scope          string
+	Description    string
+	Breaking       bool
+	BreakingChange string     // Text of the BREAKING CHANGE footer, if any
+	Refs           []issueRef // Issues referenced below the subject line
+}
+
+// issueRef is an issue or pull request referenced in a commit message.
+type issueRef struct {
+	Repo   string // owner/name for references to another repository
+	Number int
+	Closes bool // Written as "fixes #1", "closes #1" and so on
+}
+
+var (
+	conventionalHeader = regexp.MustCompile(`^(\w+)(?:\(([^()]*)\))?(!)?: +(.+)$`)
+	breakingFooter     = regexp.MustCompile(`^BREAKING[ -]CHANGE: *(.*)$`)
+	footerToken        = regexp.MustCompile(`^(?:[\w-]+: |[\w-]+ #|BREAKING[ -]CHANGE:)`)
+	// Groups: preceding character, closing keyword, repository, number
+	issueReference = regexp.MustCompile(`(?i)(^|[\s(\[,])(?:(close[sd]?|fix(?:e[sd])?|resolve[sd]?):? +)?([\w.-]+/[\w.-]+)?#(\d+)\b`)
+)
+
+// commitTypeTitles orders the digest's commit groups. Other types, and
+// messages without one, are listed last.
+var commitTypeTitles = []struct{ Type, Title string }{
+	{"feat", "✨ Features"},
+	{"fix", "🐛 Fixes"},
+	{"perf", "⚡ Performance"},
+	{"refactor", "♻️ Refactoring"},
+	{"docs", "📚 Documentation"},
+	{"test", "🧪 Tests"},
+	{"build", "📦 Build"},
+	{"ci", "🤖 CI"},
+	{"style", "🎨 Style"},
+	{"chore", "🧹 Chores"},
+	{"revert", "⏪ Reverts"},
+}
+
+func parseCommitMessage(message string) conventionalCommit {
+	subject, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	subject = strings.TrimSpace(subject)
+
+	commit := conventionalCommit{Description: subject}
+	if m := conventionalHeader.FindStringSubmatch(subject); m != nil {
+		commit.Type = strings.ToLower(m[1])
+		commit.Scope = strings.TrimSpace(m[2])
+		commit.Breaking = m[3] != ""
+		commit.Description = m[4]
+	}
+
+	if text, ok := breakingChange(body); ok {
+		commit.Breaking = true
+		commit.BreakingChange = text
+	}
+
+	// References in the subject are linked where they are
+	seen := make(map[issueRef]bool)
+	for _, m := range issueReference.FindAllStringSubmatch(subject, -1) {
+		seen[newIssueRef(m)] = true
+	}
+	for _, m := range issueReference.FindAllStringSubmatch(body, -1) {
+		ref := newIssueRef(m)
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		ref.Closes = m[2] != ""
+		commit.Refs = append(commit.Refs, ref)
+	}
+	return commit
+}
+
+// breakingChange returns the text of the BREAKING CHANGE footer in body, and
+// whether there is one. The footer runs on until a blank line or the next
+// footer, and its lines are joined into one paragraph.
+func breakingChange(body string) (string, bool) {
+	var text []string
+	found := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if !found {
+			if m := breakingFooter.FindStringSubmatch(line); m != nil {
+				found = true
+				if m[1] != "" {
+					text = append(text, m[1])
+				}
+			}
+			continue
+		}
+		if line == "" || footerToken.MatchString(line) {
+			break
+		}
+		text = append(text, line)
+	}
+	return strings.Join(text, " "), found
+}
+
+// newIssueRef returns the reference an issueReference match is for, without
+// Closes.
+func newIssueRef(match []string) issueRef {
+	ref := issueRef{Repo: match[3]}
+	fmt.Sscan(match[4], &ref.Number)
+	return ref
+}
+
+// summary formats the commit for a list: a 💥 for breaking changes, the type
+// and scope (or just the scope without withType), the description cut to
+// maxLength runes (0 for no limit), and the issues referenced further down.
+// Issue numbers link to repoURL's issues when it is set.
+func (c conventionalCommit) summary(repoURL string, maxLength int, withType bool) string {
+	var b strings.Builder
+	if c.Breaking {
+		b.WriteString("💥 ")
+	}
+	switch {
+	case withType && c.Type != "":
+		b.WriteString("**" + c.Type)
+		if c.Scope != "" {
+			b.WriteString("(" + c.Scope + ")")
+		}
+		if c.Breaking {
+			b.WriteString("!")
+		}
+		b.WriteString(":** ")
+	case c.Scope != "":
+		b.WriteString("**" + c.Scope + ":** ")
+	}
+
+	description := c.Description
+	if maxLength > 0 {
+		description = truncateWords(description, maxLength)
+	}
+	b.WriteString(linkIssues(description, repoURL))
+
+	if len(c.Refs) > 0 {
+		var refs []string
+		for _, ref := range c.Refs {
+			text := fmt.Sprintf("%s#%d", ref.Repo, ref.Number)
+			if repoURL != "" {
+				text = fmt.Sprintf("[%s](%s)", text, issueURL(repoURL, ref.Repo, ref.Number))
+			}
+			if ref.Closes {
+				text = "fixes " + text
+			}
+			refs = append(refs, text)
+		}
+		b.WriteString(" · 🔗 " + strings.Join(refs, ", "))
+	}
+	return b.String()
+}
+
+// linkIssues turns the issue references in text into links to repoURL's
+// issues. GitHub redirects them to the pull request when that's what the
+// number is.
+func linkIssues(text, repoURL string) string {
+	if repoURL == "" {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range issueReference.FindAllStringSubmatchIndex(text, -1) {
+		start := m[8] - 1 // The #
+		repo := ""
+		if m[6] >= 0 {
+			start = m[6]
+			repo = text[m[6]:m[7]]
+		}
+		var number int
+		fmt.Sscan(text[m[8]:m[9]], &number)
+
+		b.WriteString(text[last:start])
+		fmt.Fprintf(&b, "[%s](%s)", text[start:m[9]], issueURL(repoURL, repo, number))
+		last = m[9]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// issueURL returns the URL of an issue in repoURL's repository, or in repo on
+// the same host when it is set.
+func issueURL(repoURL, repo string, number int) string {
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	if repo != "" {
+		host := repoURL
+		for i := 0; i < 2; i++ {
+			if slash := strings.LastIndex(host, "/"); slash > 0 {
+				host = host[:slash]
+			}
+		}
+		repoURL = host + "/" + repo
+	}
+	return fmt.Sprintf("%s/issues/%d", repoURL, number)
+}
+
+// commitRepoURL returns the web URL of the repository a commit was made in.
+func commitRepoURL(commit github.Commit) string {
+	if commit.Repository.FullName == "" {
+		return ""
+	}
+	return "https://github.com/" + commit.Repository.FullName
+}
+
+// truncateWords cuts s to at most max runes at a word boundary, so issue
+// references and links aren't cut in half.
+func truncateWords(s string, max int) string {
+	if runeLen(s) <= max {
+		return s
+	}
+	cut := string([]rune(s)[:max-1])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.:;") + "…"
+}
+
+const otherCommitsTitle = "💻 Other Commits"
+
+// commitGroup is a digest section of commits of one type, sorted by
+// repository.
+type commitGroup struct {
+	Title   string
+	Type    string // "" for breaking changes and commits of other types
+	Commits []github.Commit
+	parsed  []conventionalCommit
+}
+
+// groupCommits sorts commits into a group for breaking changes, one per known
+// type, and one for everything else, leaving out empty groups.
+func groupCommits(commits []github.Commit) []*commitGroup {
+	breaking := &commitGroup{Title: "💥 Breaking Changes"}
+	other := &commitGroup{Title: otherCommitsTitle}
+	byType := make(map[string]*commitGroup)
+	var groups []*commitGroup
+	for _, t := range commitTypeTitles {
+		byType[t.Type] = &commitGroup{Title: t.Title, Type: t.Type}
+	}
+
+	for _, commit := range commits {
+		parsed := parseCommitMessage(commit.Message)
+		group := other
+		if parsed.Breaking {
+			group = breaking
+		} else if g, ok := byType[parsed.Type]; ok {
+			group = g
+		}
+		group.Commits = append(group.Commits, commit)
+		group.parsed = append(group.parsed, parsed)
+	}
+
+	candidates := []*commitGroup{breaking}
+	for _, t := range commitTypeTitles {
+		candidates = append(candidates, byType[t.Type])
+	}
+	candidates = append(candidates, other)
+	for _, group := range candidates {
+		if len(group.Commits) == 0 {
+			continue
+		}
+		sort.Stable(group)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func (g *commitGroup) Len() int { return len(g.Commits) }
+func (g *commitGroup) Less(i, j int) bool {
+	return g.Commits[i].Repository.FullName < g.Commits[j].Repository.FullName
+}
+func (g *commitGroup) Swap(i, j int) {
+	g.Commits[i], g.Commits[j] = g.Commits[j], g.Commits[i]
+	g.parsed[i], g.parsed[j] = g.parsed[j], g.parsed[i]
+}
+
+// format lists up to limit commits under a heading per repository. Types are
+// shown only where the group mixes them, and breaking changes get their
+// footer's explanation.
+func (g *commitGroup) format(limit int) string {
+	var lines []string
+	repo := ""
+	for i, commit := range g.Commits {
+		if i >= limit {
+			lines = append(lines, fmt.Sprintf("... and %s", pluralize(len(g.Commits)-limit, "more commit")))
+			break
+		}
+		if i == 0 || commit.Repository.FullName != repo {
+			repo = commit.Repository.FullName
+			lines = append(lines, fmt.Sprintf("**%s**", commit.Repository.Name))
+		}
+		parsed := g.parsed[i]
+		line := fmt.Sprintf("• [`%s`](%s) %s", shortSHA(commit.SHA), commit.URL,
+			parsed.summary(commitRepoURL(commit), 60, g.Type == ""))
+		if parsed.BreakingChange != "" {
+			line += "\n  ↳ " + truncateWords(parsed.BreakingChange, 80)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,271 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wilfierd/gh-notify/github"
+)
+
+func TestParseCommitMessageBreakingChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		breaking bool
+		text     string
+	}{
+		{"none", "feat: add widgets\n\nSome body.", false, ""},
+		{"bang", "feat!: drop v1", true, ""},
+		{"one line", "feat: x\n\nBREAKING CHANGE: config moved", true, "config moved"},
+		{"hyphenated", "feat: x\n\nBREAKING-CHANGE: config moved", true, "config moved"},
+		{
+			name:     "whole paragraph",
+			message:  "feat: x\n\nBREAKING CHANGE: the config file moved\nto gh-notify.yaml, and the old\nname is ignored.",
+			breaking: true,
+			text:     "the config file moved to gh-notify.yaml, and the old name is ignored.",
+		},
+		{
+			name:     "ends at a blank line",
+			message:  "feat: x\n\nBREAKING CHANGE: config moved\nto a new file.\n\nUnrelated notes.",
+			breaking: true,
+			text:     "config moved to a new file.",
+		},
+		{
+			name:     "ends at the next footer",
+			message:  "feat: x\n\nBREAKING CHANGE: config moved\nto a new file.\nRefs: #12\nSigned-off-by: Mona <mona@example.com>",
+			breaking: true,
+			text:     "config moved to a new file.",
+		},
+		{
+			name:     "ends at an issue footer",
+			message:  "feat: x\n\nBREAKING CHANGE: config moved\nCloses #12",
+			breaking: true,
+			text:     "config moved",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit := parseCommitMessage(tt.message)
+			if commit.Breaking != tt.breaking || commit.BreakingChange != tt.text {
+				t.Errorf("breaking = %v, %q; want %v, %q", commit.Breaking, commit.BreakingChange, tt.breaking, tt.text)
+			}
+		})
+	}
+}
+
+func TestFormatInstantAlertCommits(t *testing.T) {
+	message := "fix: " + strings.Repeat("ü", 80) + "\n\nBody"
+	result := &github.CheckResult{RecentCommits: []github.Commit{
+		{SHA: "abc", Message: message, URL: "https://github.com/acme/widgets/commit/abc", Repository: github.Repo{Name: "widgets", FullName: "acme/widgets"}},
+		{SHA: "0123456789abcdef", Message: "docs: readme", Repository: github.Repo{Name: "widgets", FullName: "acme/widgets"}},
+	}}
+
+	formatted, err := FormatInstantAlert(result, "octocat", "")
+	if err != nil {
+		t.Fatalf("FormatInstantAlert: %v", err)
+	}
+	var value string
+	for _, field := range formatted.Cards[0].Fields {
+		if strings.Contains(field.Name, "Commits") {
+			value = field.Value
+		}
+	}
+	if value == "" {
+		t.Fatal("no commits field")
+	}
+	if !strings.Contains(value, "[`abc`]") || !strings.Contains(value, "[`0123456`]") {
+		t.Errorf("commits field lacks the short SHAs:\n%s", value)
+	}
+	if !strings.Contains(value, "…") || strings.Contains(value, "Body") || strings.ContainsRune(value, '�') {
+		t.Errorf("commit message not cut cleanly at a word boundary:\n%s", value)
+	}
+}
+
+func TestLinkIssues(t *testing.T) {
+	const repoURL = "https://github.com/acme/widgets"
+	tests := []struct {
+		name, text, want string
+	}{
+		{"number", "fix crash (#123)", "fix crash ([#123](https://github.com/acme/widgets/issues/123))"},
+		{"other repository", "port acme/gadgets#4", "port [acme/gadgets#4](https://github.com/acme/gadgets/issues/4)"},
+		{"closing keyword", "fixes #45", "fixes [#45](https://github.com/acme/widgets/issues/45)"},
+		{"several", "#1, #2", "[#1](https://github.com/acme/widgets/issues/1), [#2](https://github.com/acme/widgets/issues/2)"},
+		{"inside a URL", "see https://github.com/acme/widgets/pull/9#issuecomment-1", "see https://github.com/acme/widgets/pull/9#issuecomment-1"},
+		{"URL fragment", "see https://example.com/docs#12", "see https://example.com/docs#12"},
+		{"inside code", "rename `#123` to `x`", "rename `#123` to `x`"},
+		{"not a number", "use #123abc colors", "use #123abc colors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := linkIssues(tt.text, repoURL); got != tt.want {
+				t.Errorf("linkIssues(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	if got := linkIssues("fixes #45", ""); got != "fixes #45" {
+		t.Errorf("without a repository URL got %q, want the text unchanged", got)
+	}
+}
+
+func TestIssueURL(t *testing.T) {
+	tests := []struct {
+		repoURL, repo string
+		want          string
+	}{
+		{"https://github.com/acme/widgets", "", "https://github.com/acme/widgets/issues/7"},
+		{"https://github.com/acme/widgets/", "", "https://github.com/acme/widgets/issues/7"},
+		{"https://github.com/acme/widgets", "other/repo", "https://github.com/other/repo/issues/7"},
+		{"https://ghe.example.com/acme/widgets", "other/repo", "https://ghe.example.com/other/repo/issues/7"},
+	}
+	for _, tt := range tests {
+		if got := issueURL(tt.repoURL, tt.repo, 7); got != tt.want {
+			t.Errorf("issueURL(%q, %q) = %q, want %q", tt.repoURL, tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestParseCommitMessageRefs(t *testing.T) {
+	commit := parseCommitMessage("fix(api): handle nil (#3)\n\nFixes #45\nSee acme/gadgets#4 and #3")
+	if commit.Type != "fix" || commit.Scope != "api" || commit.Description != "handle nil (#3)" {
+		t.Errorf("parsed %q(%q): %q", commit.Type, commit.Scope, commit.Description)
+	}
+	// #3 is already linked in the subject
+	want := []issueRef{{Number: 45, Closes: true}, {Repo: "acme/gadgets", Number: 4}}
+	if len(commit.Refs) != len(want) {
+		t.Fatalf("refs = %+v, want %+v", commit.Refs, want)
+	}
+	for i := range want {
+		if commit.Refs[i] != want[i] {
+			t.Errorf("refs[%d] = %+v, want %+v", i, commit.Refs[i], want[i])
+		}
+	}
+}
+
+func digestCommit(sha, repo, message string) github.Commit {
+	name := repo[strings.Index(repo, "/")+1:]
+	return github.Commit{
+		SHA:        sha,
+		Message:    message,
+		URL:        "https://github.com/" + repo + "/commit/" + sha,
+		Repository: github.Repo{Name: name, FullName: repo},
+	}
+}
+
+func TestGroupCommits(t *testing.T) {
+	commits := []github.Commit{
+		digestCommit("1", "acme/zeta", "fix: zeta bug"),
+		digestCommit("2", "acme/alpha", "chore: tidy"),
+		digestCommit("3", "acme/zeta", "feat: zeta feature"),
+		digestCommit("4", "acme/alpha", "Update README"),
+		digestCommit("5", "acme/alpha", "feat!: drop v1"),
+		digestCommit("6", "acme/alpha", "fix: alpha bug"),
+		digestCommit("7", "acme/beta", "refactor: split\n\nBREAKING CHANGE: new layout"),
+		digestCommit("8", "acme/alpha", "feat: alpha feature"),
+		digestCommit("9", "acme/beta", "wip"),
+	}
+
+	groups := groupCommits(commits)
+	want := []struct {
+		title string
+		shas  []string
+	}{
+		// Breaking changes first, whatever their type, then types in
+		// commitTypeTitles order and other commits last; each sorted by
+		// repository, keeping push order within one
+		{"💥 Breaking Changes", []string{"5", "7"}},
+		{"✨ Features", []string{"8", "3"}},
+		{"🐛 Fixes", []string{"6", "1"}},
+		{"🧹 Chores", []string{"2"}},
+		{otherCommitsTitle, []string{"4", "9"}},
+	}
+	if len(groups) != len(want) {
+		for _, group := range groups {
+			t.Logf("%s: %d commits", group.Title, len(group.Commits))
+		}
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for i, w := range want {
+		group := groups[i]
+		var shas []string
+		for _, commit := range group.Commits {
+			shas = append(shas, commit.SHA)
+		}
+		if group.Title != w.title || strings.Join(shas, ",") != strings.Join(w.shas, ",") {
+			t.Errorf("group %d = %s %v, want %s %v", i, group.Title, shas, w.title, w.shas)
+		}
+		for j, commit := range group.Commits {
+			if parsed := parseCommitMessage(commit.Message); parsed.Description != group.parsed[j].Description {
+				t.Errorf("group %d commit %d: parsed message out of step after sorting", i, j)
+			}
+		}
+	}
+}
+
+func TestCommitGroupFormat(t *testing.T) {
+	groups := groupCommits([]github.Commit{
+		digestCommit("aaaaaaa1", "acme/alpha", "feat(api)!: drop v1\n\nBREAKING CHANGE: clients must use v2"),
+		digestCommit("bbbbbbb2", "acme/beta", "fix: crash (#12)"),
+	})
+
+	breaking := groups[0].format(10)
+	for _, want := range []string{
+		"**alpha**",
+		"💥 **feat(api)!:** drop v1", // Types are shown in the mixed breaking group
+		"↳ clients must use v2",
+	} {
+		if !strings.Contains(breaking, want) {
+			t.Errorf("breaking group does not contain %q:\n%s", want, breaking)
+		}
+	}
+
+	fixes := groups[1].format(10)
+	if strings.Contains(fixes, "**fix:**") {
+		t.Errorf("fix group repeats its type:\n%s", fixes)
+	}
+	if !strings.Contains(fixes, "crash ([#12](https://github.com/acme/beta/issues/12))") {
+		t.Errorf("fix group does not link #12 to its repository:\n%s", fixes)
+	}
+
+	many := groupCommits([]github.Commit{
+		digestCommit("1", "acme/alpha", "fix: a"),
+		digestCommit("2", "acme/alpha", "fix: b"),
+		digestCommit("3", "acme/alpha", "fix: c"),
+	})[0].format(2)
+	if !strings.HasSuffix(many, "... and 1 more commit") {
+		t.Errorf("limited group does not end with the rest counted:\n%s", many)
+	}
+}
+
+func TestFormatCommitNotification(t *testing.T) {
+	message, err := FormatCommitNotification("0123456789abcdef", "feat(ui)!: new theme (#7)\n\nBREAKING CHANGE: old themes are gone",
+		"alice", "acme/widgets", "https://github.com/acme/widgets/commit/0123456789abcdef", "https://github.com/acme/widgets", "https://avatars.example.com/alice")
+	if err != nil {
+		t.Fatalf("FormatCommitNotification: %v", err)
+	}
+	card := message.Cards[0]
+	if card.Color != ColorOrange {
+		t.Errorf("color = %#x, want orange for a breaking change", card.Color)
+	}
+	if want := "**[0123456](https://github.com/acme/widgets/commit/0123456789abcdef)** 💥 **feat(ui)!:** new theme ([#7](https://github.com/acme/widgets/issues/7))"; card.Fields[0].Value != want {
+		t.Errorf("details = %q, want %q", card.Fields[0].Value, want)
+	}
+	if len(card.Fields) != 3 || card.Fields[2].Value != "old themes are gone" {
+		t.Errorf("fields = %+v, want the breaking change explained last", card.Fields)
+	}
+	if card.Author == nil || card.Author.Name != "alice" {
+		t.Errorf("author = %+v, want alice", card.Author)
+	}
+
+	plain, err := FormatCommitNotification("abc", "Update README", "bob", "acme/widgets", "", "", "")
+	if err != nil {
+		t.Fatalf("FormatCommitNotification: %v", err)
+	}
+	card = plain.Cards[0]
+	if card.Color != ColorBlue || len(card.Fields) != 2 || card.Author != nil {
+		t.Errorf("plain commit: color %#x, %d fields, author %+v; want blue, 2, none", card.Color, len(card.Fields), card.Author)
+	}
+	if !strings.HasSuffix(card.Fields[0].Value, " Update README") {
+		t.Errorf("details = %q, want the subject as is", card.Fields[0].Value)
+	}
+}
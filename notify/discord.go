@@ -1,38 +1,39 @@
 package notify
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
-type DiscordMessage struct {
-	Content string  `json:"content,omitempty"`
-	Embeds  []Embed `json:"embeds,omitempty"`
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
 }
 
-type Embed struct {
-	Title       string  `json:"title,omitempty"`
-	Description string  `json:"description,omitempty"`
-	Color       int     `json:"color,omitempty"`
-	Timestamp   string  `json:"timestamp,omitempty"`
-	Footer      *Footer `json:"footer,omitempty"`
-	Author      *Author `json:"author,omitempty"`
-	Fields      []Field `json:"fields,omitempty"`
+type discordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Author      *discordAuthor `json:"author,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
 }
 
-type Footer struct {
+type discordFooter struct {
 	Text string `json:"text,omitempty"`
 }
 
-type Author struct {
+type discordAuthor struct {
 	Name    string `json:"name,omitempty"`
 	IconURL string `json:"icon_url,omitempty"`
 }
 
-type Field struct {
+type discordField struct {
 	Name   string `json:"name"`
 	Value  string `json:"value"`
 	Inline bool   `json:"inline,omitempty"`
@@ -41,71 +42,101 @@ type Field struct {
 type DiscordNotifier struct {
 	webhookURL string
 	httpClient *http.Client
+
+	rateMu sync.Mutex
+	bucket discordBucket
+	sleep  func(time.Duration) // Waits between retries; replaced in tests
 }
 
 func NewDiscordNotifier(webhookURL string) *DiscordNotifier {
 	return &DiscordNotifier{
 		webhookURL: webhookURL,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
+		sleep:      time.Sleep,
 	}
 }
 
-func (d *DiscordNotifier) SendMessage(message *DiscordMessage) error {
-	if d.webhookURL == "" {
-		return fmt.Errorf("discord webhook URL is not configured")
-	}
+// SendMessage sends the message, split into several Discord messages if it
+// exceeds Discord's size limits (see layoutDiscord). Failures Discord reports
+// are returned as a *DiscordError, wrapped in a *PartialError if some of the
+// split messages were delivered.
+func (d *DiscordNotifier) SendMessage(message *Message) error {
+	return d.SendMessageFrom(message, 0)
+}
 
-	jsonData, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+// SendMessageFrom is like SendMessage but skips the parts before first, to
+// resume a partly delivered message. It stops at the first failure so the
+// rest don't arrive out of order.
+func (d *DiscordNotifier) SendMessageFrom(message *Message, first int) error {
+	for i, payload := range layoutDiscord(message) {
+		if i < first {
+			continue
+		}
+		if err := d.post(payload); err != nil {
+			if i > 0 {
+				return &PartialError{Sent: i, Err: err}
+			}
+			return err
+		}
 	}
+	return nil
+}
 
-	resp, err := d.httpClient.Post(d.webhookURL, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-	defer resp.Body.Close()
+// PartialError is returned when a message split into several parts was only
+// partly delivered. The parts are sent in order, so a retry can resume with
+// part Sent (see SendFrom).
+type PartialError struct {
+	Sent int   // Parts delivered, so the index of the first part that wasn't
+	Err  error // Why that part failed
+}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("discord API error: %s", resp.Status)
-	}
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("message only partly delivered (%d parts sent): %v", e.Sent, e.Err)
+}
 
-	return nil
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// SentParts returns how many parts of a split message were delivered before
+// err happened.
+func SentParts(err error) int {
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		return partial.Sent
+	}
+	return 0
 }
 
 func (d *DiscordNotifier) SendSimpleMessage(content string) error {
-	return d.SendMessage(&DiscordMessage{
-		Content: content,
-	})
+	return d.SendMessage(TextMessage(content))
 }
 
 // SendEmbedMessage sends a Discord embed message. If authorName and authorAvatarURL are provided, sets the author/avatar.
 func (d *DiscordNotifier) SendEmbedMessage(title, description string, color int, fields []Field, authorName, authorAvatarURL string) error {
-	embed := Embed{
+	card := Card{
 		Title:       title,
 		Description: description,
 		Color:       color,
-		Timestamp:   time.Now().Format(time.RFC3339),
+		Timestamp:   time.Now(),
 		Fields:      fields,
-		Footer: &Footer{
-			Text: "GitHub Notifier",
-		},
+		Footer:      "GitHub Notifier",
 	}
 
 	// Add author avatar if available
 	if authorName != "" && authorAvatarURL != "" {
-		embed.Author = &Author{
+		card.Author = &Author{
 			Name:    authorName,
 			IconURL: authorAvatarURL,
 		}
 	}
 
-	return d.SendMessage(&DiscordMessage{
-		Embeds: []Embed{embed},
+	return d.SendMessage(&Message{
+		Cards: []Card{card},
 	})
 }
 
-// Color constants for Discord embeds
+// Color constants for notification cards
 const (
 	ColorRed    = 0xFF0000 // For errors/failures
 	ColorYellow = 0xFFFF00 // For warnings
@@ -0,0 +1,240 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Discord limits, counted in characters. See
+// https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	discordMaxContent     = 2000
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+	discordMaxFields      = 25
+	discordMaxFieldName   = 256
+	discordMaxFieldValue  = 1024
+	discordMaxFooter      = 2048
+	discordMaxAuthorName  = 256
+	discordMaxEmbedTotal  = 6000 // Sum over every embed in one message
+	discordMaxEmbeds      = 10
+)
+
+// maxFieldChunks caps how many fields one long list is split into; the rest
+// is summarised as "…and N more".
+const maxFieldChunks = 3
+
+// layoutDiscord renders a Message as one or more Discord webhook payloads that
+// stay within Discord's limits. Long field lists are split across fields,
+// fields across embeds and embeds across messages; lists longer than
+// maxFieldChunks fields end with an "…and N more" line (linked to the field's
+// MoreURL when set). A message with no content and no cards yields no
+// payloads.
+func layoutDiscord(message *Message) []*discordMessage {
+	var messages []*discordMessage
+	current := &discordMessage{Content: truncate(message.Content, discordMaxContent)}
+	total := 0
+
+	for _, card := range message.Cards {
+		for _, embed := range layoutCard(card) {
+			size := embedLength(embed)
+			if len(current.Embeds) == discordMaxEmbeds || total+size > discordMaxEmbedTotal {
+				messages = append(messages, current)
+				current = &discordMessage{}
+				total = 0
+			}
+			current.Embeds = append(current.Embeds, embed)
+			total += size
+		}
+	}
+
+	if current.Content != "" || len(current.Embeds) > 0 {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// layoutCard converts a card into as many embeds as its description and
+// fields need. The first embed carries the title, author and the start of the
+// description; continuation embeds repeat the title with "(cont.)" and the
+// last one carries the footer and timestamp.
+func layoutCard(card Card) []discordEmbed {
+	first := discordEmbed{
+		Title: truncate(card.Title, discordMaxTitle),
+		URL:   card.URL,
+		Color: card.Color,
+	}
+	if card.Author != nil {
+		first.Author = &discordAuthor{
+			Name:    truncate(card.Author.Name, discordMaxAuthorName),
+			IconURL: card.Author.IconURL,
+		}
+	}
+	next := func() discordEmbed {
+		return discordEmbed{
+			Title: truncate(card.Title+" (cont.)", discordMaxTitle),
+			Color: card.Color,
+		}
+	}
+
+	var footer *discordFooter
+	if card.Footer != "" {
+		footer = &discordFooter{Text: truncate(card.Footer, discordMaxFooter)}
+	}
+	// Leave room for the footer in every embed, since any of them may be last
+	budget := discordMaxEmbedTotal
+	if footer != nil {
+		budget -= runeLen(footer.Text)
+	}
+
+	// A description too long to share an embed with the title, author and
+	// footer is split across embeds like a field
+	var descriptions []string
+	if card.Description != "" {
+		room := budget - max(embedLength(first), runeLen(next().Title))
+		for _, chunk := range splitField(Field{Value: card.Description}, min(room, discordMaxDescription)) {
+			descriptions = append(descriptions, chunk.Value)
+		}
+	}
+
+	embeds := []discordEmbed{}
+	embed := first
+	for i, description := range descriptions {
+		if i > 0 {
+			embeds = append(embeds, embed)
+			embed = next()
+		}
+		embed.Description = description
+	}
+	size := embedLength(embed)
+	for _, field := range card.Fields {
+		for _, chunk := range splitField(field, discordMaxFieldValue) {
+			f := discordField{
+				Name:   truncate(chunk.Name, discordMaxFieldName),
+				Value:  chunk.Value,
+				Inline: chunk.Inline,
+			}
+			fieldSize := runeLen(f.Name) + runeLen(f.Value)
+			if len(embed.Fields) == discordMaxFields || size+fieldSize > budget {
+				embeds = append(embeds, embed)
+				embed = next()
+				size = embedLength(embed)
+			}
+			embed.Fields = append(embed.Fields, f)
+			size += fieldSize
+		}
+	}
+
+	embed.Footer = footer
+	if !card.Timestamp.IsZero() {
+		embed.Timestamp = card.Timestamp.Format(time.RFC3339)
+	}
+	return append(embeds, embed)
+}
+
+// splitField splits a field whose value is longer than maxValue characters
+// into several fields at list item boundaries. After maxFieldChunks fields the
+// remaining items are replaced by an "…and N more" line.
+func splitField(field Field, maxValue int) []Field {
+	if field.Value == "" {
+		field.Value = "\u200b" // Discord rejects empty field values
+	}
+	if runeLen(field.Value) <= maxValue {
+		return []Field{field}
+	}
+
+	// Pack items into chunks of at most maxValue characters
+	var chunks [][]string
+	var chunk []string
+	size := 0
+	for _, item := range listItems(field.Value) {
+		item = truncate(item, maxValue)
+		if len(chunk) > 0 && size+1+runeLen(item) > maxValue {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+		if len(chunk) > 0 {
+			size++ // newline separator
+		}
+		chunk = append(chunk, item)
+		size += runeLen(item)
+	}
+	chunks = append(chunks, chunk)
+
+	if len(chunks) > maxFieldChunks {
+		hidden := 0
+		for _, rest := range chunks[maxFieldChunks:] {
+			hidden += len(rest)
+		}
+		chunks = chunks[:maxFieldChunks]
+
+		// Make room for the overflow line in the last chunk shown
+		last := chunks[len(chunks)-1]
+		for len(last) > 0 && runeLen(strings.Join(last, "\n"))+1+runeLen(overflowLine(hidden, field.MoreURL)) > maxValue {
+			last = last[:len(last)-1]
+			hidden++
+		}
+		chunks[len(chunks)-1] = append(last, overflowLine(hidden, field.MoreURL))
+	}
+
+	fields := make([]Field, len(chunks))
+	for i, chunk := range chunks {
+		fields[i] = field
+		fields[i].Value = strings.Join(chunk, "\n")
+		if i > 0 {
+			fields[i].Name = field.Name + " (cont.)"
+		}
+	}
+	return fields
+}
+
+// listItems splits a field value into list items: one per line, with indented
+// lines (such as a commit message under its SHA) kept with the item above.
+func listItems(value string) []string {
+	var items []string
+	for _, line := range strings.Split(value, "\n") {
+		if len(items) > 0 && strings.HasPrefix(line, " ") {
+			items[len(items)-1] += "\n" + line
+			continue
+		}
+		items = append(items, line)
+	}
+	return items
+}
+
+func overflowLine(hidden int, moreURL string) string {
+	if moreURL == "" {
+		return fmt.Sprintf("…and %d more", hidden)
+	}
+	return fmt.Sprintf("[…and %d more](%s)", hidden, moreURL)
+}
+
+// embedLength counts the characters Discord includes in an embed's total.
+func embedLength(embed discordEmbed) int {
+	size := runeLen(embed.Title) + runeLen(embed.Description)
+	if embed.Author != nil {
+		size += runeLen(embed.Author.Name)
+	}
+	if embed.Footer != nil {
+		size += runeLen(embed.Footer.Text)
+	}
+	for _, field := range embed.Fields {
+		size += runeLen(field.Name) + runeLen(field.Value)
+	}
+	return size
+}
+
+// truncate shortens s to at most max characters, ending it with "…" if cut.
+func truncate(s string, max int) string {
+	if runeLen(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,313 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkDiscordLimits fails the test if any payload breaks one of Discord's
+// limits.
+func checkDiscordLimits(t *testing.T, payloads []*discordMessage) {
+	t.Helper()
+	for i, payload := range payloads {
+		if n := runeLen(payload.Content); n > discordMaxContent {
+			t.Errorf("payload %d: content is %d characters, limit %d", i, n, discordMaxContent)
+		}
+		if len(payload.Embeds) > discordMaxEmbeds {
+			t.Errorf("payload %d: %d embeds, limit %d", i, len(payload.Embeds), discordMaxEmbeds)
+		}
+		if payload.Content == "" && len(payload.Embeds) == 0 {
+			t.Errorf("payload %d is empty", i)
+		}
+		total := 0
+		for j, embed := range payload.Embeds {
+			total += embedLength(embed)
+			if n := runeLen(embed.Title); n > discordMaxTitle {
+				t.Errorf("payload %d embed %d: title is %d characters, limit %d", i, j, n, discordMaxTitle)
+			}
+			if n := runeLen(embed.Description); n > discordMaxDescription {
+				t.Errorf("payload %d embed %d: description is %d characters, limit %d", i, j, n, discordMaxDescription)
+			}
+			if len(embed.Fields) > discordMaxFields {
+				t.Errorf("payload %d embed %d: %d fields, limit %d", i, j, len(embed.Fields), discordMaxFields)
+			}
+			for k, field := range embed.Fields {
+				if n := runeLen(field.Name); n > discordMaxFieldName {
+					t.Errorf("payload %d embed %d field %d: name is %d characters, limit %d", i, j, k, n, discordMaxFieldName)
+				}
+				if n := runeLen(field.Value); n > discordMaxFieldValue || n == 0 {
+					t.Errorf("payload %d embed %d field %d: value is %d characters, want 1..%d", i, j, k, n, discordMaxFieldValue)
+				}
+			}
+		}
+		if total > discordMaxEmbedTotal {
+			t.Errorf("payload %d: embeds total %d characters, limit %d", i, total, discordMaxEmbedTotal)
+		}
+	}
+}
+
+// listValue returns n lines of the form "- item i" padded to width runes.
+func listValue(n, width int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		line := fmt.Sprintf("- item %d ", i)
+		lines[i] = line + strings.Repeat("é", width-runeLen(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func manyFields(n int, value string) []Field {
+	fields := make([]Field, n)
+	for i := range fields {
+		fields[i] = Field{Name: fmt.Sprintf("Field %d", i), Value: value}
+	}
+	return fields
+}
+
+func manyCards(n int) []Card {
+	cards := make([]Card, n)
+	for i := range cards {
+		cards[i] = Card{Title: fmt.Sprintf("Card %d", i), Description: "short"}
+	}
+	return cards
+}
+
+func TestLayoutDiscordLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  *Message
+		payloads int   // Expected number of Discord messages
+		embeds   []int // Expected embeds per message, if checked
+	}{
+		{
+			name:     "empty message",
+			message:  &Message{},
+			payloads: 0,
+		},
+		{
+			name:     "content only",
+			message:  TextMessage("hello"),
+			payloads: 1,
+		},
+		{
+			name:     "long content",
+			message:  TextMessage(strings.Repeat("é", 2500)),
+			payloads: 1,
+		},
+		{
+			name:     "256 rune title",
+			message:  &Message{Cards: []Card{{Title: strings.Repeat("ü", 300)}}},
+			payloads: 1,
+			embeds:   []int{1},
+		},
+		{
+			name:     "4096 rune description",
+			message:  &Message{Cards: []Card{{Title: "t", Description: strings.Repeat("ß", 5000)}}},
+			payloads: 1,
+			embeds:   []int{1},
+		},
+		{
+			name: "every text at its limit",
+			message: &Message{Cards: []Card{{
+				Title:       strings.Repeat("t", 300),
+				Description: strings.Repeat("d", 5000),
+				Author:      &Author{Name: strings.Repeat("a", 300)},
+				Footer:      strings.Repeat("f", 3000),
+			}}},
+			payloads: 1,
+			embeds:   []int{1},
+		},
+		{
+			name: "long description list with footer",
+			message: &Message{Cards: []Card{{
+				Title:       "t",
+				Description: listValue(78, 50),
+				Footer:      strings.Repeat("f", 2048),
+			}}},
+			payloads: 2,
+			embeds:   []int{1, 1},
+		},
+		{
+			name:     "256 rune field name",
+			message:  &Message{Cards: []Card{{Title: "t", Fields: []Field{{Name: strings.Repeat("n", 300), Value: "v"}}}}},
+			payloads: 1,
+			embeds:   []int{1},
+		},
+		{
+			name:     "1024 rune field value split at list items",
+			message:  &Message{Cards: []Card{{Title: "t", Fields: []Field{{Name: "List", Value: listValue(30, 50)}}}}},
+			payloads: 1,
+			embeds:   []int{1},
+		},
+		{
+			name:     "empty field value",
+			message:  &Message{Cards: []Card{{Title: "t", Fields: []Field{{Name: "Empty"}}}}},
+			payloads: 1,
+			embeds:   []int{1},
+		},
+		{
+			name:     "25 fields per embed",
+			message:  &Message{Cards: []Card{{Title: "t", Fields: manyFields(30, "v")}}},
+			payloads: 1,
+			embeds:   []int{2},
+		},
+		{
+			name:     "6000 characters per message",
+			message:  &Message{Cards: []Card{{Title: "t", Footer: "footer", Fields: manyFields(12, strings.Repeat("x", 1000))}}},
+			payloads: 3,
+			embeds:   []int{1, 1, 1},
+		},
+		{
+			name:     "10 embeds per message",
+			message:  &Message{Content: "header", Cards: manyCards(12)},
+			payloads: 2,
+			embeds:   []int{10, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads := layoutDiscord(tt.message)
+			checkDiscordLimits(t, payloads)
+			if len(payloads) != tt.payloads {
+				t.Fatalf("got %d payloads, want %d", len(payloads), tt.payloads)
+			}
+			for i, want := range tt.embeds {
+				if got := len(payloads[i].Embeds); got != want {
+					t.Errorf("payload %d: got %d embeds, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLayoutDiscordKeepsEverything(t *testing.T) {
+	// 12 fields of 1000 characters don't fit one message, but all of them
+	// must arrive, in order
+	message := &Message{Cards: []Card{{Title: "t", Fields: manyFields(12, strings.Repeat("x", 1000))}}}
+
+	var names []string
+	for _, payload := range layoutDiscord(message) {
+		for _, embed := range payload.Embeds {
+			for _, field := range embed.Fields {
+				names = append(names, field.Name)
+			}
+		}
+	}
+	if len(names) != 12 {
+		t.Fatalf("got %d fields, want 12", len(names))
+	}
+	for i, name := range names {
+		if want := fmt.Sprintf("Field %d", i); name != want {
+			t.Errorf("field %d is %q, want %q", i, name, want)
+		}
+	}
+}
+
+func TestLayoutDiscordSplitsDescription(t *testing.T) {
+	// Title, author and footer leave too little room for the whole
+	// description, so it continues in a second embed
+	description := listValue(78, 50)
+	message := &Message{Cards: []Card{{
+		Title:       "t",
+		Description: description,
+		Author:      &Author{Name: strings.Repeat("a", 256)},
+		Footer:      strings.Repeat("f", 2048),
+	}}}
+
+	var parts []string
+	var embeds []discordEmbed
+	for _, payload := range layoutDiscord(message) {
+		embeds = append(embeds, payload.Embeds...)
+	}
+	for _, embed := range embeds {
+		parts = append(parts, embed.Description)
+	}
+	if len(embeds) != 2 {
+		t.Fatalf("got %d embeds, want 2", len(embeds))
+	}
+	if got := strings.Join(parts, "\n"); got != description {
+		t.Error("description parts do not join back into the original")
+	}
+	if embeds[0].Author == nil || embeds[1].Author != nil {
+		t.Error("author should be on the first embed only")
+	}
+	if embeds[0].Footer != nil || embeds[1].Footer == nil {
+		t.Error("footer should be on the last embed only")
+	}
+	if embeds[1].Title != "t (cont.)" {
+		t.Errorf("second embed title is %q, want %q", embeds[1].Title, "t (cont.)")
+	}
+}
+
+func TestLayoutDiscordTruncates(t *testing.T) {
+	title := strings.Repeat("ü", 300)
+	payloads := layoutDiscord(&Message{Cards: []Card{{Title: title}}})
+	got := payloads[0].Embeds[0].Title
+	if runeLen(got) != discordMaxTitle || !strings.HasSuffix(got, "…") {
+		t.Errorf("title is %d runes ending %q, want %d ending with …", runeLen(got), got[len(got)-3:], discordMaxTitle)
+	}
+	if !strings.HasPrefix(title, strings.TrimSuffix(got, "…")) {
+		t.Error("truncated title is not a prefix of the original")
+	}
+}
+
+var overflowPattern = regexp.MustCompile(`^\[?…and (\d+) more(\]\((.*)\))?$`)
+
+func TestSplitFieldOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    Field
+		fields   int
+		overflow bool
+	}{
+		{"fits", Field{Name: "List", Value: listValue(10, 50)}, 1, false},
+		{"split without overflow", Field{Name: "List", Value: listValue(50, 50)}, 3, false},
+		{"overflow", Field{Name: "List", Value: listValue(500, 50)}, maxFieldChunks, true},
+		{"overflow with link", Field{Name: "List", Value: listValue(500, 50), MoreURL: "https://github.com/pulls"}, maxFieldChunks, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := len(listItems(tt.field.Value))
+			fields := splitField(tt.field, discordMaxFieldValue)
+			if len(fields) != tt.fields {
+				t.Fatalf("got %d fields, want %d", len(fields), tt.fields)
+			}
+
+			shown, hidden := 0, 0
+			for i, field := range fields {
+				if n := runeLen(field.Value); n > discordMaxFieldValue {
+					t.Errorf("field %d value is %d characters, limit %d", i, n, discordMaxFieldValue)
+				}
+				if i > 0 && field.Name != "List (cont.)" {
+					t.Errorf("field %d is named %q, want %q", i, field.Name, "List (cont.)")
+				}
+				for _, line := range strings.Split(field.Value, "\n") {
+					match := overflowPattern.FindStringSubmatch(line)
+					if match == nil {
+						shown++
+						continue
+					}
+					if i != len(fields)-1 {
+						t.Errorf("overflow line in field %d, want it in the last", i)
+					}
+					hidden, _ = strconv.Atoi(match[1])
+					if match[3] != tt.field.MoreURL {
+						t.Errorf("overflow links to %q, want %q", match[3], tt.field.MoreURL)
+					}
+				}
+			}
+
+			if (hidden > 0) != tt.overflow {
+				t.Errorf("overflow line present = %v, want %v", hidden > 0, tt.overflow)
+			}
+			if shown+hidden != items {
+				t.Errorf("%d items shown + %d hidden, want %d in total", shown, hidden, items)
+			}
+		})
+	}
+}
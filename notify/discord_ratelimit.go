@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	discordMaxRetries = 3                // Retries for throttled (429) and 5xx responses
+	discordMaxWait    = 60 * time.Second // Longest we wait for a bucket reset before giving up
+)
+
+// DiscordError is a webhook request that Discord refused, with the details
+// from its JSON error body. Throttled errors can be retried later; other
+// client errors mean the message itself was rejected.
+type DiscordError struct {
+	StatusCode int
+	Code       int             // Discord's JSON error code, e.g. 50035 for an invalid form body
+	Message    string          // Discord's error message
+	Errors     json.RawMessage // Per-field validation errors, if any
+	RetryAfter time.Duration   // How long Discord asked us to wait when throttled
+	Global     bool            // The global rate limit, rather than the webhook's bucket, was hit
+}
+
+func (e *DiscordError) Error() string {
+	switch {
+	case e.Throttled():
+		return fmt.Sprintf("discord rate limited, retry after %s", e.RetryAfter.Round(time.Millisecond))
+	case len(e.Errors) > 0:
+		return fmt.Sprintf("discord API error %d: %s (code %d): %s", e.StatusCode, e.Message, e.Code, e.Errors)
+	case e.Message != "":
+		return fmt.Sprintf("discord API error %d: %s (code %d)", e.StatusCode, e.Message, e.Code)
+	default:
+		return fmt.Sprintf("discord API error %d", e.StatusCode)
+	}
+}
+
+// Throttled reports whether the message was refused by a rate limit.
+func (e *DiscordError) Throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// Rejected reports whether Discord refused the message's content (a 4xx
+// other than 429), so sending it again won't help.
+func (e *DiscordError) Rejected() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 && !e.Throttled()
+}
+
+// IsDiscordThrottled reports whether err (or any error it wraps) is a
+// throttled DiscordError.
+func IsDiscordThrottled(err error) bool {
+	var discordErr *DiscordError
+	return errors.As(err, &discordErr) && discordErr.Throttled()
+}
+
+// discordBucket is the rate limit bucket of one webhook, from Discord's
+// X-RateLimit-* response headers.
+type discordBucket struct {
+	remaining int
+	resetAt   time.Time
+}
+
+// post sends payload to the webhook, waiting for the webhook's bucket to reset
+// when it is exhausted and retrying 429s (after the advised delay), 5xx
+// responses and network errors.
+func (d *DiscordNotifier) post(payload interface{}) error {
+	if d.webhookURL == "" {
+		return fmt.Errorf("discord webhook URL is not configured")
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := d.waitForBucket(); err != nil {
+			return err
+		}
+
+		resp, err := d.httpClient.Post(d.webhookURL, "application/json", bytes.NewReader(jsonData))
+		if err != nil {
+			if attempt >= discordMaxRetries {
+				return fmt.Errorf("failed to send message: %w", err)
+			}
+			slog.Warn("discord request failed, retrying", "error", err)
+			d.sleep(time.Second << attempt)
+			continue
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		d.updateBucket(resp.Header)
+
+		if resp.StatusCode < 400 {
+			return nil
+		}
+
+		discordErr := parseDiscordError(resp, body)
+		switch {
+		case discordErr.Throttled():
+			if attempt >= discordMaxRetries || discordErr.RetryAfter > discordMaxWait {
+				return discordErr
+			}
+			slog.Warn("discord rate limited, retrying", "retry_after", discordErr.RetryAfter, "global", discordErr.Global)
+			d.sleep(discordErr.RetryAfter)
+
+		case resp.StatusCode >= 500 && attempt < discordMaxRetries:
+			slog.Warn("discord server error, retrying", "status", resp.StatusCode)
+			d.sleep(time.Second << attempt)
+
+		default:
+			return discordErr
+		}
+	}
+}
+
+// waitForBucket sleeps until the webhook's bucket resets if the last response
+// said no requests were left.
+func (d *DiscordNotifier) waitForBucket() error {
+	d.rateMu.Lock()
+	bucket := d.bucket
+	d.rateMu.Unlock()
+
+	if bucket.resetAt.IsZero() || bucket.remaining > 0 {
+		return nil
+	}
+	wait := time.Until(bucket.resetAt)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > discordMaxWait {
+		return &DiscordError{StatusCode: http.StatusTooManyRequests, Message: "webhook bucket exhausted", RetryAfter: wait}
+	}
+	d.sleep(wait)
+	return nil
+}
+
+// updateBucket records the webhook's bucket from the X-RateLimit-* headers.
+func (d *DiscordNotifier) updateBucket(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	resetAfter, err := strconv.ParseFloat(header.Get("X-RateLimit-Reset-After"), 64)
+	if err != nil {
+		return
+	}
+
+	d.rateMu.Lock()
+	d.bucket = discordBucket{
+		remaining: remaining,
+		resetAt:   time.Now().Add(secondsToDuration(resetAfter)),
+	}
+	d.rateMu.Unlock()
+}
+
+// parseDiscordError builds a DiscordError from an error response. The delay
+// for a 429 comes from the body's retry_after, then the Retry-After header.
+func parseDiscordError(resp *http.Response, body []byte) *DiscordError {
+	discordErr := &DiscordError{StatusCode: resp.StatusCode}
+
+	var errorBody struct {
+		Message    string          `json:"message"`
+		Code       int             `json:"code"`
+		Errors     json.RawMessage `json:"errors"`
+		RetryAfter float64         `json:"retry_after"`
+		Global     bool            `json:"global"`
+	}
+	if json.Unmarshal(body, &errorBody) == nil {
+		discordErr.Message = errorBody.Message
+		discordErr.Code = errorBody.Code
+		discordErr.Errors = errorBody.Errors
+		discordErr.Global = errorBody.Global
+		discordErr.RetryAfter = secondsToDuration(errorBody.RetryAfter)
+	}
+
+	if discordErr.Throttled() && discordErr.RetryAfter == 0 {
+		if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+			discordErr.RetryAfter = secondsToDuration(seconds)
+		} else {
+			discordErr.RetryAfter = time.Second
+		}
+	}
+
+	return discordErr
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
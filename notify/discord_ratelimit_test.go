@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// discordResponse is one canned webhook response.
+type discordResponse struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// newDiscordServer answers each request with the next response, repeating
+// the last one, and counts the requests.
+func newDiscordServer(t *testing.T, responses ...discordResponse) (*httptest.Server, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		response := responses[min(requests, len(responses)-1)]
+		requests++
+		mu.Unlock()
+		for name, value := range response.header {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(response.status)
+		w.Write([]byte(response.body))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// newTestDiscord returns a notifier for url that records its waits instead
+// of sleeping.
+func newTestDiscord(url string) (*DiscordNotifier, *[]time.Duration) {
+	var waits []time.Duration
+	d := NewDiscordNotifier(url)
+	d.sleep = func(wait time.Duration) { waits = append(waits, wait) }
+	return d, &waits
+}
+
+var (
+	discordOK        = discordResponse{status: http.StatusNoContent}
+	discordThrottled = discordResponse{status: http.StatusTooManyRequests,
+		body: `{"message": "You are being rate limited.", "retry_after": 0.5, "global": false}`}
+	discordUnavailable = discordResponse{status: http.StatusBadGateway, body: "bad gateway"}
+)
+
+func TestDiscordPostRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []discordResponse
+		wantRequests int
+		wantWaits    []time.Duration
+	}{
+		{"429 with retry_after", []discordResponse{discordThrottled, discordOK}, 2, []time.Duration{500 * time.Millisecond}},
+		{"429 with Retry-After header",
+			[]discordResponse{{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "2"}}, discordOK},
+			2, []time.Duration{2 * time.Second}},
+		{"5xx", []discordResponse{discordUnavailable, discordUnavailable, discordOK}, 3, []time.Duration{time.Second, 2 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newDiscordServer(t, tt.responses...)
+			d, waits := newTestDiscord(server.URL)
+
+			if err := d.post(map[string]string{"content": "hi"}); err != nil {
+				t.Fatalf("post: %v", err)
+			}
+			if got := requests(); got != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", got, tt.wantRequests)
+			}
+			if len(*waits) != len(tt.wantWaits) {
+				t.Fatalf("waited %v, want %v", *waits, tt.wantWaits)
+			}
+			for i, wait := range tt.wantWaits {
+				if (*waits)[i] != wait {
+					t.Errorf("wait %d = %s, want %s", i, (*waits)[i], wait)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscordPostErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      discordResponse
+		wantRequests  int
+		wantStatus    int
+		wantThrottled bool
+		wantRejected  bool
+	}{
+		{"still throttled", discordThrottled, discordMaxRetries + 1, http.StatusTooManyRequests, true, false},
+		{"retry_after too long", discordResponse{status: http.StatusTooManyRequests, body: `{"retry_after": 3600, "global": true}`},
+			1, http.StatusTooManyRequests, true, false},
+		{"still failing", discordUnavailable, discordMaxRetries + 1, http.StatusBadGateway, false, false},
+		{"invalid form body", discordResponse{status: http.StatusBadRequest,
+			body: `{"message": "Invalid Form Body", "code": 50035, "errors": {"embeds": {}}}`},
+			1, http.StatusBadRequest, false, true},
+		{"unknown webhook", discordResponse{status: http.StatusNotFound, body: `{"message": "Unknown Webhook", "code": 10015}`},
+			1, http.StatusNotFound, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newDiscordServer(t, tt.response)
+			d, _ := newTestDiscord(server.URL)
+
+			err := d.post(map[string]string{"content": "hi"})
+			var discordErr *DiscordError
+			if !errors.As(err, &discordErr) {
+				t.Fatalf("post error = %v, want a *DiscordError", err)
+			}
+			if got := requests(); got != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", got, tt.wantRequests)
+			}
+			if discordErr.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", discordErr.StatusCode, tt.wantStatus)
+			}
+			if discordErr.Throttled() != tt.wantThrottled || IsDiscordThrottled(err) != tt.wantThrottled {
+				t.Errorf("throttled = %v, want %v", discordErr.Throttled(), tt.wantThrottled)
+			}
+			if discordErr.Rejected() != tt.wantRejected {
+				t.Errorf("rejected = %v, want %v", discordErr.Rejected(), tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestParseDiscordError(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}}
+	discordErr := parseDiscordError(resp, []byte(`{"message": "Invalid Form Body", "code": 50035, "errors": {"content": {}}}`))
+	if discordErr.Code != 50035 || discordErr.Message != "Invalid Form Body" || string(discordErr.Errors) != `{"content": {}}` {
+		t.Errorf("parsed %+v", discordErr)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	discordErr = parseDiscordError(resp, []byte("not json"))
+	if discordErr.RetryAfter != time.Second {
+		t.Errorf("retry after = %s without any hint, want 1s", discordErr.RetryAfter)
+	}
+
+	resp.Header.Set("Retry-After", "1.5")
+	discordErr = parseDiscordError(resp, []byte(`{"retry_after": 0.25, "global": true}`))
+	if discordErr.RetryAfter != 250*time.Millisecond || !discordErr.Global {
+		t.Errorf("retry after = %s, global %v; want the body's 250ms, global", discordErr.RetryAfter, discordErr.Global)
+	}
+}
+
+func TestDiscordPostWaitsForBucketReset(t *testing.T) {
+	exhausted := discordResponse{status: http.StatusNoContent,
+		header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset-After": "30"}}
+	server, requests := newDiscordServer(t, exhausted)
+	d, waits := newTestDiscord(server.URL)
+
+	if err := d.post(map[string]string{"content": "one"}); err != nil {
+		t.Fatalf("first post: %v", err)
+	}
+	if err := d.post(map[string]string{"content": "two"}); err != nil {
+		t.Fatalf("second post: %v", err)
+	}
+	if requests() != 2 {
+		t.Errorf("sent %d requests, want 2", requests())
+	}
+	if len(*waits) != 1 || (*waits)[0] <= 29*time.Second || (*waits)[0] > 30*time.Second {
+		t.Errorf("waited %v before the second post, want the 30s bucket reset", *waits)
+	}
+
+	// A reset further away than discordMaxWait fails without a request
+	d.updateBucket(http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset-After": {"600"}})
+	err := d.post(map[string]string{"content": "three"})
+	if !IsDiscordThrottled(err) {
+		t.Errorf("post error = %v, want throttled", err)
+	}
+	if requests() != 2 {
+		t.Errorf("sent a request while the bucket was exhausted")
+	}
+}
@@ -0,0 +1,347 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wilfierd/gh-notify/github"
+)
+
+// FormatPushNotification formats a push with every commit in it. title
+// replaces the default title when set, and avatarURL is shown next to the
+// pusher when set.
+func FormatPushNotification(event *github.PushEvent, title, avatarURL string) (*Message, error) {
+	kind := "branch"
+	if event.IsTag() {
+		kind = "tag"
+	}
+	ref := event.RefName()
+	pusher := event.Sender.Login
+	if pusher == "" {
+		pusher = event.Pusher.Name
+	}
+
+	var description string
+	color := ColorBlue
+	switch {
+	case event.Deleted:
+		description = fmt.Sprintf("**%s** deleted %s `%s`", pusher, kind, ref)
+		color = ColorRed
+	case event.Created && len(event.Commits) == 0:
+		description = fmt.Sprintf("**%s** created %s `%s`", pusher, kind, ref)
+	case event.Created:
+		description = fmt.Sprintf("**%s** created %s `%s` with %s", pusher, kind, ref, pluralize(len(event.Commits), "commit"))
+	case event.Forced:
+		description = fmt.Sprintf("⚠️ **%s** force-pushed %s%s", pusher, pluralize(len(event.Commits), "commit"), refSuffix(ref))
+		color = ColorOrange
+	default:
+		description = fmt.Sprintf("**%s** pushed %s%s", pusher, pluralize(len(event.Commits), "commit"), refSuffix(ref))
+	}
+
+	if title == "" {
+		title = "📝 New Commit Pushed"
+		if len(event.Commits) > 1 {
+			title = fmt.Sprintf("📝 %d New Commits Pushed", len(event.Commits))
+		}
+	}
+
+	var fields []Field
+	if len(event.Commits) > 0 {
+		var commitList []string
+		breaking := false
+		for _, commit := range event.Commits {
+			sha := fmt.Sprintf("`%s`", shortSHA(commit.ID))
+			if commit.URL != "" {
+				sha = fmt.Sprintf("[%s](%s)", sha, commit.URL)
+			}
+			parsed := parseCommitMessage(commit.Message)
+			breaking = breaking || parsed.Breaking
+			commitList = append(commitList, fmt.Sprintf("• %s %s — %s", sha, parsed.summary(event.Repository.HTMLURL, 0, true), commit.Author.Login()))
+		}
+		if breaking && !event.Deleted {
+			description += "\n💥 Includes breaking changes"
+			color = ColorOrange
+		}
+		fields = append(fields, Field{
+			Name:    "🚀 Commits",
+			Value:   strings.Join(commitList, "\n"),
+			MoreURL: event.Compare,
+			Inline:  false,
+		})
+	}
+	fields = append(fields, Field{
+		Name:   " Repository",
+		Value:  fmt.Sprintf("[%s](%s)", event.Repository.FullName, event.Repository.HTMLURL),
+		Inline: true,
+	})
+	if ref != "" {
+		fields = append(fields, Field{
+			Name:   "🌿 " + strings.ToUpper(kind[:1]) + kind[1:],
+			Value:  fmt.Sprintf("`%s`", ref),
+			Inline: true,
+		})
+	}
+	if event.Compare != "" && !event.Deleted {
+		fields = append(fields, Field{
+			Name:   "🔍 Compare",
+			Value:  fmt.Sprintf("[View changes](%s)", event.Compare),
+			Inline: true,
+		})
+	}
+
+	card := Card{
+		Title:       title,
+		URL:         event.Compare,
+		Description: description,
+		Color:       color,
+		Timestamp:   time.Now(),
+		Fields:      fields,
+		Footer:      "GitHub Notifier • Commit Tracker",
+	}
+	card.Author = eventAuthor(pusher, avatarURL)
+
+	return &Message{
+		Cards: []Card{card},
+	}, nil
+}
+
+// eventBodyLength caps the PR descriptions and release notes shown, leaving
+// room for the rest of the card.
+const eventBodyLength = 1500
+
+// FormatPullRequestNotification formats an opened or merged pull request.
+func FormatPullRequestNotification(event *github.PullRequestEvent, title, avatarURL string) (*Message, error) {
+	pr := event.PullRequest
+	sender := event.Sender.Login
+
+	description := fmt.Sprintf("**%s** opened [#%d %s](%s)", sender, pr.Number, pr.Title, pr.HTMLURL)
+	color := ColorBlue
+	defaultTitle := "🔀 Pull Request Opened"
+	if pr.Merged {
+		merger := sender
+		if pr.MergedBy != nil {
+			merger = pr.MergedBy.Login
+		}
+		description = fmt.Sprintf("**%s** merged [#%d %s](%s)", merger, pr.Number, pr.Title, pr.HTMLURL)
+		color = ColorPurple
+		defaultTitle = "✅ Pull Request Merged"
+	}
+	if pr.Draft {
+		description += " as a draft"
+	}
+	if body := strings.TrimSpace(pr.Body); body != "" && !pr.Merged {
+		description += "\n\n" + truncate(body, eventBodyLength)
+	}
+	if title == "" {
+		title = defaultTitle
+	}
+
+	fields := []Field{{
+		Name:   " Repository",
+		Value:  fmt.Sprintf("[%s](%s)", event.Repository.FullName, event.Repository.HTMLURL),
+		Inline: true,
+	}}
+	if pr.Head != nil && pr.Base.Ref != "" {
+		fields = append(fields, Field{
+			Name:   "🌿 Branches",
+			Value:  fmt.Sprintf("`%s` → `%s`", pr.Head.Ref, pr.Base.Ref),
+			Inline: true,
+		})
+	}
+	if pr.ChangedFiles > 0 {
+		fields = append(fields, Field{
+			Name: "📊 Changes",
+			Value: fmt.Sprintf("%s, %s, +%d −%d",
+				pluralize(pr.Commits, "commit"), pluralize(pr.ChangedFiles, "file"), pr.Additions, pr.Deletions),
+			Inline: true,
+		})
+	}
+
+	card := Card{
+		Title:       title,
+		URL:         pr.HTMLURL,
+		Description: description,
+		Color:       color,
+		Timestamp:   time.Now(),
+		Fields:      fields,
+		Footer:      "GitHub Notifier • Pull Request Tracker",
+		Author:      eventAuthor(sender, avatarURL),
+	}
+	return &Message{Cards: []Card{card}}, nil
+}
+
+// FormatReleaseNotification formats a published release with its notes.
+func FormatReleaseNotification(event *github.ReleaseEvent, title, avatarURL string) (*Message, error) {
+	release := event.Release
+	name := release.Name
+	if name == "" {
+		name = release.TagName
+	}
+	publisher := release.Author.Login
+	if publisher == "" {
+		publisher = event.Sender.Login
+	}
+
+	description := fmt.Sprintf("**%s** published [%s](%s)", publisher, name, release.HTMLURL)
+	if release.Prerelease {
+		description += " (pre-release)"
+	}
+	if notes := strings.TrimSpace(release.Body); notes != "" {
+		description += "\n\n" + truncate(notes, eventBodyLength)
+		if runeLen(notes) > eventBodyLength {
+			description += fmt.Sprintf("\n[Read the full release notes](%s)", release.HTMLURL)
+		}
+	}
+	if title == "" {
+		title = "🚀 New Release: " + name
+	}
+
+	card := Card{
+		Title:       title,
+		URL:         release.HTMLURL,
+		Description: description,
+		Color:       ColorGreen,
+		Timestamp:   time.Now(),
+		Fields: []Field{
+			{
+				Name:   " Repository",
+				Value:  fmt.Sprintf("[%s](%s)", event.Repository.FullName, event.Repository.HTMLURL),
+				Inline: true,
+			},
+			{
+				Name:   "🏷️ Tag",
+				Value:  fmt.Sprintf("`%s`", release.TagName),
+				Inline: true,
+			},
+		},
+		Footer: "GitHub Notifier • Release Tracker",
+		Author: eventAuthor(publisher, avatarURL),
+	}
+	return &Message{Cards: []Card{card}}, nil
+}
+
+// FormatTagNotification formats a new tag.
+func FormatTagNotification(event *github.CreateEvent, title, avatarURL string) (*Message, error) {
+	tagURL := ""
+	if event.Repository.HTMLURL != "" {
+		tagURL = fmt.Sprintf("%s/tree/%s", event.Repository.HTMLURL, event.Ref)
+	}
+	tag := fmt.Sprintf("`%s`", event.Ref)
+	if tagURL != "" {
+		tag = fmt.Sprintf("[%s](%s)", tag, tagURL)
+	}
+	if title == "" {
+		title = "🏷️ New Tag: " + event.Ref
+	}
+
+	card := Card{
+		Title:       title,
+		URL:         tagURL,
+		Description: fmt.Sprintf("**%s** created tag %s", event.Sender.Login, tag),
+		Color:       ColorBlue,
+		Timestamp:   time.Now(),
+		Fields: []Field{{
+			Name:   " Repository",
+			Value:  fmt.Sprintf("[%s](%s)", event.Repository.FullName, event.Repository.HTMLURL),
+			Inline: true,
+		}},
+		Footer: "GitHub Notifier • Release Tracker",
+		Author: eventAuthor(event.Sender.Login, avatarURL),
+	}
+	return &Message{Cards: []Card{card}}, nil
+}
+
+// FormatWorkflowRunNotification formats a workflow run that didn't succeed.
+func FormatWorkflowRunNotification(event *github.WorkflowRunEvent, title, avatarURL string) (*Message, error) {
+	run := event.WorkflowRun
+	actor := run.Actor.Login
+	if actor == "" {
+		actor = event.Sender.Login
+	}
+	conclusion := strings.ReplaceAll(run.Conclusion, "_", " ")
+
+	description := fmt.Sprintf("[%s #%d](%s) %s", run.Name, run.RunNumber, run.HTMLURL, conclusion)
+	if run.DisplayTitle != "" {
+		description += fmt.Sprintf(" on **%s**", run.DisplayTitle)
+	}
+	if title == "" {
+		title = "❌ Workflow Failed: " + run.Name
+	}
+
+	fields := []Field{{
+		Name:   " Repository",
+		Value:  fmt.Sprintf("[%s](%s)", event.Repository.FullName, event.Repository.HTMLURL),
+		Inline: true,
+	}}
+	if run.HeadBranch != "" {
+		fields = append(fields, Field{
+			Name:   "🌿 Branch",
+			Value:  fmt.Sprintf("`%s`", run.HeadBranch),
+			Inline: true,
+		})
+	}
+	if run.HeadSHA != "" {
+		commit := fmt.Sprintf("`%s`", shortSHA(run.HeadSHA))
+		if event.Repository.HTMLURL != "" {
+			commit = fmt.Sprintf("[%s](%s/commit/%s)", commit, event.Repository.HTMLURL, run.HeadSHA)
+		}
+		fields = append(fields, Field{
+			Name:   "📌 Commit",
+			Value:  commit,
+			Inline: true,
+		})
+	}
+	if run.Event != "" {
+		fields = append(fields, Field{
+			Name:   "⚡ Triggered by",
+			Value:  fmt.Sprintf("`%s` from %s", run.Event, actor),
+			Inline: true,
+		})
+	}
+
+	card := Card{
+		Title:       title,
+		URL:         run.HTMLURL,
+		Description: description,
+		Color:       ColorRed,
+		Timestamp:   time.Now(),
+		Fields:      fields,
+		Footer:      "GitHub Notifier • Workflow Tracker",
+		Author:      eventAuthor(actor, avatarURL),
+	}
+	return &Message{Cards: []Card{card}}, nil
+}
+
+// eventAuthor returns the card author for login, or nil without an avatar.
+func eventAuthor(login, avatarURL string) *Author {
+	if avatarURL == "" {
+		return nil
+	}
+	return &Author{
+		Name:    login,
+		IconURL: avatarURL,
+	}
+}
+
+// refSuffix returns " to `ref`", or "" if the ref isn't known.
+func refSuffix(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	return fmt.Sprintf(" to `%s`", ref)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
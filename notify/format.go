@@ -8,7 +8,7 @@ import (
 	"github.com/wilfierd/gh-notify/github"
 )
 
-func FormatInstantAlert(result *github.CheckResult, username string, avatarURL string) (*DiscordMessage, error) {
+func FormatInstantAlert(result *github.CheckResult, username string, avatarURL string) (*Message, error) {
 	if !result.HasAlerts() {
 		return nil, nil
 	}
@@ -26,22 +26,25 @@ func FormatInstantAlert(result *github.CheckResult, username string, avatarURL s
 	// Calculate actual count of items being shown (not using GetAlertCount as it may include old alerts)
 	alertCount := len(result.PRsNeedingReview) +
 		len(result.StaleOwnPRs) +
+		len(result.Reviews) +
 		len(result.AssignedIssues) +
 		len(result.UnreadNotifications) +
 		len(result.FailedWorkflows) +
 		nonExpiredInvitationsCount +
+		len(result.Memberships) +
 		len(result.RecentCommits)
 
 	// PRs needing review
 	if len(result.PRsNeedingReview) > 0 {
 		var prList []string
 		for _, pr := range result.PRsNeedingReview {
-			prList = append(prList, fmt.Sprintf("• [#%d %s](%s)", pr.Number, pr.Title, pr.HTMLURL))
+			prList = append(prList, fmt.Sprintf("• [#%d %s](%s)%s", pr.Number, pr.Title, pr.HTMLURL, changeSuffix(result, pr.HTMLURL)))
 		}
 		fields = append(fields, Field{
-			Name:   "🔍 PRs waiting for your review",
-			Value:  strings.Join(prList, "\n"),
-			Inline: false,
+			Name:    "🔍 PRs waiting for your review",
+			Value:   strings.Join(prList, "\n"),
+			MoreURL: "https://github.com/pulls/review-requested",
+			Inline:  false,
 		})
 	}
 
@@ -50,13 +53,33 @@ func FormatInstantAlert(result *github.CheckResult, username string, avatarURL s
 		var prList []string
 		for _, pr := range result.StaleOwnPRs {
 			daysSince := int(time.Since(pr.UpdatedAt).Hours() / 24)
-			prList = append(prList, fmt.Sprintf("• [#%d %s](%s) (%d days old)",
-				pr.Number, pr.Title, pr.HTMLURL, daysSince))
+			prList = append(prList, fmt.Sprintf("• [#%d %s](%s) (%d days old)%s",
+				pr.Number, pr.Title, pr.HTMLURL, daysSince, changeSuffix(result, pr.HTMLURL)))
 		}
 		fields = append(fields, Field{
-			Name:   "⏰ Your PRs need attention",
-			Value:  strings.Join(prList, "\n"),
-			Inline: false,
+			Name:    "⏰ Your PRs need attention",
+			Value:   strings.Join(prList, "\n"),
+			MoreURL: "https://github.com/pulls",
+			Inline:  false,
+		})
+	}
+
+	// Reviews on own PRs, from webhooks
+	if len(result.Reviews) > 0 {
+		var reviewList []string
+		for _, reviewed := range result.Reviews {
+			verdict := "✅ approved"
+			if reviewed.Review.State == "changes_requested" {
+				verdict = "✏️ changes requested"
+			}
+			reviewList = append(reviewList, fmt.Sprintf("• [#%d %s](%s) — %s by %s",
+				reviewed.Number, reviewed.Title, reviewed.HTMLURL, verdict, reviewed.Review.User.Login))
+		}
+		fields = append(fields, Field{
+			Name:    "📝 Reviews on your PRs",
+			Value:   strings.Join(reviewList, "\n"),
+			MoreURL: "https://github.com/pulls",
+			Inline:  false,
 		})
 	}
 
@@ -64,13 +87,14 @@ func FormatInstantAlert(result *github.CheckResult, username string, avatarURL s
 	if len(result.AssignedIssues) > 0 {
 		var issueList []string
 		for _, issue := range result.AssignedIssues {
-			issueList = append(issueList, fmt.Sprintf("• [#%d %s](%s)",
-				issue.Number, issue.Title, issue.HTMLURL))
+			issueList = append(issueList, fmt.Sprintf("• [#%d %s](%s)%s",
+				issue.Number, issue.Title, issue.HTMLURL, changeSuffix(result, issue.HTMLURL)))
 		}
 		fields = append(fields, Field{
-			Name:   "📋 Issues assigned to you",
-			Value:  strings.Join(issueList, "\n"),
-			Inline: false,
+			Name:    "📋 Issues assigned to you",
+			Value:   strings.Join(issueList, "\n"),
+			MoreURL: "https://github.com/issues/assigned",
+			Inline:  false,
 		})
 	}
 
@@ -115,6 +139,20 @@ func FormatInstantAlert(result *github.CheckResult, username string, avatarURL s
 		}
 	}
 
+	// Repositories the user was added to, from webhooks
+	if len(result.Memberships) > 0 {
+		var memberList []string
+		for _, membership := range result.Memberships {
+			memberList = append(memberList, fmt.Sprintf("• [%s](%s) by %s",
+				membership.Repository.FullName, membership.Repository.HTMLURL, membership.AddedBy.Login))
+		}
+		fields = append(fields, Field{
+			Name:   "🤝 Added to repositories",
+			Value:  strings.Join(memberList, "\n"),
+			Inline: false,
+		})
+	}
+
 	// Recent commits (only show if tracking is enabled)
 	if len(result.RecentCommits) > 0 {
 		var commitList []string
@@ -123,16 +161,10 @@ func FormatInstantAlert(result *github.CheckResult, username string, avatarURL s
 				commitList = append(commitList, fmt.Sprintf("... and %d more commits", len(result.RecentCommits)-10))
 				break
 			}
-			// Truncate commit message if too long
-			message := commit.Message
-			if len(message) > 50 {
-				message = message[:50] + "..."
-			}
-			// Remove newlines from commit message
-			message = strings.ReplaceAll(message, "\n", " ")
-			
-			commitList = append(commitList, fmt.Sprintf("• [%s](%s) in **%s**\n  `%s`",
-				commit.SHA[:7], commit.URL, commit.Repository.Name, message))
+			// Subject line only, cut at a word boundary
+			summary := parseCommitMessage(commit.Message).summary(commitRepoURL(commit), 50, true)
+			commitList = append(commitList, fmt.Sprintf("• [`%s`](%s) in **%s**\n  %s",
+				shortSHA(commit.SHA), commit.URL, commit.Repository.Name, summary))
 		}
 		fields = append(fields, Field{
 			Name:   "💻 Recent Commits",
@@ -146,38 +178,49 @@ func FormatInstantAlert(result *github.CheckResult, username string, avatarURL s
 		return nil, nil
 	}
 
-	return &DiscordMessage{
-		Embeds: []Embed{
+	if len(result.SkippedSections) > 0 {
+		fields = append(fields, skippedSectionsField(result.SkippedSections))
+	}
+
+	return &Message{
+		Cards: []Card{
 			{
 				Title:       fmt.Sprintf("🔔 GitHub Alerts (%d items)", alertCount),
 				Description: "Here are some items that need your attention:",
 				Color:       ColorOrange,
-				Timestamp:   time.Now().Format(time.RFC3339),
+				Timestamp:   time.Now(),
 				Fields:      fields,
 				Author: &Author{
 					Name:    username,
 					IconURL: avatarURL,
 				},
-				Footer: &Footer{
-					Text: "GitHub Notifier",
-				},
+				Footer: "GitHub Notifier",
 			},
 		},
 	}, nil
 }
 
-func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL string) (*DiscordMessage, error) {
+func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL string) (*Message, error) {
 	var fields []Field
 	dateStr := digest.Date.Format("02-01-2006")
 
 	var title, description string
 	var color int
 
+	footer := "GitHub Notifier • Daily Report"
+
 	if digest.IsEvening {
 		// Evening Digest - Show accomplishments
 		title = fmt.Sprintf("🌆 Evening Summary – %s", dateStr)
 		description = fmt.Sprintf("Here's what you accomplished today, %s!", username)
 		color = ColorGreen // Green for accomplishments
+		period := "Today"
+		if digest.IsWeekly {
+			title = fmt.Sprintf("📅 Weekly Summary – %s to %s", digest.Since.Format("02-01-2006"), dateStr)
+			description = fmt.Sprintf("Here's what you accomplished this week, %s!", username)
+			footer = "GitHub Notifier • Weekly Report"
+			period = "This Week"
+		}
 
 		hasActivity := false
 
@@ -188,9 +231,10 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 				prList = append(prList, fmt.Sprintf("• [#%d %s](%s)", pr.Number, pr.Title, pr.HTMLURL))
 			}
 			fields = append(fields, Field{
-				Name:   "📤 Pull Requests Opened",
-				Value:  strings.Join(prList, "\n"),
-				Inline: false,
+				Name:    "📤 Pull Requests Opened",
+				Value:   strings.Join(prList, "\n"),
+				MoreURL: "https://github.com/pulls",
+				Inline:  false,
 			})
 			hasActivity = true
 		}
@@ -202,9 +246,10 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 				prList = append(prList, fmt.Sprintf("• [#%d %s](%s)", pr.Number, pr.Title, pr.HTMLURL))
 			}
 			fields = append(fields, Field{
-				Name:   "✅ Pull Requests Merged",
-				Value:  strings.Join(prList, "\n"),
-				Inline: false,
+				Name:    "✅ Pull Requests Merged",
+				Value:   strings.Join(prList, "\n"),
+				MoreURL: "https://github.com/pulls",
+				Inline:  false,
 			})
 			hasActivity = true
 		}
@@ -216,9 +261,10 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 				issueList = append(issueList, fmt.Sprintf("• [#%d %s](%s)", issue.Number, issue.Title, issue.HTMLURL))
 			}
 			fields = append(fields, Field{
-				Name:   "🐛 Issues Opened",
-				Value:  strings.Join(issueList, "\n"),
-				Inline: false,
+				Name:    "🐛 Issues Opened",
+				Value:   strings.Join(issueList, "\n"),
+				MoreURL: "https://github.com/issues",
+				Inline:  false,
 			})
 			hasActivity = true
 		}
@@ -230,40 +276,25 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 				issueList = append(issueList, fmt.Sprintf("• [#%d %s](%s)", issue.Number, issue.Title, issue.HTMLURL))
 			}
 			fields = append(fields, Field{
-				Name:   "✅ Issues Resolved",
-				Value:  strings.Join(issueList, "\n"),
-				Inline: false,
+				Name:    "✅ Issues Resolved",
+				Value:   strings.Join(issueList, "\n"),
+				MoreURL: "https://github.com/issues",
+				Inline:  false,
 			})
 			hasActivity = true
 		}
 
-		// Commits today
+		// Commits today, grouped by type
 		if len(digest.CommitsToday) > 0 {
-			var commitList []string
-			for _, commit := range digest.CommitsToday {
-				// Truncate commit message if too long
-				message := commit.Message
-				if len(message) > 60 {
-					message = message[:60] + "..."
+			groups := groupCommits(digest.CommitsToday)
+			for _, group := range groups {
+				name := fmt.Sprintf("%s (%d)", group.Title, len(group.Commits))
+				if len(groups) == 1 && group.Title == otherCommitsTitle {
+					name = fmt.Sprintf("💻 Commits %s (%d)", period, len(group.Commits))
 				}
-				// Remove newlines from commit message
-				message = strings.ReplaceAll(message, "\n", " ")
-
-				commitList = append(commitList, fmt.Sprintf("• [%s](%s) in %s\n  %s",
-					commit.SHA[:7], commit.URL, commit.Repository.Name, message))
-			}
-
-			// If too many commits, show first few and mention total
-			if len(commitList) > 5 {
 				fields = append(fields, Field{
-					Name:   fmt.Sprintf("💻 Recent Commits (%d total)", len(digest.CommitsToday)),
-					Value:  strings.Join(commitList[:5], "\n") + "\n" + fmt.Sprintf("... and %d more commits", len(commitList)-5),
-					Inline: false,
-				})
-			} else {
-				fields = append(fields, Field{
-					Name:   fmt.Sprintf("💻 Commits Today (%d)", len(digest.CommitsToday)),
-					Value:  strings.Join(commitList, "\n"),
+					Name:   name,
+					Value:  group.format(5),
 					Inline: false,
 				})
 			}
@@ -271,11 +302,16 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 		}
 
 		if !hasActivity {
-			fields = append(fields, Field{
+			quiet := Field{
 				Name:   "🌙 Quiet day",
 				Value:  "No significant GitHub activity today",
 				Inline: false,
-			})
+			}
+			if digest.IsWeekly {
+				quiet.Name = "🌙 Quiet week"
+				quiet.Value = "No significant GitHub activity this week"
+			}
+			fields = append(fields, quiet)
 		}
 
 	} else {
@@ -293,9 +329,10 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 				prList = append(prList, fmt.Sprintf("• [#%d %s](%s)", pr.Number, pr.Title, pr.HTMLURL))
 			}
 			fields = append(fields, Field{
-				Name:   "� Reviews Waiting",
-				Value:  strings.Join(prList, "\n"),
-				Inline: false,
+				Name:    "� Reviews Waiting",
+				Value:   strings.Join(prList, "\n"),
+				MoreURL: "https://github.com/pulls/review-requested",
+				Inline:  false,
 			})
 			hasWork = true
 		}
@@ -307,9 +344,10 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 				issueList = append(issueList, fmt.Sprintf("• [#%d %s](%s)", issue.Number, issue.Title, issue.HTMLURL))
 			}
 			fields = append(fields, Field{
-				Name:   "📝 Issues Assigned to You",
-				Value:  strings.Join(issueList, "\n"),
-				Inline: false,
+				Name:    "📝 Issues Assigned to You",
+				Value:   strings.Join(issueList, "\n"),
+				MoreURL: "https://github.com/issues/assigned",
+				Inline:  false,
 			})
 			hasWork = true
 		}
@@ -342,20 +380,15 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 			}
 		}
 
-		// Recent commits for context in morning digest
+		// Recent commits for context in morning digest, breaking changes first
 		if len(digest.CommitsToday) > 0 {
 			var commitList []string
-			for _, commit := range digest.CommitsToday {
-				// Truncate commit message if too long
-				message := commit.Message
-				if len(message) > 50 {
-					message = message[:50] + "..."
+			for _, group := range groupCommits(digest.CommitsToday) {
+				for i, commit := range group.Commits {
+					commitList = append(commitList, fmt.Sprintf("• [`%s`](%s) in %s\n  %s",
+						shortSHA(commit.SHA), commit.URL, commit.Repository.Name,
+						group.parsed[i].summary(commitRepoURL(commit), 50, true)))
 				}
-				// Remove newlines from commit message
-				message = strings.ReplaceAll(message, "\n", " ")
-
-				commitList = append(commitList, fmt.Sprintf("• [%s](%s) in %s\n  %s",
-					commit.SHA[:7], commit.URL, commit.Repository.Name, message))
 			}
 
 			// Show recent activity for context
@@ -397,41 +430,43 @@ func FormatDailyDigest(digest *github.DailyDigest, username string, avatarURL st
 		})
 	}
 
-	return &DiscordMessage{
-		Embeds: []Embed{
+	if len(digest.SkippedSections) > 0 {
+		fields = append(fields, skippedSectionsField(digest.SkippedSections))
+	}
+
+	return &Message{
+		Cards: []Card{
 			{
 				Title:       title,
 				Description: description,
 				Color:       color,
-				Timestamp:   time.Now().Format(time.RFC3339),
+				Timestamp:   time.Now(),
 				Fields:      fields,
 				Author: &Author{
 					Name:    username,
 					IconURL: avatarURL,
 				},
-				Footer: &Footer{
-					Text: "GitHub Notifier • Daily Report",
-				},
+				Footer: footer,
 			},
 		},
 	}, nil
 }
 
-func FormatCommitNotification(sha, message, author, repoName, commitURL, repoURL, avatarURL string) (*DiscordMessage, error) {
-	shortSHA := sha
-	if len(sha) > 7 {
-		shortSHA = sha[:7]
-	}
+// FormatCommitNotification formats a single commit. Conventional Commit
+// messages show their type and scope, breaking changes are highlighted with
+// the footer's explanation, and referenced issues link to repoURL.
+func FormatCommitNotification(sha, message, author, repoName, commitURL, repoURL, avatarURL string) (*Message, error) {
+	commit := parseCommitMessage(message)
 
-	embed := Embed{
+	card := Card{
 		Title:       "📝 New Commit Pushed",
 		Description: fmt.Sprintf("Here's the latest commit from **%s**!", author),
 		Color:       ColorBlue,
-		Timestamp:   time.Now().Format(time.RFC3339),
+		Timestamp:   time.Now(),
 		Fields: []Field{
 			{
 				Name:   "🚀 Commit Details",
-				Value:  fmt.Sprintf("**[%s](%s)** %s", shortSHA, commitURL, message),
+				Value:  fmt.Sprintf("**[%s](%s)** %s", shortSHA(sha), commitURL, commit.summary(repoURL, 0, true)),
 				Inline: false,
 			},
 			{
@@ -440,24 +475,51 @@ func FormatCommitNotification(sha, message, author, repoName, commitURL, repoURL
 				Inline: true,
 			},
 		},
-		Footer: &Footer{
-			Text: "GitHub Notifier • Commit Tracker",
-		},
+		Footer: "GitHub Notifier • Commit Tracker",
+	}
+	if commit.Breaking {
+		card.Color = ColorOrange
+		if commit.BreakingChange != "" {
+			card.Fields = append(card.Fields, Field{
+				Name:   "💥 Breaking Change",
+				Value:  commit.BreakingChange,
+				Inline: false,
+			})
+		}
 	}
 
 	// Add author avatar if available
 	if avatarURL != "" {
-		embed.Author = &Author{
+		card.Author = &Author{
 			Name:    author,
 			IconURL: avatarURL,
 		}
 	}
 
-	return &DiscordMessage{
-		Embeds: []Embed{embed},
+	return &Message{
+		Cards: []Card{card},
 	}, nil
 }
 
+// changeSuffix returns " — 🔄 reason" for an item alerted again because it
+// changed, or "" for a new one.
+func changeSuffix(result *github.CheckResult, htmlURL string) string {
+	if reason := result.Changes[htmlURL]; reason != "" {
+		return " — 🔄 " + reason
+	}
+	return ""
+}
+
+// skippedSectionsField notes sections that are missing or incomplete because
+// GitHub's rate limit was hit while collecting them.
+func skippedSectionsField(sections []string) Field {
+	return Field{
+		Name:   "⚠️ Incomplete data",
+		Value:  fmt.Sprintf("GitHub rate limit reached, skipped or truncated: %s", strings.Join(sections, ", ")),
+		Inline: false,
+	}
+}
+
 func FormatSimpleAlert(title, message string) string {
 	return fmt.Sprintf("🔔 **%s**\n%s", title, message)
 }
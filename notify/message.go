@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Message is a transport-neutral notification. Formatters build Messages and
+// each Notifier renders them into its own payload format (Discord embeds,
+// Slack Block Kit, Teams Adaptive Cards or raw JSON).
+type Message struct {
+	Content string `json:"content,omitempty"`
+	Cards   []Card `json:"cards,omitempty"`
+}
+
+// Card is a titled block of content, rendered as an embed on Discord, a set of
+// blocks on Slack and an Adaptive Card on Teams.
+type Card struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Color       int       `json:"color,omitempty"`
+	Timestamp   time.Time `json:"timestamp,omitempty"`
+	Author      *Author   `json:"author,omitempty"`
+	Footer      string    `json:"footer,omitempty"`
+	Fields      []Field   `json:"fields,omitempty"`
+}
+
+type Author struct {
+	Name    string `json:"name,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type Field struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Inline  bool   `json:"inline,omitempty"`
+	MoreURL string `json:"more_url,omitempty"` // Where to see the full list if the value has to be cut short
+}
+
+// Notifier delivers a Message to a chat or webhook destination.
+type Notifier interface {
+	SendMessage(message *Message) error
+}
+
+// Resumer is implemented by notifiers that split long messages into parts
+// sent one after another, such as Discord's. SendMessageFrom skips the parts
+// before first.
+type Resumer interface {
+	SendMessageFrom(message *Message, first int) error
+}
+
+// SendFrom sends message from part first on (see SentParts). Notifiers that
+// don't split messages always send all of it.
+func SendFrom(notifier Notifier, message *Message, first int) error {
+	if resumer, ok := notifier.(Resumer); ok && first > 0 {
+		return resumer.SendMessageFrom(message, first)
+	}
+	return notifier.SendMessage(message)
+}
+
+// Supported notifier types
+const (
+	TypeDiscord = "discord"
+	TypeSlack   = "slack"
+	TypeTeams   = "teams"
+	TypeWebhook = "webhook"
+)
+
+// NewNotifier creates the notifier for the given type ("discord", "slack",
+// "teams" or "webhook"). An empty type defaults to Discord.
+func NewNotifier(notifierType, webhookURL string) (Notifier, error) {
+	switch notifierType {
+	case TypeDiscord, "":
+		return NewDiscordNotifier(webhookURL), nil
+	case TypeSlack:
+		return NewSlackNotifier(webhookURL), nil
+	case TypeTeams:
+		return NewTeamsNotifier(webhookURL), nil
+	case TypeWebhook:
+		return NewWebhookNotifier(webhookURL), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", notifierType)
+	}
+}
+
+// TextMessage builds a Message that only carries plain text content.
+func TextMessage(content string) *Message {
+	return &Message{Content: content}
+}
+
+// postJSON marshals payload and POSTs it to url, treating any status >= 400 as an error.
+func postJSON(client *http.Client, url, service string, payload interface{}) error {
+	if url == "" {
+		return fmt.Errorf("%s webhook URL is not configured", service)
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s API error: %s", service, resp.Status)
+	}
+
+	return nil
+}
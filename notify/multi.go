@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MultiNotifier sends every message to all of its notifiers. A failure for
+// one destination doesn't stop delivery to the others.
+type MultiNotifier []Notifier
+
+// SendMessage returns a *DestinationError for each notifier that failed,
+// joined with errors.Join (see DestinationErrors).
+func (m MultiNotifier) SendMessage(message *Message) error {
+	var errs []error
+	for i, notifier := range m {
+		if err := notifier.SendMessage(message); err != nil {
+			errs = append(errs, &DestinationError{Destination: i, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DestinationError is a failed delivery to one of a MultiNotifier's notifiers.
+type DestinationError struct {
+	Destination int // Index of the notifier
+	Err         error
+}
+
+func (e *DestinationError) Error() string {
+	return fmt.Sprintf("destination %d: %v", e.Destination+1, e.Err)
+}
+
+func (e *DestinationError) Unwrap() error {
+	return e.Err
+}
+
+// Destinations returns the notifiers a notifier delivers to: those of a
+// MultiNotifier, or the notifier itself.
+func Destinations(notifier Notifier) []Notifier {
+	if multi, ok := notifier.(MultiNotifier); ok {
+		return multi
+	}
+	return []Notifier{notifier}
+}
+
+// DestinationErrors splits an error returned by SendMessage into the failures
+// of each destination (see Destinations). An error from any other notifier is
+// the failure of its only destination, 0.
+func DestinationErrors(err error) []*DestinationError {
+	if err == nil {
+		return nil
+	}
+	var failures []*DestinationError
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			var failure *DestinationError
+			if errors.As(err, &failure) {
+				failures = append(failures, failure)
+			}
+		}
+	}
+	if len(failures) == 0 {
+		failures = append(failures, &DestinationError{Destination: 0, Err: err})
+	}
+	return failures
+}
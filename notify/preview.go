@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PreviewNotifier writes messages to a terminal instead of sending them: the
+// JSON payload a destination of the given type would receive, followed by a
+// human-readable preview. It is used by --dry-run.
+type PreviewNotifier struct {
+	out          io.Writer
+	notifierType string
+}
+
+func NewPreviewNotifier(out io.Writer, notifierType string) *PreviewNotifier {
+	return &PreviewNotifier{
+		out:          out,
+		notifierType: notifierType,
+	}
+}
+
+func (p *PreviewNotifier) SendMessage(message *Message) error {
+	payload, err := renderPayload(p.notifierType, message)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "──── %s payload (dry run, not sent) ────\n", p.notifierType)
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false) // keep Slack's <url|text> links readable
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(payload); err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	fmt.Fprintf(&b, "──── %s preview ────\n", p.notifierType)
+	writePreview(&b, message)
+	b.WriteString("────\n")
+
+	_, err = io.WriteString(p.out, b.String())
+	return err
+}
+
+// renderPayload returns the request body a notifier of the given type sends.
+func renderPayload(notifierType string, message *Message) (interface{}, error) {
+	switch notifierType {
+	case TypeDiscord, "":
+		// One payload per Discord message the layout needs
+		payloads := layoutDiscord(message)
+		if len(payloads) == 1 {
+			return payloads[0], nil
+		}
+		return payloads, nil
+	case TypeSlack:
+		payloads := layoutSlack(message)
+		if len(payloads) == 1 {
+			return payloads[0], nil
+		}
+		return payloads, nil
+	case TypeTeams:
+		return renderTeams(message), nil
+	case TypeWebhook:
+		return message, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", notifierType)
+	}
+}
+
+// writePreview renders message as indented plain text.
+func writePreview(b *strings.Builder, message *Message) {
+	if message.Content != "" {
+		fmt.Fprintf(b, "%s\n", message.Content)
+	}
+	for _, card := range message.Cards {
+		fmt.Fprintf(b, "\n■ %s\n", card.Title)
+		if card.URL != "" {
+			fmt.Fprintf(b, "  %s\n", card.URL)
+		}
+		if card.Author != nil && card.Author.Name != "" {
+			fmt.Fprintf(b, "  by %s\n", card.Author.Name)
+		}
+		if card.Description != "" {
+			fmt.Fprintf(b, "  %s\n", indent(card.Description, "  "))
+		}
+		for _, field := range card.Fields {
+			fmt.Fprintf(b, "\n  %s\n    %s\n", field.Name, indent(field.Value, "    "))
+		}
+		if card.Footer != "" || !card.Timestamp.IsZero() {
+			footer := card.Footer
+			if !card.Timestamp.IsZero() {
+				footer = strings.TrimPrefix(footer+" • "+card.Timestamp.Format(time.RFC1123), " • ")
+			}
+			fmt.Fprintf(b, "\n  — %s\n", footer)
+		}
+	}
+}
+
+func indent(text, prefix string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Slack limits, counted in characters. See
+// https://api.slack.com/reference/block-kit/blocks
+const (
+	slackMaxMessageText = 40000 // Top-level text; Slack cuts off the rest
+	slackMaxBlocks      = 50    // Blocks in one message, over every attachment
+	slackMaxHeader      = 150   // Header block text
+	slackMaxText        = 3000  // Section and context text
+	slackMaxAltText     = 2000  // Image alt text
+)
+
+type slackMessage struct {
+	Text        string            `json:"text,omitempty"`
+	Blocks      []slackBlock      `json:"blocks,omitempty"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color,omitempty"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Fields   []slackText    `json:"fields,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackElement struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	AltText  string `json:"alt_text,omitempty"`
+}
+
+// SlackNotifier posts messages to a Slack incoming webhook using Block Kit.
+type SlackNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SendMessage sends the message, split into several Slack messages if it
+// exceeds Slack's block limits (see layoutSlack). Failures after the first
+// part are wrapped in a *PartialError.
+func (s *SlackNotifier) SendMessage(message *Message) error {
+	return s.SendMessageFrom(message, 0)
+}
+
+// SendMessageFrom is like SendMessage but skips the parts before first, to
+// resume a partly delivered message.
+func (s *SlackNotifier) SendMessageFrom(message *Message, first int) error {
+	for i, payload := range layoutSlack(message) {
+		if i < first {
+			continue
+		}
+		if err := postJSON(s.httpClient, s.webhookURL, "slack", payload); err != nil {
+			if i > 0 {
+				return &PartialError{Sent: i, Err: err}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// layoutSlack converts a Message into one or more Slack payloads that stay
+// within Slack's limits. Each card becomes a colored attachment holding
+// header, section and context blocks; long field lists are split across
+// sections the way layoutDiscord splits fields, and cards across messages
+// once a message holds slackMaxBlocks blocks. A message with no content and
+// no cards yields no payloads.
+func layoutSlack(message *Message) []*slackMessage {
+	var messages []*slackMessage
+	current := &slackMessage{Text: truncate(slackMarkdown(message.Content), slackMaxMessageText)}
+	blocks := 0
+
+	for _, card := range message.Cards {
+		if current.Text == "" {
+			current.Text = truncate(card.Title, slackMaxMessageText) // Fallback text for notifications
+		}
+		for _, attachment := range layoutSlackCard(card) {
+			if blocks+len(attachment.Blocks) > slackMaxBlocks {
+				messages = append(messages, current)
+				current = &slackMessage{Text: truncate(card.Title, slackMaxMessageText)}
+				blocks = 0
+			}
+			current.Attachments = append(current.Attachments, attachment)
+			blocks += len(attachment.Blocks)
+		}
+	}
+
+	if current.Text != "" || len(current.Attachments) > 0 {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// layoutSlackCard converts a card into as many attachments as its blocks
+// need. The first attachment carries the author, title and description;
+// continuation attachments repeat the title with "(cont.)" and the last one
+// carries the footer.
+func layoutSlackCard(card Card) []slackAttachment {
+	color := fmt.Sprintf("#%06X", card.Color)
+
+	var blocks []slackBlock
+	if card.Author != nil && card.Author.Name != "" {
+		name := truncate(card.Author.Name, slackMaxText)
+		author := slackBlock{Type: "context"}
+		if card.Author.IconURL != "" {
+			author.Elements = append(author.Elements, slackElement{
+				Type:     "image",
+				ImageURL: card.Author.IconURL,
+				AltText:  truncate(name, slackMaxAltText),
+			})
+		}
+		author.Elements = append(author.Elements, slackElement{Type: "mrkdwn", Text: name})
+		blocks = append(blocks, author)
+	}
+	if card.Title != "" {
+		blocks = append(blocks, slackHeader(card.Title))
+	}
+
+	var sections []slackBlock
+	if card.Description != "" {
+		for _, chunk := range splitField(Field{Value: card.Description}, slackMaxText) {
+			sections = append(sections, slackSection(chunk.Value))
+		}
+	}
+	for _, field := range card.Fields {
+		name := truncate(field.Name, slackMaxHeader)
+		// The name goes in bold on the section's first line
+		for _, chunk := range splitField(field, slackMaxText-runeLen(name)-3) {
+			sections = append(sections, slackSection(fmt.Sprintf("*%s*\n%s", chunk.Name, chunk.Value)))
+		}
+	}
+
+	footer := truncate(card.Footer, slackMaxText-100) // Room for the date
+	if !card.Timestamp.IsZero() {
+		date := fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>",
+			card.Timestamp.Unix(), card.Timestamp.Format(time.RFC1123))
+		footer = strings.TrimPrefix(footer+" • "+date, " • ")
+	}
+	// Leave room for the footer in every attachment, since any may be last
+	budget := slackMaxBlocks
+	if footer != "" {
+		budget--
+	}
+
+	var attachments []slackAttachment
+	for _, section := range sections {
+		if len(blocks) == budget {
+			attachments = append(attachments, slackAttachment{Color: color, Blocks: blocks})
+			blocks = nil
+			if card.Title != "" {
+				blocks = append(blocks, slackHeader(card.Title+" (cont.)"))
+			}
+		}
+		blocks = append(blocks, section)
+	}
+	if footer != "" {
+		blocks = append(blocks, slackBlock{
+			Type:     "context",
+			Elements: []slackElement{{Type: "mrkdwn", Text: footer}},
+		})
+	}
+	return append(attachments, slackAttachment{Color: color, Blocks: blocks})
+}
+
+func slackHeader(text string) slackBlock {
+	return slackBlock{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: truncate(text, slackMaxHeader)},
+	}
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{
+		Type: "section",
+		Text: &slackText{Type: "mrkdwn", Text: slackMarkdown(text)},
+	}
+}
+
+var (
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)\)`)
+	markdownBoldPattern = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+)
+
+// slackMarkdown rewrites the Markdown produced by the formatters into Slack's
+// mrkdwn dialect: [text](url) becomes <url|text> and **bold** becomes *bold*.
+func slackMarkdown(text string) string {
+	text = markdownLinkPattern.ReplaceAllString(text, "<$2|$1>")
+	return markdownBoldPattern.ReplaceAllString(text, "*$1*")
+}
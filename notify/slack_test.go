@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkSlackLimits fails the test if any payload breaks one of Slack's
+// limits.
+func checkSlackLimits(t *testing.T, payloads []*slackMessage) {
+	t.Helper()
+	for i, payload := range payloads {
+		if n := runeLen(payload.Text); n > slackMaxMessageText {
+			t.Errorf("payload %d: text is %d characters, limit %d", i, n, slackMaxMessageText)
+		}
+		if payload.Text == "" && len(payload.Attachments) == 0 {
+			t.Errorf("payload %d is empty", i)
+		}
+		blocks := 0
+		for j, attachment := range payload.Attachments {
+			blocks += len(attachment.Blocks)
+			for k, block := range attachment.Blocks {
+				limit := slackMaxText
+				if block.Type == "header" {
+					limit = slackMaxHeader
+				}
+				if block.Text != nil {
+					if n := runeLen(block.Text.Text); n > limit || n == 0 {
+						t.Errorf("payload %d attachment %d block %d: %s text is %d characters, want 1..%d", i, j, k, block.Type, n, limit)
+					}
+				}
+				for _, element := range block.Elements {
+					if n := runeLen(element.Text); n > slackMaxText {
+						t.Errorf("payload %d attachment %d block %d: element text is %d characters, limit %d", i, j, k, n, slackMaxText)
+					}
+					if n := runeLen(element.AltText); n > slackMaxAltText {
+						t.Errorf("payload %d attachment %d block %d: alt text is %d characters, limit %d", i, j, k, n, slackMaxAltText)
+					}
+				}
+			}
+		}
+		if blocks > slackMaxBlocks {
+			t.Errorf("payload %d: %d blocks, limit %d", i, blocks, slackMaxBlocks)
+		}
+	}
+}
+
+func TestLayoutSlackLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  *Message
+		payloads int // Expected number of Slack messages
+	}{
+		{
+			name:     "empty message",
+			message:  &Message{},
+			payloads: 0,
+		},
+		{
+			name:     "content only",
+			message:  TextMessage("hello"),
+			payloads: 1,
+		},
+		{
+			name:     "long content",
+			message:  TextMessage(strings.Repeat("é", 50000)),
+			payloads: 1,
+		},
+		{
+			name:     "150 rune header",
+			message:  &Message{Cards: []Card{{Title: strings.Repeat("ü", 300)}}},
+			payloads: 1,
+		},
+		{
+			name:     "3000 rune description",
+			message:  &Message{Cards: []Card{{Title: "t", Description: listValue(200, 50)}}},
+			payloads: 1,
+		},
+		{
+			name:     "3000 rune field split at list items",
+			message:  &Message{Cards: []Card{{Title: "t", Fields: []Field{{Name: "List", Value: listValue(100, 50)}}}}},
+			payloads: 1,
+		},
+		{
+			name: "long author and footer",
+			message: &Message{Cards: []Card{{
+				Title:     "t",
+				Author:    &Author{Name: strings.Repeat("a", 4000), IconURL: "https://github.com/a.png"},
+				Footer:    strings.Repeat("f", 4000),
+				Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			}}},
+			payloads: 1,
+		},
+		{
+			name:     "50 blocks per message",
+			message:  &Message{Cards: []Card{{Title: "t", Footer: "footer", Fields: manyFields(120, "v")}}},
+			payloads: 3,
+		},
+		{
+			name:     "cards across messages",
+			message:  &Message{Content: "header", Cards: manyCards(30)},
+			payloads: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads := layoutSlack(tt.message)
+			checkSlackLimits(t, payloads)
+			if len(payloads) != tt.payloads {
+				t.Fatalf("got %d payloads, want %d", len(payloads), tt.payloads)
+			}
+		})
+	}
+}
+
+func TestLayoutSlackKeepsEverything(t *testing.T) {
+	// 120 fields need more than one message, but all of them must arrive,
+	// in order, with the footer once at the end
+	message := &Message{Cards: []Card{{Title: "t", Footer: "footer", Fields: manyFields(120, "v")}}}
+
+	var names []string
+	footers := 0
+	for _, payload := range layoutSlack(message) {
+		for _, attachment := range payload.Attachments {
+			for _, block := range attachment.Blocks {
+				switch {
+				case block.Type == "section":
+					name, _, _ := strings.Cut(block.Text.Text, "\n")
+					names = append(names, strings.Trim(name, "*"))
+				case block.Type == "context" && block.Elements[0].Text == "footer":
+					footers++
+				}
+			}
+		}
+	}
+	if len(names) != 120 {
+		t.Fatalf("got %d fields, want 120", len(names))
+	}
+	for i, name := range names {
+		if want := fmt.Sprintf("Field %d", i); name != want {
+			t.Errorf("field %d is %q, want %q", i, name, want)
+		}
+	}
+	if footers != 1 {
+		t.Errorf("got %d footers, want 1", footers)
+	}
+}
+
+func TestLayoutSlackContinuation(t *testing.T) {
+	payloads := layoutSlack(&Message{Cards: []Card{{Title: "Open PRs", Fields: manyFields(60, "v")}}})
+	if len(payloads) != 2 {
+		t.Fatalf("got %d payloads, want 2", len(payloads))
+	}
+	if payloads[1].Text != "Open PRs" {
+		t.Errorf("second message text is %q, want the card title", payloads[1].Text)
+	}
+	header := payloads[1].Attachments[0].Blocks[0]
+	if header.Type != "header" || header.Text.Text != "Open PRs (cont.)" {
+		t.Errorf("second message starts with %s %q, want header %q", header.Type, header.Text.Text, "Open PRs (cont.)")
+	}
+}
+
+func TestSlackMarkdown(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"[#12](https://github.com/o/r/pull/12) by **alice**", "<https://github.com/o/r/pull/12|#12> by *alice*"},
+		{"[a](https://x) and [b](https://y)", "<https://x|a> and <https://y|b>"},
+		{"[not a link] (https://x)", "[not a link] (https://x)"},
+	}
+	for _, tt := range tests {
+		if got := slackMarkdown(tt.in); got != tt.want {
+			t.Errorf("slackMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"net/http"
+	"time"
+)
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	Actions []teamsAction  `json:"actions,omitempty"`
+	MSTeams *teamsOptions  `json:"msteams,omitempty"`
+}
+
+type teamsElement struct {
+	Type     string         `json:"type"`
+	Text     string         `json:"text,omitempty"`
+	Wrap     bool           `json:"wrap,omitempty"`
+	Weight   string         `json:"weight,omitempty"`
+	Size     string         `json:"size,omitempty"`
+	Color    string         `json:"color,omitempty"`
+	IsSubtle bool           `json:"isSubtle,omitempty"`
+	Spacing  string         `json:"spacing,omitempty"`
+	URL      string         `json:"url,omitempty"`
+	Style    string         `json:"style,omitempty"`
+	Width    string         `json:"width,omitempty"`
+	Columns  []teamsElement `json:"columns,omitempty"`
+	Items    []teamsElement `json:"items,omitempty"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type teamsOptions struct {
+	Width string `json:"width,omitempty"`
+}
+
+// TeamsNotifier posts messages to a Microsoft Teams incoming webhook (or
+// Workflows webhook) as Adaptive Cards.
+type TeamsNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+func NewTeamsNotifier(webhookURL string) *TeamsNotifier {
+	return &TeamsNotifier{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *TeamsNotifier) SendMessage(message *Message) error {
+	return postJSON(t.httpClient, t.webhookURL, "teams", renderTeams(message))
+}
+
+// renderTeams converts a Message into a Teams payload with one Adaptive Card
+// per card. Adaptive Card TextBlocks understand the Markdown links and bold
+// text the formatters produce, so values are passed through unchanged.
+func renderTeams(message *Message) *teamsMessage {
+	payload := &teamsMessage{Type: "message"}
+
+	cards := message.Cards
+	if len(cards) == 0 && message.Content != "" {
+		cards = []Card{{Description: message.Content}}
+	}
+
+	for i, card := range cards {
+		var body []teamsElement
+		if i == 0 && message.Content != "" && len(message.Cards) > 0 {
+			body = append(body, teamsElement{Type: "TextBlock", Text: message.Content, Wrap: true})
+		}
+		if card.Author != nil && card.Author.Name != "" {
+			author := teamsElement{Type: "ColumnSet"}
+			if card.Author.IconURL != "" {
+				author.Columns = append(author.Columns, teamsElement{
+					Type:  "Column",
+					Width: "auto",
+					Items: []teamsElement{{Type: "Image", URL: card.Author.IconURL, Size: "Small", Style: "Person"}},
+				})
+			}
+			author.Columns = append(author.Columns, teamsElement{
+				Type:  "Column",
+				Width: "stretch",
+				Items: []teamsElement{{Type: "TextBlock", Text: card.Author.Name, Weight: "Bolder", Wrap: true}},
+			})
+			body = append(body, author)
+		}
+		if card.Title != "" {
+			body = append(body, teamsElement{
+				Type:   "TextBlock",
+				Text:   card.Title,
+				Weight: "Bolder",
+				Size:   "Medium",
+				Color:  teamsColor(card.Color),
+				Wrap:   true,
+			})
+		}
+		if card.Description != "" {
+			body = append(body, teamsElement{Type: "TextBlock", Text: card.Description, Wrap: true})
+		}
+		for _, field := range card.Fields {
+			body = append(body,
+				teamsElement{Type: "TextBlock", Text: field.Name, Weight: "Bolder", Wrap: true, Spacing: "Medium"},
+				teamsElement{Type: "TextBlock", Text: field.Value, Wrap: true, Spacing: "Small"},
+			)
+		}
+		footer := card.Footer
+		if !card.Timestamp.IsZero() {
+			if footer != "" {
+				footer += " • "
+			}
+			footer += card.Timestamp.Format("02-01-2006 15:04 MST")
+		}
+		if footer != "" {
+			body = append(body, teamsElement{Type: "TextBlock", Text: footer, Size: "Small", IsSubtle: true, Wrap: true})
+		}
+
+		adaptiveCard := teamsCard{
+			Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+			Type:    "AdaptiveCard",
+			Version: "1.4",
+			Body:    body,
+			MSTeams: &teamsOptions{Width: "Full"},
+		}
+		if card.URL != "" {
+			adaptiveCard.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: "Open in GitHub", URL: card.URL}}
+		}
+
+		payload.Attachments = append(payload.Attachments, teamsAttachment{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     adaptiveCard,
+		})
+	}
+
+	return payload
+}
+
+// teamsColor maps card colors onto the limited Adaptive Card color palette.
+func teamsColor(color int) string {
+	switch color {
+	case ColorRed:
+		return "Attention"
+	case ColorYellow, ColorOrange:
+		return "Warning"
+	case ColorGreen:
+		return "Good"
+	case ColorBlue, ColorPurple:
+		return "Accent"
+	default:
+		return "Default"
+	}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderTeams(t *testing.T) {
+	message := &Message{
+		Content: "Daily digest",
+		Cards: []Card{{
+			Title:       "Open PRs",
+			URL:         "https://github.com/o/r/pulls",
+			Description: "2 waiting",
+			Color:       ColorRed,
+			Author:      &Author{Name: "alice", IconURL: "https://github.com/alice.png"},
+			Fields:      []Field{{Name: "o/r", Value: "[#1](https://github.com/o/r/pull/1)"}},
+			Footer:      "commit-notifier",
+			Timestamp:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	payload := renderTeams(message)
+	if payload.Type != "message" || len(payload.Attachments) != 1 {
+		t.Fatalf("got type %q with %d attachments, want message with 1", payload.Type, len(payload.Attachments))
+	}
+	card := payload.Attachments[0].Content
+	if card.Type != "AdaptiveCard" {
+		t.Errorf("card type is %q, want AdaptiveCard", card.Type)
+	}
+
+	want := []struct {
+		typ, text string
+	}{
+		{"TextBlock", "Daily digest"},
+		{"ColumnSet", ""},
+		{"TextBlock", "Open PRs"},
+		{"TextBlock", "2 waiting"},
+		{"TextBlock", "o/r"},
+		{"TextBlock", "[#1](https://github.com/o/r/pull/1)"},
+		{"TextBlock", "commit-notifier • 01-05-2024 12:00 UTC"},
+	}
+	if len(card.Body) != len(want) {
+		t.Fatalf("got %d body elements, want %d", len(card.Body), len(want))
+	}
+	for i, w := range want {
+		if got := card.Body[i]; got.Type != w.typ || got.Text != w.text {
+			t.Errorf("body %d is %s %q, want %s %q", i, got.Type, got.Text, w.typ, w.text)
+		}
+	}
+
+	if got := card.Body[2].Color; got != "Attention" {
+		t.Errorf("title color is %q, want Attention", got)
+	}
+	if columns := card.Body[1].Columns; len(columns) != 2 || columns[0].Items[0].URL != "https://github.com/alice.png" {
+		t.Errorf("author columns = %+v, want icon then name", columns)
+	}
+	if len(card.Actions) != 1 || card.Actions[0].URL != "https://github.com/o/r/pulls" {
+		t.Errorf("actions = %+v, want one opening the card URL", card.Actions)
+	}
+}
+
+func TestRenderTeamsContentOnly(t *testing.T) {
+	payload := renderTeams(TextMessage("hello"))
+	if len(payload.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(payload.Attachments))
+	}
+	body := payload.Attachments[0].Content.Body
+	if len(body) != 1 || body[0].Text != "hello" {
+		t.Errorf("body = %+v, want a single hello TextBlock", body)
+	}
+	if actions := payload.Attachments[0].Content.Actions; actions != nil {
+		t.Errorf("actions = %+v, want none", actions)
+	}
+}
+
+func TestTeamsColor(t *testing.T) {
+	tests := map[int]string{
+		ColorRed:    "Attention",
+		ColorYellow: "Warning",
+		ColorGreen:  "Good",
+		ColorBlue:   "Accent",
+		0x123456:    "Default",
+	}
+	for color, want := range tests {
+		if got := teamsColor(color); got != want {
+			t.Errorf("teamsColor(%#x) = %q, want %q", color, got, want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier POSTs the transport-neutral Message as raw JSON, for
+// destinations that do their own rendering.
+type WebhookNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+func NewWebhookNotifier(webhookURL string) *WebhookNotifier {
+	return &WebhookNotifier{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (w *WebhookNotifier) SendMessage(message *Message) error {
+	return postJSON(w.httpClient, w.webhookURL, "webhook", message)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/notify"
+)
+
+// fakeNotifier records what it is asked to send and fails with err, if set.
+type fakeNotifier struct {
+	err      error
+	sends    []int // First part of each send
+	messages []*notify.Message
+}
+
+func (f *fakeNotifier) SendMessage(message *notify.Message) error {
+	return f.SendMessageFrom(message, 0)
+}
+
+func (f *fakeNotifier) SendMessageFrom(message *notify.Message, first int) error {
+	f.sends = append(f.sends, first)
+	f.messages = append(f.messages, message)
+	return f.err
+}
+
+func outboxConfig(n int) *config.Config {
+	cfg := &config.Config{}
+	for i := 0; i < n; i++ {
+		cfg.Destinations = append(cfg.Destinations, config.Destination{Type: "discord", URL: "https://example.com"})
+	}
+	return cfg
+}
+
+func TestQueueMessageOnlyFailedDestinations(t *testing.T) {
+	ok := &fakeNotifier{}
+	failing := &fakeNotifier{err: &notify.PartialError{Sent: 2, Err: errors.New("connection reset")}}
+	notifier := notify.MultiNotifier{ok, failing}
+	cfg := outboxConfig(2)
+	state := cache.NewState()
+	message := notify.TextMessage("alert")
+
+	err := notifier.SendMessage(message)
+	if !queueMessage(state, cfg, notifier, message, []string{"workflow_1"}, err) {
+		t.Fatal("queueMessage reported nothing queued or marked")
+	}
+
+	// The first destination got it, so the key counts as sent once
+	if state.AlertCount("workflow_1") != 1 {
+		t.Errorf("alert count = %d, want 1", state.AlertCount("workflow_1"))
+	}
+	pending := state.PendingMessages()
+	if len(pending) != 1 {
+		t.Fatalf("got %d outbox entries, want 1", len(pending))
+	}
+	if entry := pending[0]; entry.Destination != 1 || entry.Part != 2 || len(entry.Keys) != 0 {
+		t.Errorf("entry for destination %d from part %d with keys %v, want destination 1 from part 2 without keys",
+			entry.Destination, entry.Part, entry.Keys)
+	}
+
+	failing.err = nil
+	drainOutbox(notifier, state, cfg)
+	if len(ok.sends) != 1 {
+		t.Errorf("delivered destination sent %d times, want 1", len(ok.sends))
+	}
+	if len(failing.sends) != 2 || failing.sends[1] != 2 {
+		t.Errorf("failed destination sends from parts %v, want [0 2]", failing.sends)
+	}
+	if len(state.PendingMessages()) != 0 {
+		t.Errorf("outbox still has %d entries", len(state.PendingMessages()))
+	}
+	if state.AlertCount("workflow_1") != 1 {
+		t.Errorf("alert count = %d after retry, want 1", state.AlertCount("workflow_1"))
+	}
+}
+
+func TestQueueMessageRejectedPerDestination(t *testing.T) {
+	rejecting := &fakeNotifier{err: &notify.DiscordError{StatusCode: http.StatusBadRequest}}
+	unavailable := &fakeNotifier{err: errors.New("503 Service Unavailable")}
+	notifier := notify.MultiNotifier{rejecting, unavailable}
+	cfg := outboxConfig(2)
+	state := cache.NewState()
+	message := notify.TextMessage("alert")
+
+	err := notifier.SendMessage(message)
+	if !queueMessage(state, cfg, notifier, message, []string{"workflow_1"}, err) {
+		t.Fatal("queueMessage reported nothing queued, want the unavailable destination queued")
+	}
+
+	pending := state.PendingMessages()
+	if len(pending) != 1 || pending[0].Destination != 1 {
+		t.Fatalf("outbox = %+v, want one entry for destination 1", pending)
+	}
+	// Nobody got it yet, so the key waits for the queued copy
+	if !state.IsNotificationQueued("workflow_1") || state.AlertCount("workflow_1") != 0 {
+		t.Error("key should be queued, not marked sent")
+	}
+
+	unavailable.err = nil
+	drainOutbox(notifier, state, cfg)
+	if len(rejecting.sends) != 1 {
+		t.Errorf("rejecting destination sent %d times, want 1", len(rejecting.sends))
+	}
+	if state.AlertCount("workflow_1") != 1 {
+		t.Errorf("alert count = %d, want 1", state.AlertCount("workflow_1"))
+	}
+}
+
+func TestDeliveredMarksKeysOnce(t *testing.T) {
+	first := &fakeNotifier{err: errors.New("timeout")}
+	second := &fakeNotifier{err: errors.New("timeout")}
+	notifier := notify.MultiNotifier{first, second}
+	cfg := outboxConfig(2)
+	state := cache.NewState()
+	message := notify.TextMessage("alert")
+
+	queueMessage(state, cfg, notifier, message, []string{"workflow_1"}, notifier.SendMessage(message))
+	if len(state.PendingMessages()) != 2 {
+		t.Fatalf("got %d outbox entries, want 2", len(state.PendingMessages()))
+	}
+
+	first.err, second.err = nil, nil
+	drainOutbox(notifier, state, cfg)
+	if got := state.AlertCount("workflow_1"); got != 1 {
+		t.Errorf("alert count = %d, want 1", got)
+	}
+	if len(first.sends) != 2 || len(second.sends) != 2 {
+		t.Errorf("sends = %d and %d, want 2 each", len(first.sends), len(second.sends))
+	}
+}
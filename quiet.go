@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+	"github.com/wilfierd/gh-notify/notify"
+)
+
+// deferredReview is the category of deferred reviews on the user's PRs,
+// which have no alert category of their own.
+const deferredReview = "review"
+
+// deferredMembership is the category of deferred repositories the user was
+// added to. They are urgent when invitations are.
+const deferredMembership = "membership"
+
+// deferAlerts holds back the alerts in result that aren't urgent, leaving only
+// the urgent ones in it, and returns how many were deferred.
+func deferAlerts(state *cache.State, cfg *config.Config, result *github.CheckResult, fingerprints map[string]cache.Fingerprint) int {
+	deferred := 0
+	hold := func(category, key, htmlURL string, item any) {
+		data, err := json.Marshal(item)
+		if err != nil {
+			slog.Error("failed to defer alert", "key", key, "error", err)
+			return
+		}
+		alert := cache.DeferredAlert{Key: key, Category: category, Item: data, Change: result.Changes[htmlURL]}
+		if fingerprint, ok := fingerprints[key]; ok {
+			alert.Fingerprint = &fingerprint
+			delete(fingerprints, key)
+		}
+		state.Defer(alert)
+		deferred++
+	}
+
+	result.PRsNeedingReview = deferPullRequests(cfg, config.CategoryReviewRequests, result.PRsNeedingReview, cache.ReviewRequestKey, hold)
+	result.StaleOwnPRs = deferPullRequests(cfg, config.CategoryStalePRs, result.StaleOwnPRs, cache.StalePRKey, hold)
+
+	var issues []github.Issue
+	for _, issue := range result.AssignedIssues {
+		if cfg.UrgentCategory(config.CategoryAssignedIssues) {
+			issues = append(issues, issue)
+		} else {
+			hold(config.CategoryAssignedIssues, cache.AssignedIssueKey(issue.RepoFullName(), issue.Number), issue.HTMLURL, issue)
+		}
+	}
+	result.AssignedIssues = issues
+
+	var notifications []github.Notification
+	for _, notification := range result.UnreadNotifications {
+		if cfg.UrgentCategory(config.CategoryNotifications) {
+			notifications = append(notifications, notification)
+		} else {
+			hold(config.CategoryNotifications, cache.NotificationKey(notification.ID), "", notification)
+		}
+	}
+	result.UnreadNotifications = notifications
+
+	var invitations []github.Invitation
+	for _, invitation := range result.RepositoryInvitations {
+		if cfg.UrgentCategory(config.CategoryInvitations) {
+			invitations = append(invitations, invitation)
+		} else {
+			hold(config.CategoryInvitations, cache.InvitationKey(invitation.ID), "", invitation)
+		}
+	}
+	result.RepositoryInvitations = invitations
+
+	var memberships []github.Membership
+	for _, membership := range result.Memberships {
+		if cfg.UrgentCategory(config.CategoryInvitations) {
+			memberships = append(memberships, membership)
+		} else {
+			hold(deferredMembership, cache.MembershipKey(membership.Repository.FullName), "", membership)
+		}
+	}
+	result.Memberships = memberships
+
+	// A failure on a feature branch can wait until morning
+	var workflows []github.WorkflowRun
+	for _, workflow := range result.FailedWorkflows {
+		if cfg.UrgentCategory(config.CategoryWorkflows) && workflow.OnDefaultBranch() {
+			workflows = append(workflows, workflow)
+		} else {
+			hold(config.CategoryWorkflows, cache.WorkflowKey(workflow.ID), "", workflow)
+		}
+	}
+	result.FailedWorkflows = workflows
+
+	// Pushes and reviews are never urgent
+	for _, commit := range result.RecentCommits {
+		hold(config.CategoryActivity, cache.CommitKey(commit.Repository.FullName, commit.SHA), "", commit)
+	}
+	result.RecentCommits = nil
+	for _, reviewed := range result.Reviews {
+		hold(deferredReview, cache.ReviewKey(reviewed.RepoFullName(), reviewed.Number, reviewed.Review.ID), "", reviewed)
+	}
+	result.Reviews = nil
+
+	return deferred
+}
+
+func deferPullRequests(cfg *config.Config, category string, prs []github.PullRequest, keyFor func(string, int) string,
+	hold func(category, key, htmlURL string, item any)) []github.PullRequest {
+	var kept []github.PullRequest
+	for _, pr := range prs {
+		if cfg.UrgentCategory(category) {
+			kept = append(kept, pr)
+		} else {
+			hold(category, keyFor(pr.RepoFullName(), pr.Number), pr.HTMLURL, pr)
+		}
+	}
+	return kept
+}
+
+// alertKeys returns the notification keys to mark sent once result is
+// delivered. Expired invitations are shown but not marked.
+func alertKeys(result *github.CheckResult) []string {
+	var keys []string
+	for _, pr := range result.PRsNeedingReview {
+		keys = append(keys, cache.ReviewRequestKey(pr.RepoFullName(), pr.Number))
+	}
+	for _, pr := range result.StaleOwnPRs {
+		keys = append(keys, cache.StalePRKey(pr.RepoFullName(), pr.Number))
+	}
+	for _, issue := range result.AssignedIssues {
+		keys = append(keys, cache.AssignedIssueKey(issue.RepoFullName(), issue.Number))
+	}
+	for _, invitation := range result.RepositoryInvitations {
+		if !invitation.IsExpired() {
+			keys = append(keys, cache.InvitationKey(invitation.ID))
+		}
+	}
+	for _, notification := range result.UnreadNotifications {
+		keys = append(keys, cache.NotificationKey(notification.ID))
+	}
+	for _, workflow := range result.FailedWorkflows {
+		keys = append(keys, cache.WorkflowKey(workflow.ID))
+	}
+	for _, reviewed := range result.Reviews {
+		keys = append(keys, cache.ReviewKey(reviewed.RepoFullName(), reviewed.Number, reviewed.Review.ID))
+	}
+	for _, membership := range result.Memberships {
+		keys = append(keys, cache.MembershipKey(membership.Repository.FullName))
+	}
+	return keys
+}
+
+// sendDeferredAlerts sends the alerts held back during quiet hours as one
+// batch and reports whether there was anything to send (see collectDeferred
+// for current).
+func sendDeferredAlerts(githubClient *github.Client, notifier notify.Notifier, state *cache.State, username string, cfg *config.Config, current *github.CheckResult) (bool, error) {
+	batch := collectDeferred(state, cfg, current)
+	message, err := batch.format(githubClient, username)
+	if err != nil || message == nil {
+		return false, err
+	}
+	return batch.record(state, cfg, notifier, message, notifier.SendMessage(message))
+}
+
+// deferredBatch is the alerts held back during quiet hours, collected to be
+// sent as one message. Collecting and recording the batch touch the state,
+// formatting and sending it don't.
+type deferredBatch struct {
+	result       *github.CheckResult
+	fingerprints map[string]cache.Fingerprint
+	keys         []string // Every deferred alert taken into the batch
+}
+
+// collectDeferred gathers the deferred alerts into a batch. Pull requests and
+// assigned issues missing from current, the latest check, are dropped: they
+// were reviewed, merged, closed or unassigned in the meantime. Only lists
+// current got completely (see github.CheckResult.Complete) count, and a nil
+// current keeps everything.
+func collectDeferred(state *cache.State, cfg *config.Config, current *github.CheckResult) *deferredBatch {
+	var listed map[string]bool
+	if current != nil {
+		listed = listedKeys(current)
+	}
+	gone := func(category, key string) bool {
+		return listed != nil && current.Complete(category) && !listed[key]
+	}
+
+	batch := &github.CheckResult{Changes: make(map[string]string)}
+	fingerprints := make(map[string]cache.Fingerprint)
+	var keys, dropped []string
+	for _, alert := range state.DeferredAlerts() {
+		keys = append(keys, alert.Key)
+		if alert.Fingerprint != nil {
+			fingerprints[alert.Key] = *alert.Fingerprint
+		}
+
+		var err error
+		var htmlURL string
+		switch alert.Category {
+		case config.CategoryReviewRequests, config.CategoryStalePRs:
+			var pr github.PullRequest
+			if err = json.Unmarshal(alert.Item, &pr); err == nil {
+				htmlURL = pr.HTMLURL
+				switch {
+				case gone(alert.Category, alert.Key):
+					dropped = append(dropped, alert.Key)
+				case alert.Category == config.CategoryStalePRs:
+					batch.StaleOwnPRs = append(batch.StaleOwnPRs, pr)
+				default:
+					batch.PRsNeedingReview = append(batch.PRsNeedingReview, pr)
+				}
+			}
+		case config.CategoryAssignedIssues:
+			var issue github.Issue
+			if err = json.Unmarshal(alert.Item, &issue); err == nil {
+				htmlURL = issue.HTMLURL
+				if gone(alert.Category, alert.Key) {
+					dropped = append(dropped, alert.Key)
+				} else {
+					batch.AssignedIssues = append(batch.AssignedIssues, issue)
+				}
+			}
+		case config.CategoryNotifications:
+			var notification github.Notification
+			if err = json.Unmarshal(alert.Item, &notification); err == nil {
+				batch.UnreadNotifications = append(batch.UnreadNotifications, notification)
+			}
+		case config.CategoryInvitations:
+			var invitation github.Invitation
+			if err = json.Unmarshal(alert.Item, &invitation); err == nil {
+				batch.RepositoryInvitations = append(batch.RepositoryInvitations, invitation)
+			}
+		case config.CategoryWorkflows:
+			var workflow github.WorkflowRun
+			if err = json.Unmarshal(alert.Item, &workflow); err == nil {
+				batch.FailedWorkflows = append(batch.FailedWorkflows, workflow)
+			}
+		case config.CategoryActivity:
+			var commit github.Commit
+			if err = json.Unmarshal(alert.Item, &commit); err == nil {
+				batch.RecentCommits = append(batch.RecentCommits, commit)
+			}
+		case deferredReview:
+			var reviewed github.ReviewedPR
+			if err = json.Unmarshal(alert.Item, &reviewed); err == nil {
+				batch.Reviews = append(batch.Reviews, reviewed)
+			}
+		case deferredMembership:
+			var membership github.Membership
+			if err = json.Unmarshal(alert.Item, &membership); err == nil {
+				batch.Memberships = append(batch.Memberships, membership)
+			}
+		default:
+			err = fmt.Errorf("unknown category %q", alert.Category)
+		}
+		if err != nil {
+			slog.Warn("dropping unreadable deferred alert", "key", alert.Key, "error", err)
+			dropped = append(dropped, alert.Key)
+			continue
+		}
+		if alert.Change != "" {
+			batch.Changes[htmlURL] = alert.Change
+		}
+	}
+
+	for _, key := range dropped {
+		delete(fingerprints, key)
+	}
+	if !cfg.DryRun {
+		state.RemoveDeferred(dropped)
+	}
+	return &deferredBatch{result: batch, fingerprints: fingerprints, keys: keys}
+}
+
+// format builds the batch's message, or returns nil if nothing is left to
+// send.
+func (b *deferredBatch) format(githubClient *github.Client, username string) (*notify.Message, error) {
+	if !b.result.HasAlerts() {
+		return nil, nil
+	}
+	var avatarURL string
+	if user, err := githubClient.GetUser(); err == nil {
+		avatarURL = user.AvatarURL
+	}
+	message, err := notify.FormatInstantAlert(b.result, username, avatarURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format deferred alerts: %w", err)
+	}
+	return message, nil
+}
+
+// record marks the batch sent once message went out, or hands it to the
+// outbox if sendErr says it didn't. Either way the alerts are no longer
+// deferred, unless nothing could be queued.
+func (b *deferredBatch) record(state *cache.State, cfg *config.Config, notifier notify.Notifier, message *notify.Message, sendErr error) (bool, error) {
+	keysToMark := alertKeys(b.result)
+	if sendErr != nil {
+		// The outbox takes over the batch; keep it deferred if it can't
+		if queueMessage(state, cfg, notifier, message, keysToMark, sendErr) {
+			storeFingerprints(state, b.fingerprints)
+			state.RemoveDeferred(b.keys)
+		}
+		return false, fmt.Errorf("failed to send deferred alerts: %w", sendErr)
+	}
+
+	if cfg.DryRun {
+		slog.Info("dry run, deferred alerts kept", "alerts", len(b.keys))
+		return true, nil
+	}
+
+	for _, key := range keysToMark {
+		state.MarkNotificationSent(key)
+	}
+	storeFingerprints(state, b.fingerprints)
+	state.RemoveDeferred(b.keys)
+	slog.Info("sent alerts deferred during quiet hours", "items", b.result.GetAlertCount())
+	return true, nil
+}
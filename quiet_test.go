@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+)
+
+func TestCollectDeferredKeepsItemsMissingFromIncompleteLists(t *testing.T) {
+	pr := github.PullRequest{Number: 1, Title: "Add widgets", HTMLURL: "https://github.com/acme/widgets/pull/1",
+		RepositoryURL: "https://api.github.com/repos/acme/widgets"}
+	issue := github.Issue{Number: 2, Title: "Broken", HTMLURL: "https://github.com/acme/widgets/issues/2",
+		RepositoryURL: "https://api.github.com/repos/acme/widgets"}
+	prKey := cache.ReviewRequestKey("acme/widgets", 1)
+	issueKey := cache.AssignedIssueKey("acme/widgets", 2)
+
+	tests := []struct {
+		name       string
+		current    *github.CheckResult
+		wantPRs    int
+		wantIssues int
+	}{
+		{"no check", nil, 1, 1},
+		{"still listed", &github.CheckResult{PRsNeedingReview: []github.PullRequest{pr}, AssignedIssues: []github.Issue{issue}}, 1, 1},
+		{"gone from complete lists", &github.CheckResult{}, 0, 0},
+		{"missing from a truncated search", &github.CheckResult{Incomplete: []string{config.CategoryReviewRequests}}, 1, 0},
+		{"both searches truncated", &github.CheckResult{Incomplete: []string{config.CategoryReviewRequests, config.CategoryAssignedIssues}}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := cache.NewState()
+			prData, _ := json.Marshal(pr)
+			issueData, _ := json.Marshal(issue)
+			state.Defer(cache.DeferredAlert{Key: prKey, Category: config.CategoryReviewRequests, Item: prData})
+			state.Defer(cache.DeferredAlert{Key: issueKey, Category: config.CategoryAssignedIssues, Item: issueData})
+
+			batch := collectDeferred(state, &config.Config{}, tt.current)
+			if got := len(batch.result.PRsNeedingReview); got != tt.wantPRs {
+				t.Errorf("batch has %d review requests, want %d", got, tt.wantPRs)
+			}
+			if got := len(batch.result.AssignedIssues); got != tt.wantIssues {
+				t.Errorf("batch has %d assigned issues, want %d", got, tt.wantIssues)
+			}
+			if _, kept := state.Deferred[prKey]; kept != (tt.wantPRs == 1) {
+				t.Errorf("review request still deferred = %v, want %v", kept, tt.wantPRs == 1)
+			}
+		})
+	}
+}
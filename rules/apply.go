@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+)
+
+// FilterResult drops the alerts the rules don't allow. Pushed commits and
+// reviews on the user's PRs are matched as config.CategoryActivity, as in
+// digests, and repositories the user was added to as
+// config.CategoryInvitations.
+func (e *Engine) FilterResult(result *github.CheckResult) {
+	result.PRsNeedingReview = keep(e, result.PRsNeedingReview, pullRequestItem(config.CategoryReviewRequests))
+	result.StaleOwnPRs = keep(e, result.StaleOwnPRs, pullRequestItem(config.CategoryStalePRs))
+	result.AssignedIssues = keep(e, result.AssignedIssues, issueItem(config.CategoryAssignedIssues))
+	result.UnreadNotifications = keep(e, result.UnreadNotifications, notificationItem)
+	result.RepositoryInvitations = keep(e, result.RepositoryInvitations, invitationItem)
+	result.FailedWorkflows = keep(e, result.FailedWorkflows, workflowItem)
+	result.RecentCommits = keep(e, result.RecentCommits, commitItem)
+	result.Reviews = keep(e, result.Reviews, reviewItem)
+	result.Memberships = keep(e, result.Memberships, membershipItem)
+}
+
+// FilterDigest drops the digest entries the rules don't allow. Pending
+// reviews, assigned issues, failed workflows and invitations are matched as
+// their alert categories; everything else as config.CategoryActivity.
+func (e *Engine) FilterDigest(digest *github.DailyDigest) {
+	activityPR := pullRequestItem(config.CategoryActivity)
+	activityIssue := issueItem(config.CategoryActivity)
+
+	digest.PRsOpened = keep(e, digest.PRsOpened, activityPR)
+	digest.PRsMerged = keep(e, digest.PRsMerged, activityPR)
+	digest.PRsReviewed = keep(e, digest.PRsReviewed, activityPR)
+	digest.IssuesOpened = keep(e, digest.IssuesOpened, activityIssue)
+	digest.IssuesClosed = keep(e, digest.IssuesClosed, activityIssue)
+	digest.CommitsToday = keep(e, digest.CommitsToday, commitItem)
+	digest.PendingReviews = keep(e, digest.PendingReviews, pullRequestItem(config.CategoryReviewRequests))
+	digest.AssignedIssues = keep(e, digest.AssignedIssues, issueItem(config.CategoryAssignedIssues))
+	digest.FailedWorkflows = keep(e, digest.FailedWorkflows, workflowItem)
+	digest.RepositoryInvitations = keep(e, digest.RepositoryInvitations, invitationItem)
+}
+
+func keep[T any](e *Engine, items []T, toItem func(T) Item) []T {
+	var kept []T
+	for _, item := range items {
+		if e.Allows(toItem(item)) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func pullRequestItem(category string) func(github.PullRequest) Item {
+	return func(pr github.PullRequest) Item {
+		draft := pr.Draft
+		return Item{
+			Category: category,
+			Repo:     pr.RepoFullName(),
+			Author:   pr.User.Login,
+			Title:    pr.Title,
+			Labels:   labelNames(pr.Labels),
+			Draft:    &draft,
+		}
+	}
+}
+
+func issueItem(category string) func(github.Issue) Item {
+	return func(issue github.Issue) Item {
+		return Item{
+			Category: category,
+			Repo:     issue.RepoFullName(),
+			Author:   issue.User.Login,
+			Title:    issue.Title,
+			Labels:   labelNames(issue.Labels),
+		}
+	}
+}
+
+func notificationItem(notification github.Notification) Item {
+	return Item{
+		Category:    config.CategoryNotifications,
+		Repo:        notification.Repository.FullName,
+		Title:       notification.Subject.Title,
+		Reason:      notification.Reason,
+		SubjectType: notification.Subject.Type,
+	}
+}
+
+func invitationItem(invitation github.Invitation) Item {
+	return Item{
+		Category: config.CategoryInvitations,
+		Repo:     invitation.Repository.FullName,
+		Author:   invitation.Inviter.Login,
+	}
+}
+
+// membershipItem matches being added to a repository as an invitation, the
+// way it usually comes about.
+func membershipItem(membership github.Membership) Item {
+	return Item{
+		Category: config.CategoryInvitations,
+		Repo:     membership.Repository.FullName,
+		Author:   membership.AddedBy.Login,
+	}
+}
+
+func workflowItem(workflow github.WorkflowRun) Item {
+	return Item{
+		Category: config.CategoryWorkflows,
+		Repo:     workflow.Repository.FullName,
+		Title:    workflow.Name,
+	}
+}
+
+// reviewItem matches a review by its reviewer, as the author of the alert.
+func reviewItem(reviewed github.ReviewedPR) Item {
+	item := pullRequestItem(config.CategoryActivity)(reviewed.PullRequest)
+	item.Author = reviewed.Review.User.Login
+	return item
+}
+
+func commitItem(commit github.Commit) Item {
+	return Item{
+		Category: config.CategoryActivity,
+		Repo:     commit.Repository.FullName,
+		Author:   commit.Author.Login,
+		Title:    commit.Message,
+	}
+}
+
+// labelNames returns the label names, non-nil even when there are none.
+func labelNames(labels []github.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
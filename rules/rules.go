@@ -0,0 +1,153 @@
+// Package rules filters alerts and digest entries with the include/exclude
+// rules from the configuration.
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/wilfierd/gh-notify/config"
+)
+
+// Item is what rules see of an alert or digest entry. Empty fields (and nil
+// Labels and Draft) mean the item has no such attribute, so rules testing it
+// don't apply to the item.
+type Item struct {
+	Category    string // Alert category, or config.CategoryActivity
+	Repo        string // "owner/repo"
+	Author      string
+	Title       string
+	Labels      []string
+	Reason      string
+	SubjectType string
+	Draft       *bool
+}
+
+// Engine decides which items to keep. An item is dropped if an exclude rule
+// matches it, or if include rules apply to it and none matches.
+type Engine struct {
+	rules []rule
+}
+
+type rule struct {
+	config.Rule
+	title *regexp.Regexp
+}
+
+// New compiles rules, typically config.Config.AlertRules.
+func New(rules []config.Rule) (*Engine, error) {
+	engine := &Engine{}
+	for i, r := range rules {
+		compiled := rule{Rule: r}
+		if r.Title != "" {
+			title, err := regexp.Compile(r.Title)
+			if err != nil {
+				return nil, fmt.Errorf("rules[%d].title: %w", i, err)
+			}
+			compiled.title = title
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+// Allows reports whether item survives the rules.
+func (e *Engine) Allows(item Item) bool {
+	included, hasInclude := false, false
+	for _, r := range e.rules {
+		if !r.appliesTo(item) {
+			continue
+		}
+		switch r.Action {
+		case config.RuleExclude:
+			if r.matches(item) {
+				return false
+			}
+		case config.RuleInclude:
+			hasInclude = true
+			included = included || r.matches(item)
+		}
+	}
+	return included || !hasInclude
+}
+
+// appliesTo reports whether the rule covers the item's category and the item
+// has every attribute the rule tests.
+func (r rule) appliesTo(item Item) bool {
+	if len(r.Categories) > 0 && !containsFold(r.Categories, item.Category) {
+		return false
+	}
+	return (len(r.Repos) == 0 && len(r.Owners) == 0 || item.Repo != "") &&
+		(len(r.Authors) == 0 || item.Author != "") &&
+		(len(r.Labels) == 0 || item.Labels != nil) &&
+		(len(r.Reasons) == 0 || item.Reason != "") &&
+		(len(r.SubjectTypes) == 0 || item.SubjectType != "") &&
+		(r.Draft == nil || item.Draft != nil) &&
+		(r.title == nil || item.Title != "")
+}
+
+// matches reports whether every condition of the rule holds for the item.
+func (r rule) matches(item Item) bool {
+	if len(r.Repos) > 0 && !matchesRepo(r.Repos, item.Repo) {
+		return false
+	}
+	if len(r.Owners) > 0 && !containsFold(r.Owners, owner(item.Repo)) {
+		return false
+	}
+	if len(r.Authors) > 0 && !containsFold(r.Authors, item.Author) {
+		return false
+	}
+	if len(r.Labels) > 0 && !anyFold(r.Labels, item.Labels) {
+		return false
+	}
+	if len(r.Reasons) > 0 && !containsFold(r.Reasons, item.Reason) {
+		return false
+	}
+	if len(r.SubjectTypes) > 0 && !containsFold(r.SubjectTypes, item.SubjectType) {
+		return false
+	}
+	if r.Draft != nil && *r.Draft != *item.Draft {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(item.Title) {
+		return false
+	}
+	return true
+}
+
+// matchesRepo reports whether repo matches any of the patterns, which may use
+// path.Match wildcards such as "owner/*".
+func matchesRepo(patterns []string, repo string) bool {
+	repo = strings.ToLower(repo)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), repo); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func owner(repo string) string {
+	owner, _, _ := strings.Cut(repo, "/")
+	return owner
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyFold(wanted, have []string) bool {
+	for _, value := range have {
+		if containsFold(wanted, value) {
+			return true
+		}
+	}
+	return false
+}
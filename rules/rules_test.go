@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/wilfierd/gh-notify/config"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestRuleMatches(t *testing.T) {
+	item := Item{
+		Category:    config.CategoryNotifications,
+		Repo:        "Acme/Widgets",
+		Author:      "octocat",
+		Title:       "fix: flaky test",
+		Labels:      []string{"bug", "CI"},
+		Reason:      "mention",
+		SubjectType: "PullRequest",
+		Draft:       boolPtr(false),
+	}
+
+	tests := []struct {
+		name string
+		rule config.Rule
+		want bool
+	}{
+		{"no conditions", config.Rule{}, true},
+		{"exact repo", config.Rule{Repos: []string{"acme/widgets"}}, true},
+		{"repo wildcard", config.Rule{Repos: []string{"acme/*"}}, true},
+		{"other repo", config.Rule{Repos: []string{"acme/gadgets"}}, false},
+		{"owner", config.Rule{Owners: []string{"ACME"}}, true},
+		{"other owner", config.Rule{Owners: []string{"globex"}}, false},
+		{"author", config.Rule{Authors: []string{"OctoCat"}}, true},
+		{"any label", config.Rule{Labels: []string{"docs", "ci"}}, true},
+		{"no such label", config.Rule{Labels: []string{"docs"}}, false},
+		{"reason", config.Rule{Reasons: []string{"review_requested", "mention"}}, true},
+		{"other reason", config.Rule{Reasons: []string{"subscribed"}}, false},
+		{"subject type", config.Rule{SubjectTypes: []string{"pullrequest"}}, true},
+		{"draft", config.Rule{Draft: boolPtr(true)}, false},
+		{"not draft", config.Rule{Draft: boolPtr(false)}, true},
+		{"title", config.Rule{Title: "^fix:"}, true},
+		{"other title", config.Rule{Title: "^feat:"}, false},
+		{"every condition holds", config.Rule{Repos: []string{"acme/*"}, Reasons: []string{"mention"}}, true},
+		{"one condition fails", config.Rule{Repos: []string{"acme/*"}, Reasons: []string{"assign"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := New([]config.Rule{tt.rule})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := engine.rules[0].matches(item); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	pr := Item{Category: config.CategoryReviewRequests, Repo: "acme/widgets", Author: "octocat", Title: "Add widget", Labels: []string{}, Draft: boolPtr(false)}
+	dependabot := Item{Category: config.CategoryReviewRequests, Repo: "acme/widgets", Author: "dependabot[bot]", Title: "Bump yaml", Labels: []string{"dependencies"}, Draft: boolPtr(false)}
+	mention := Item{Category: config.CategoryNotifications, Repo: "acme/widgets", Title: "Question", Reason: "mention", SubjectType: "Issue"}
+	subscribed := Item{Category: config.CategoryNotifications, Repo: "acme/widgets", Title: "Release", Reason: "subscribed", SubjectType: "Release"}
+	commit := Item{Category: config.CategoryActivity, Repo: "acme/widgets", Author: "octocat", Title: "fix: typo"}
+
+	includeMentions := config.Rule{Action: config.RuleInclude, Categories: []string{config.CategoryNotifications}, Reasons: []string{"mention"}}
+	excludeBots := config.Rule{Action: config.RuleExclude, Authors: []string{"dependabot[bot]"}}
+	includeAcme := config.Rule{Action: config.RuleInclude, Owners: []string{"acme"}}
+	excludeWidgets := config.Rule{Action: config.RuleExclude, Repos: []string{"acme/widgets"}}
+
+	tests := []struct {
+		name  string
+		rules []config.Rule
+		item  Item
+		want  bool
+	}{
+		{"no rules", nil, pr, true},
+		{"exclude matches", []config.Rule{excludeBots}, dependabot, false},
+		{"exclude does not match", []config.Rule{excludeBots}, pr, true},
+		{"exclude beats include", []config.Rule{includeAcme, excludeWidgets}, pr, false},
+		{"exclude beats include in any order", []config.Rule{excludeWidgets, includeAcme}, pr, false},
+		{"include matches", []config.Rule{includeMentions}, mention, true},
+		{"include covers the item but does not match", []config.Rule{includeMentions}, subscribed, false},
+		{"include for another category", []config.Rule{includeMentions}, pr, true},
+		{"any include may match", []config.Rule{{Action: config.RuleInclude, Reasons: []string{"assign"}}, includeMentions}, mention, true},
+		// Rules testing an attribute the item lacks don't apply to it
+		{"reason rule skips PRs", []config.Rule{{Action: config.RuleInclude, Reasons: []string{"mention"}}}, pr, true},
+		{"reason rule skips commits", []config.Rule{{Action: config.RuleExclude, Reasons: []string{"subscribed"}}}, commit, true},
+		{"author rule skips notifications", []config.Rule{{Action: config.RuleExclude, Authors: []string{"octocat"}}}, mention, true},
+		{"label rule skips commits", []config.Rule{{Action: config.RuleInclude, Labels: []string{"bug"}}}, commit, true},
+		{"label rule applies to unlabelled PRs", []config.Rule{{Action: config.RuleInclude, Labels: []string{"bug"}}}, pr, false},
+		{"draft rule skips issues", []config.Rule{{Action: config.RuleExclude, Draft: boolPtr(false)}}, mention, true},
+		{"draft rule applies to PRs", []config.Rule{{Action: config.RuleExclude, Draft: boolPtr(false)}}, pr, false},
+		{"activity category", []config.Rule{{Action: config.RuleExclude, Categories: []string{config.CategoryActivity}, Title: "^fix:"}}, commit, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := New(tt.rules)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := engine.Allows(tt.item); got != tt.want {
+				t.Errorf("Allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidTitle(t *testing.T) {
+	_, err := New([]config.Rule{{Action: config.RuleExclude}, {Action: config.RuleExclude, Title: "("}})
+	if err == nil {
+		t.Fatal("New accepted an invalid title pattern")
+	}
+}
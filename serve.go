@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/notify"
+	"github.com/wilfierd/gh-notify/webhook"
+)
+
+// webhookServer alerts about webhook deliveries as they arrive. It shares the
+// cache with polling runs: alerted items are marked sent so checks don't
+// repeat them, and delivery IDs are remembered so redeliveries are ignored.
+// Deliveries are answered as soon as they are recorded and sent in the
+// background; the cache is saved by tick.
+type webhookServer struct {
+	*app
+	mu        sync.Mutex // Guards the state, which isn't safe for concurrent use
+	changed   bool       // The state changed since it was last saved
+	sending   sync.WaitGroup
+	avatarURL string
+}
+
+// runServe listens on addr until ctx is cancelled, then waits for the
+// deliveries in progress and flushes the cache.
+func runServe(ctx context.Context, a *app, addr string) error {
+	if a.cfg.WebhookSecret == "" {
+		return errors.New("a webhook secret is required (set GITHUB_WEBHOOK_SECRET or server.webhook_secret)")
+	}
+
+	s := &webhookServer{app: a}
+	if user, err := a.githubClient.GetUser(); err == nil {
+		s.avatarURL = user.AvatarURL
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/webhook", &webhook.Handler{Secret: []byte(a.cfg.WebhookSecret), Handle: s.handle})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	slog.Info("webhook server started", "addr", addr, "path", "/webhook")
+
+	// Retry queued messages and send what quiet hours held back
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-errs:
+			return fmt.Errorf("webhook server failed: %w", err)
+
+		case <-ticker.C:
+			s.tick()
+
+		case <-ctx.Done():
+			slog.Info("shutdown requested, finishing deliveries")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("webhook server did not shut down cleanly", "error", err)
+			}
+			s.sending.Wait()
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.save()
+		}
+	}
+}
+
+// handle records a delivery and sends its alert in the background, unless
+// it was handled before.
+func (s *webhookServer) handle(delivery webhook.Delivery) error {
+	logger := slog.With("delivery", delivery.ID, "event", delivery.Event)
+	alert, err := webhook.Translate(delivery.Event, delivery.Payload, s.username)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	message, keys, fingerprints, err := s.record(delivery, alert, logger)
+	s.mu.Unlock()
+	if err != nil || message == nil {
+		return err
+	}
+
+	s.sending.Add(1)
+	go func() {
+		defer s.sending.Done()
+		s.send(message, keys, fingerprints, logger)
+	}()
+	return nil
+}
+
+// record marks the delivery handled and applies the filters, the
+// categories' policies and quiet hours to its alert. It returns the message
+// to send, if any, with the keys to mark sent and the fingerprints to store
+// once it is delivered. The caller holds s.mu.
+func (s *webhookServer) record(delivery webhook.Delivery, alert *webhook.Alert, logger *slog.Logger) (*notify.Message, []string, map[string]cache.Fingerprint, error) {
+	deliveryKey := cache.DeliveryKey(delivery.ID)
+	if s.state.IsNotificationSent(deliveryKey, 0) {
+		logger.Info("duplicate webhook delivery ignored")
+		return nil, nil, nil, nil
+	}
+	if !s.cfg.DryRun {
+		s.state.MarkNotificationSent(deliveryKey)
+		s.changed = true
+	}
+	if alert == nil {
+		logger.Debug("webhook delivery doesn't concern the user")
+		return nil, nil, nil, nil
+	}
+
+	// Items polling already alerted about, or that are within their
+	// cooldown, aren't alerted again
+	applyFilters(alert.Result, s.cfg, s.rules)
+	result, fingerprints := newAlerts(s.state, s.cfg, alert.Result, logger)
+	if s.cfg.InQuietHours(time.Now()) {
+		deferred := deferAlerts(s.state, s.cfg, result, fingerprints)
+		logger.Info("quiet hours, alerts deferred", "deferred", deferred)
+	}
+	if !result.HasAlerts() {
+		return nil, nil, nil, nil
+	}
+	message, err := notify.FormatInstantAlert(result, s.username, s.avatarURL)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to format alert: %w", err)
+	}
+	return message, alertKeys(result), fingerprints, nil
+}
+
+// send delivers a message, then marks its keys sent and stores the items'
+// fingerprints, or queues it for the destinations that failed.
+func (s *webhookServer) send(message *notify.Message, keys []string, fingerprints map[string]cache.Fingerprint, logger *slog.Logger) {
+	err := s.notifier.SendMessage(message)
+	if s.cfg.DryRun {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		// Queued messages are retried by tick and by polling runs
+		if queueMessage(s.state, s.cfg, s.notifier, message, keys, err) {
+			storeFingerprints(s.state, fingerprints)
+			s.changed = true
+		}
+		logger.Error("failed to send webhook alert", "error", err)
+		return
+	}
+	for _, key := range keys {
+		s.state.MarkNotificationSent(key)
+	}
+	storeFingerprints(s.state, fingerprints)
+	s.changed = len(keys) > 0 || s.changed
+	logger.Info("sent webhook alert", "keys", len(keys))
+}
+
+// tick retries queued messages, sends the alerts quiet hours held back once
+// they are over, prunes old entries and saves the cache if anything changed.
+// What to send is read under the lock and the results recorded under it, but
+// the sends happen without it, so deliveries keep being handled meanwhile.
+func (s *webhookServer) tick() {
+	s.mu.Lock()
+	pending := s.state.PendingMessages()
+	var batch *deferredBatch
+	if !s.cfg.InQuietHours(time.Now()) && len(s.state.Deferred) > 0 {
+		batch = collectDeferred(s.state, s.cfg, nil)
+	}
+	s.mu.Unlock()
+
+	var attempts []outboxAttempt
+	if !s.cfg.DryRun {
+		for _, entry := range pending {
+			attempts = append(attempts, retryQueued(s.notifier, s.cfg, entry))
+		}
+	}
+	var message *notify.Message
+	var sendErr error
+	if batch != nil {
+		var err error
+		if message, err = batch.format(s.githubClient, s.username); err != nil {
+			slog.Error("failed to send deferred alerts", "error", err)
+		} else if message != nil {
+			sendErr = s.notifier.SendMessage(message)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attempt := range attempts {
+		attempt.apply(s.state)
+	}
+	if message != nil {
+		if _, err := batch.record(s.state, s.cfg, s.notifier, message, sendErr); err != nil {
+			slog.Error("failed to send deferred alerts", "error", err)
+		}
+	}
+	if s.state.CleanupOldEntries(historyRetention) {
+		s.changed = true
+	}
+	if s.changed || s.state.OutboxChanged() || s.state.DeferredChanged() || s.state.FingerprintsChanged() {
+		if err := s.save(); err != nil {
+			slog.Warn("failed to save cache state", "store", s.store, "error", err)
+		}
+	}
+}
+
+func (s *webhookServer) save() error {
+	if s.cfg.DryRun {
+		return nil
+	}
+	if err := s.store.Save(s.state); err != nil {
+		return fmt.Errorf("failed to save cache state: %w", err)
+	}
+	s.changed = false
+	return nil
+}
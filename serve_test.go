@@ -0,0 +1,350 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wilfierd/gh-notify/cache"
+	"github.com/wilfierd/gh-notify/config"
+	"github.com/wilfierd/gh-notify/github"
+	"github.com/wilfierd/gh-notify/notify"
+	"github.com/wilfierd/gh-notify/rules"
+	"github.com/wilfierd/gh-notify/webhook"
+)
+
+// blockingNotifier holds every send until release is closed.
+type blockingNotifier struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    int
+}
+
+func (b *blockingNotifier) SendMessage(message *notify.Message) error {
+	<-b.release
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent++
+	return nil
+}
+
+func (b *blockingNotifier) sends() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sent
+}
+
+// gateNotifier signals the first send on started and holds every send until
+// release is closed.
+type gateNotifier struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	sent    int
+}
+
+func newGateNotifier() *gateNotifier {
+	return &gateNotifier{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (g *gateNotifier) SendMessage(message *notify.Message) error {
+	g.once.Do(func() { close(g.started) })
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sent++
+	return nil
+}
+
+func newTestServer(t *testing.T, cfg *config.Config, notifier notify.Notifier) *webhookServer {
+	t.Helper()
+	if cfg.Timezone == "" {
+		cfg.Timezone = "UTC"
+	}
+	engine, err := rules.New(cfg.AlertRules())
+	if err != nil {
+		t.Fatalf("rules.New: %v", err)
+	}
+	return &webhookServer{app: &app{
+		cfg:          cfg,
+		store:        cache.NewFileStore(filepath.Join(t.TempDir(), "cache.json")),
+		state:        cache.NewState(),
+		githubClient: github.NewClient("token"),
+		notifier:     notifier,
+		rules:        engine,
+		username:     "octocat",
+	}}
+}
+
+func pushDelivery(id, repo string) webhook.Delivery {
+	payload := fmt.Sprintf(`{
+		"ref": "refs/heads/main",
+		"commits": [{"id": "abc1234def", "message": "fix: typo", "url": "https://github.com/%[1]s/commit/abc1234def",
+			"timestamp": "2026-10-16T08:00:00Z", "author": {"name": "Mona", "username": "mona"}}],
+		"repository": {"full_name": "%[1]s", "html_url": "https://github.com/%[1]s"}
+	}`, repo)
+	return webhook.Delivery{ID: id, Event: "push", Payload: []byte(payload)}
+}
+
+func TestHandleRepliesBeforeSending(t *testing.T) {
+	notifier := &blockingNotifier{release: make(chan struct{})}
+	s := newTestServer(t, &config.Config{}, notifier)
+
+	done := make(chan error, 1)
+	go func() { done <- s.handle(pushDelivery("1", "acme/widgets")) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handle waited for the send")
+	}
+
+	// A redelivery while the first is still sending is ignored
+	if err := s.handle(pushDelivery("1", "acme/widgets")); err != nil {
+		t.Fatalf("handle redelivery: %v", err)
+	}
+
+	close(notifier.release)
+	s.sending.Wait()
+	if got := notifier.sends(); got != 1 {
+		t.Errorf("sent %d messages, want 1", got)
+	}
+	if !s.state.IsNotificationSent(cache.DeliveryKey("1"), 0) {
+		t.Error("delivery not recorded")
+	}
+}
+
+func TestHandleFiltersPushes(t *testing.T) {
+	notifier := &fakeNotifier{}
+	cfg := &config.Config{Filters: config.Filters{ExcludeRepos: []string{"acme/*"}}}
+	s := newTestServer(t, cfg, notifier)
+
+	if err := s.handle(pushDelivery("1", "acme/widgets")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if err := s.handle(pushDelivery("2", "globex/gadgets")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+	if len(notifier.sends) != 1 {
+		t.Errorf("sent %d messages, want 1 for the repository not excluded", len(notifier.sends))
+	}
+}
+
+func TestHandleDefersPushesDuringQuietHours(t *testing.T) {
+	notifier := &fakeNotifier{}
+	cfg := &config.Config{QuietHours: config.QuietHours{
+		Windows: []config.QuietWindow{{Start: "00:00", End: "00:00"}},
+		Urgent:  []string{config.CategoryWorkflows},
+	}}
+	s := newTestServer(t, cfg, notifier)
+
+	if err := s.handle(pushDelivery("1", "acme/widgets")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+	if len(notifier.sends) != 0 {
+		t.Errorf("sent %d messages during quiet hours, want 0", len(notifier.sends))
+	}
+	if _, ok := s.state.Deferred[cache.CommitKey("acme/widgets", "abc1234def")]; !ok {
+		t.Errorf("push not deferred: %v", s.state.Deferred)
+	}
+}
+
+func memberDelivery(id, repo string) webhook.Delivery {
+	payload := fmt.Sprintf(`{
+		"action": "added",
+		"member": {"login": "octocat"},
+		"sender": {"login": "mona"},
+		"repository": {"full_name": "%[1]s", "html_url": "https://github.com/%[1]s"}
+	}`, repo)
+	return webhook.Delivery{ID: id, Event: "member", Payload: []byte(payload)}
+}
+
+func TestHandleFiltersAndDefersMemberships(t *testing.T) {
+	notifier := &fakeNotifier{}
+	cfg := &config.Config{Filters: config.Filters{ExcludeRepos: []string{"acme/*"}}}
+	s := newTestServer(t, cfg, notifier)
+
+	if err := s.handle(memberDelivery("1", "acme/widgets")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if err := s.handle(memberDelivery("2", "globex/gadgets")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+	if len(notifier.sends) != 1 {
+		t.Fatalf("sent %d messages, want 1 for the repository not excluded", len(notifier.sends))
+	}
+	if !s.state.IsNotificationSent(cache.MembershipKey("globex/gadgets"), 0) {
+		t.Error("membership key not marked sent")
+	}
+
+	cfg.QuietHours = config.QuietHours{Windows: []config.QuietWindow{{Start: "00:00", End: "00:00"}}}
+	if err := s.handle(memberDelivery("3", "initech/tps")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+	if len(notifier.sends) != 1 {
+		t.Errorf("sent %d messages, want the membership deferred during quiet hours", len(notifier.sends))
+	}
+	if _, ok := s.state.Deferred[cache.MembershipKey("initech/tps")]; !ok {
+		t.Errorf("membership not deferred: %v", s.state.Deferred)
+	}
+}
+
+func reviewRequestDelivery(id string, number int) webhook.Delivery {
+	payload := fmt.Sprintf(`{
+		"action": "review_requested",
+		"requested_reviewer": {"login": "octocat"},
+		"pull_request": {"number": %[1]d, "title": "Add widgets", "state": "open",
+			"html_url": "https://github.com/acme/widgets/pull/%[1]d", "user": {"login": "mona"},
+			"updated_at": "2026-10-16T08:00:00Z"},
+		"repository": {"full_name": "acme/widgets", "url": "https://api.github.com/repos/acme/widgets"}
+	}`, number)
+	return webhook.Delivery{ID: id, Event: "pull_request", Payload: []byte(payload)}
+}
+
+func TestHandleSkipsAlreadyAlertedItems(t *testing.T) {
+	notifier := &fakeNotifier{}
+	s := newTestServer(t, &config.Config{}, notifier)
+
+	// A polling run already alerted about #5
+	s.state.MarkNotificationSent(cache.ReviewRequestKey("acme/widgets", 5))
+	if err := s.handle(reviewRequestDelivery("1", 5)); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+	if len(notifier.sends) != 0 {
+		t.Fatalf("sent %d messages for a review request already alerted, want 0", len(notifier.sends))
+	}
+
+	if err := s.handle(reviewRequestDelivery("2", 6)); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+	if len(notifier.sends) != 1 {
+		t.Fatalf("sent %d messages for a new review request, want 1", len(notifier.sends))
+	}
+	key := cache.ReviewRequestKey("acme/widgets", 6)
+	if !s.state.IsNotificationSent(key, 0) {
+		t.Error("review request key not marked sent")
+	}
+	if _, ok := s.state.Fingerprint(key); !ok {
+		t.Error("review request fingerprint not stored")
+	}
+
+	// Requested again within the cooldown, under a new delivery ID
+	if err := s.handle(reviewRequestDelivery("3", 6)); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+	if len(notifier.sends) != 1 {
+		t.Errorf("sent %d messages, want the repeat within the cooldown skipped", len(notifier.sends))
+	}
+}
+
+func TestTickPrunesDeliveries(t *testing.T) {
+	s := newTestServer(t, &config.Config{}, &fakeNotifier{})
+	old := cache.DeliveryKey("old")
+	s.state.MarkNotificationSent(old)
+	s.state.SentNotifications[old] = time.Now().Add(-historyRetention - time.Hour)
+	s.state.MarkNotificationSent(cache.DeliveryKey("new"))
+
+	s.tick()
+	if _, ok := s.state.SentNotifications[old]; ok {
+		t.Error("tick kept a delivery older than the retention")
+	}
+
+	saved, err := s.store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := saved.SentNotifications[cache.DeliveryKey("new")]; !ok {
+		t.Error("tick did not save the recent delivery")
+	}
+}
+
+func TestHandleReviewOnOwnPR(t *testing.T) {
+	notifier := &fakeNotifier{}
+	s := newTestServer(t, &config.Config{}, notifier)
+	payload := `{
+		"action": "submitted",
+		"review": {"id": 77, "state": "APPROVED", "user": {"login": "mona"}},
+		"pull_request": {"number": 5, "title": "Add widgets", "html_url": "https://github.com/acme/widgets/pull/5",
+			"user": {"login": "octocat"}, "updated_at": "2026-10-16T08:00:00Z"},
+		"repository": {"full_name": "acme/widgets", "url": "https://api.github.com/repos/acme/widgets"}
+	}`
+
+	if err := s.handle(webhook.Delivery{ID: "1", Event: "pull_request_review", Payload: []byte(payload)}); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	s.sending.Wait()
+
+	if len(notifier.messages) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(notifier.messages))
+	}
+	var names []string
+	for _, field := range notifier.messages[0].Cards[0].Fields {
+		names = append(names, field.Name)
+	}
+	if len(names) != 1 || names[0] != "📝 Reviews on your PRs" {
+		t.Errorf("sections = %q, want only the reviews section", names)
+	}
+	if !s.state.IsNotificationSent(cache.ReviewKey("acme/widgets", 5, 77), 0) {
+		t.Error("review key not marked sent")
+	}
+	if _, ok := s.state.SentNotifications[cache.StalePRKey("acme/widgets", 5)]; ok {
+		t.Error("review marked the stale PR alert sent")
+	}
+}
+
+func TestHandleDuringDrain(t *testing.T) {
+	notifier := newGateNotifier()
+	cfg := outboxConfig(1)
+	s := newTestServer(t, cfg, notifier)
+	message := notify.TextMessage("alert")
+	queueMessage(s.state, cfg, notifier, message, []string{"workflow_1"}, errors.New("timeout"))
+
+	ticked := make(chan struct{})
+	go func() {
+		s.tick()
+		close(ticked)
+	}()
+	<-notifier.started
+
+	// The drain is stuck sending; a delivery must still be recorded
+	handled := make(chan error, 1)
+	go func() { handled <- s.handle(pushDelivery("1", "acme/widgets")) }()
+	select {
+	case err := <-handled:
+		if err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handle waited for the drain")
+	}
+
+	close(notifier.release)
+	<-ticked
+	s.sending.Wait()
+
+	if len(s.state.PendingMessages()) != 0 {
+		t.Errorf("outbox still has %d entries", len(s.state.PendingMessages()))
+	}
+	if !s.state.IsNotificationSent("workflow_1", 0) {
+		t.Error("drained message's key not marked sent")
+	}
+	if !s.state.IsNotificationSent(cache.DeliveryKey("1"), 0) {
+		t.Error("delivery handled during the drain not recorded")
+	}
+	if notifier.sent != 2 {
+		t.Errorf("sent %d messages, want 2", notifier.sent)
+	}
+}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wilfierd/gh-notify/github"
+)
+
+// Alert is a delivery translated for the notifier. Result holds alerts in the
+// shape polling produces, so the same filters, rules, quiet hours and
+// formatting apply.
+type Alert struct {
+	Result *github.CheckResult
+}
+
+type pullRequestReviewEvent struct {
+	Action      string                 `json:"action"`
+	Review      github.Review          `json:"review"`
+	PullRequest github.PullRequest     `json:"pull_request"`
+	Repository  github.EventRepository `json:"repository"`
+}
+
+type issuesEvent struct {
+	Action string `json:"action"`
+	Issue  struct {
+		github.Issue
+		Assignees []github.User `json:"assignees"`
+	} `json:"issue"`
+	Assignee   *github.User           `json:"assignee"`
+	Repository github.EventRepository `json:"repository"`
+}
+
+type memberEvent struct {
+	Action     string                 `json:"action"`
+	Member     github.User            `json:"member"`
+	Sender     github.User            `json:"sender"`
+	Repository github.EventRepository `json:"repository"`
+}
+
+// Translate turns a delivery into an alert for username. It returns nil for
+// events and actions that don't concern the user: review requests for someone
+// else, reviews on other people's PRs, successful workflow runs and so on.
+func Translate(event string, payload []byte, username string) (*Alert, error) {
+	switch event {
+	case "push":
+		var e github.PushEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("invalid push payload: %w", err)
+		}
+		return translatePush(e), nil
+	case "pull_request":
+		var e github.PullRequestEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("invalid pull_request payload: %w", err)
+		}
+		return translatePullRequest(e, username), nil
+	case "pull_request_review":
+		var e pullRequestReviewEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("invalid pull_request_review payload: %w", err)
+		}
+		return translatePullRequestReview(e, username), nil
+	case "issues":
+		var e issuesEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("invalid issues payload: %w", err)
+		}
+		return translateIssues(e, username), nil
+	case "workflow_run":
+		var e github.WorkflowRunEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("invalid workflow_run payload: %w", err)
+		}
+		return translateWorkflowRun(e), nil
+	case "member":
+		var e memberEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("invalid member payload: %w", err)
+		}
+		return translateMember(e, username), nil
+	}
+	return nil, nil
+}
+
+// translatePush reports the pushed commits. Branch deletions carry none.
+func translatePush(e github.PushEvent) *Alert {
+	var commits []github.Commit
+	for _, commit := range e.Commits {
+		commits = append(commits, github.Commit{
+			SHA:        commit.ID,
+			Message:    commit.Message,
+			Author:     github.User{Login: commit.Author.Login()},
+			Date:       commit.Timestamp,
+			URL:        commit.URL,
+			Repository: e.Repository.Repo,
+		})
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+	return &Alert{Result: &github.CheckResult{RecentCommits: commits}}
+}
+
+// translatePullRequest reports review requests for the user.
+func translatePullRequest(e github.PullRequestEvent, username string) *Alert {
+	if e.Action != "review_requested" || e.RequestedReviewer == nil || !strings.EqualFold(e.RequestedReviewer.Login, username) {
+		return nil
+	}
+	pr := e.PullRequest.PullRequest
+	pr.RepositoryURL = e.Repository.URL
+	return &Alert{Result: &github.CheckResult{PRsNeedingReview: []github.PullRequest{pr}}}
+}
+
+// translatePullRequestReview reports approvals and change requests on the
+// user's own pull requests.
+func translatePullRequestReview(e pullRequestReviewEvent, username string) *Alert {
+	pr := e.PullRequest
+	if e.Action != "submitted" || !strings.EqualFold(pr.User.Login, username) || strings.EqualFold(e.Review.User.Login, username) {
+		return nil
+	}
+	state := strings.ToLower(e.Review.State)
+	if state != "approved" && state != "changes_requested" {
+		return nil
+	}
+	pr.RepositoryURL = e.Repository.URL
+	review := e.Review
+	review.State = state
+	return &Alert{Result: &github.CheckResult{
+		Reviews: []github.ReviewedPR{{PullRequest: pr, Review: review}},
+	}}
+}
+
+// translateIssues reports issues assigned to the user, and reopened issues
+// they are assigned to.
+func translateIssues(e issuesEvent, username string) *Alert {
+	issue := e.Issue.Issue
+	issue.RepositoryURL = e.Repository.URL
+	result := &github.CheckResult{AssignedIssues: []github.Issue{issue}}
+
+	switch e.Action {
+	case "assigned":
+		if e.Assignee != nil && strings.EqualFold(e.Assignee.Login, username) {
+			return &Alert{Result: result}
+		}
+	case "reopened":
+		for _, assignee := range e.Issue.Assignees {
+			if strings.EqualFold(assignee.Login, username) {
+				result.Changes = map[string]string{issue.HTMLURL: "reopened"}
+				return &Alert{Result: result}
+			}
+		}
+	}
+	return nil
+}
+
+// translateWorkflowRun reports workflow runs that failed, timed out or
+// couldn't start.
+func translateWorkflowRun(e github.WorkflowRunEvent) *Alert {
+	if e.Action != "completed" || !e.WorkflowRun.Failed() {
+		return nil
+	}
+	run := e.WorkflowRun.WorkflowRun
+	run.Repository = e.Repository.Repo
+	return &Alert{Result: &github.CheckResult{FailedWorkflows: []github.WorkflowRun{run}}}
+}
+
+// translateMember reports the user being added to a repository.
+func translateMember(e memberEvent, username string) *Alert {
+	if e.Action != "added" || !strings.EqualFold(e.Member.Login, username) {
+		return nil
+	}
+	return &Alert{Result: &github.CheckResult{
+		Memberships: []github.Membership{{Repository: e.Repository, AddedBy: e.Sender}},
+	}}
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"testing"
+)
+
+const repository = `"repository": {"full_name": "acme/widgets", "url": "https://api.github.com/repos/acme/widgets",
+	"html_url": "https://github.com/acme/widgets", "default_branch": "main"}`
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		check   func(t *testing.T, alert *Alert)
+	}{
+		{
+			name:  "push",
+			event: "push",
+			payload: `{"ref": "refs/heads/main", "commits": [
+				{"id": "abc", "message": "fix: one", "author": {"name": "Mona", "username": "mona"}},
+				{"id": "def", "message": "feat: two", "author": {"name": "Hubot"}}], ` + repository + `}`,
+			check: func(t *testing.T, alert *Alert) {
+				commits := alert.Result.RecentCommits
+				if len(commits) != 2 {
+					t.Fatalf("got %d commits, want 2", len(commits))
+				}
+				if commits[0].SHA != "abc" || commits[0].Author.Login != "mona" || commits[1].Author.Login != "Hubot" {
+					t.Errorf("commits = %+v", commits)
+				}
+				if commits[0].Repository.FullName != "acme/widgets" {
+					t.Errorf("repository = %q", commits[0].Repository.FullName)
+				}
+			},
+		},
+		{
+			name:    "branch deletion",
+			event:   "push",
+			payload: `{"ref": "refs/heads/old", "deleted": true, "commits": [], ` + repository + `}`,
+		},
+		{
+			name:  "review requested",
+			event: "pull_request",
+			payload: `{"action": "review_requested", "requested_reviewer": {"login": "OctoCat"},
+				"pull_request": {"number": 5, "title": "Add widgets"}, ` + repository + `}`,
+			check: func(t *testing.T, alert *Alert) {
+				prs := alert.Result.PRsNeedingReview
+				if len(prs) != 1 || prs[0].Number != 5 || prs[0].RepoFullName() != "acme/widgets" {
+					t.Errorf("review requests = %+v", prs)
+				}
+			},
+		},
+		{
+			name:  "review requested from someone else",
+			event: "pull_request",
+			payload: `{"action": "review_requested", "requested_reviewer": {"login": "mona"},
+				"pull_request": {"number": 5}, ` + repository + `}`,
+		},
+		{
+			name:  "changes requested on own PR",
+			event: "pull_request_review",
+			payload: `{"action": "submitted", "review": {"id": 7, "state": "CHANGES_REQUESTED", "user": {"login": "mona"}},
+				"pull_request": {"number": 5, "user": {"login": "octocat"}}, ` + repository + `}`,
+			check: func(t *testing.T, alert *Alert) {
+				reviews := alert.Result.Reviews
+				if len(reviews) != 1 || reviews[0].Review.State != "changes_requested" || reviews[0].RepoFullName() != "acme/widgets" {
+					t.Errorf("reviews = %+v", reviews)
+				}
+			},
+		},
+		{
+			name:  "comment review",
+			event: "pull_request_review",
+			payload: `{"action": "submitted", "review": {"id": 7, "state": "COMMENTED", "user": {"login": "mona"}},
+				"pull_request": {"number": 5, "user": {"login": "octocat"}}, ` + repository + `}`,
+		},
+		{
+			name:  "own review",
+			event: "pull_request_review",
+			payload: `{"action": "submitted", "review": {"id": 7, "state": "APPROVED", "user": {"login": "octocat"}},
+				"pull_request": {"number": 5, "user": {"login": "octocat"}}, ` + repository + `}`,
+		},
+		{
+			name:  "issue assigned",
+			event: "issues",
+			payload: `{"action": "assigned", "assignee": {"login": "octocat"},
+				"issue": {"number": 12, "html_url": "https://github.com/acme/widgets/issues/12"}, ` + repository + `}`,
+			check: func(t *testing.T, alert *Alert) {
+				issues := alert.Result.AssignedIssues
+				if len(issues) != 1 || issues[0].Number != 12 || issues[0].RepoFullName() != "acme/widgets" {
+					t.Errorf("assigned issues = %+v", issues)
+				}
+				if len(alert.Result.Changes) != 0 {
+					t.Errorf("changes = %v, want none", alert.Result.Changes)
+				}
+			},
+		},
+		{
+			name:  "issue reopened while assigned",
+			event: "issues",
+			payload: `{"action": "reopened", "issue": {"number": 12, "html_url": "https://github.com/acme/widgets/issues/12",
+				"assignees": [{"login": "mona"}, {"login": "octocat"}]}, ` + repository + `}`,
+			check: func(t *testing.T, alert *Alert) {
+				if got := alert.Result.Changes["https://github.com/acme/widgets/issues/12"]; got != "reopened" {
+					t.Errorf("change = %q, want reopened", got)
+				}
+			},
+		},
+		{
+			name:    "issue assigned to someone else",
+			event:   "issues",
+			payload: `{"action": "assigned", "assignee": {"login": "mona"}, "issue": {"number": 12}, ` + repository + `}`,
+		},
+		{
+			name:  "workflow run failed",
+			event: "workflow_run",
+			payload: `{"action": "completed", "workflow_run": {"id": 99, "name": "CI", "conclusion": "timed_out",
+				"head_branch": "main"}, ` + repository + `}`,
+			check: func(t *testing.T, alert *Alert) {
+				runs := alert.Result.FailedWorkflows
+				if len(runs) != 1 || runs[0].ID != 99 || !runs[0].OnDefaultBranch() {
+					t.Errorf("failed workflows = %+v", runs)
+				}
+			},
+		},
+		{
+			name:    "workflow run succeeded",
+			event:   "workflow_run",
+			payload: `{"action": "completed", "workflow_run": {"id": 99, "conclusion": "success"}, ` + repository + `}`,
+		},
+		{
+			name:    "added to a repository",
+			event:   "member",
+			payload: `{"action": "added", "member": {"login": "octocat"}, "sender": {"login": "mona"}, ` + repository + `}`,
+			check: func(t *testing.T, alert *Alert) {
+				memberships := alert.Result.Memberships
+				if len(memberships) != 1 || memberships[0].Repository.FullName != "acme/widgets" || memberships[0].AddedBy.Login != "mona" {
+					t.Errorf("memberships = %+v", memberships)
+				}
+			},
+		},
+		{
+			name:    "someone else added",
+			event:   "member",
+			payload: `{"action": "added", "member": {"login": "mona"}, "sender": {"login": "octocat"}, ` + repository + `}`,
+		},
+		{
+			name:    "unsupported event",
+			event:   "star",
+			payload: `{"action": "created"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert, err := Translate(tt.event, []byte(tt.payload), "octocat")
+			if err != nil {
+				t.Fatalf("Translate: %v", err)
+			}
+			if tt.check == nil {
+				if alert != nil {
+					t.Errorf("alert = %+v, want none", alert.Result)
+				}
+				return
+			}
+			if alert == nil || alert.Result == nil {
+				t.Fatal("no alert")
+			}
+			tt.check(t, alert)
+		})
+	}
+}
+
+func TestTranslateInvalidPayload(t *testing.T) {
+	for _, event := range []string{"push", "pull_request", "pull_request_review", "issues", "workflow_run", "member"} {
+		if _, err := Translate(event, []byte(`{not json`), "octocat"); err == nil {
+			t.Errorf("%s: no error for an invalid payload", event)
+		}
+	}
+}
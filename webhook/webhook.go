@@ -0,0 +1,89 @@
+// Package webhook receives GitHub webhook deliveries and translates them into
+// alerts.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// MaxPayloadSize is the largest delivery GitHub sends.
+const MaxPayloadSize = 25 << 20
+
+// Delivery is a webhook delivery whose signature has been verified.
+type Delivery struct {
+	ID      string // X-GitHub-Delivery
+	Event   string // X-GitHub-Event
+	Payload []byte
+}
+
+// Handler accepts deliveries signed with Secret and passes them to Handle.
+// Unsigned or wrongly signed requests are rejected; ping events are answered
+// without calling Handle.
+type Handler struct {
+	Secret []byte
+	Handle func(Delivery) error
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPayloadSize))
+	if err != nil {
+		http.Error(w, "payload too large or unreadable", http.StatusBadRequest)
+		return
+	}
+	if !VerifySignature(h.Secret, payload, r.Header.Get("X-Hub-Signature-256")) {
+		slog.Warn("webhook signature mismatch", "remote_addr", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	delivery := Delivery{
+		ID:      r.Header.Get("X-GitHub-Delivery"),
+		Event:   r.Header.Get("X-GitHub-Event"),
+		Payload: payload,
+	}
+	if delivery.ID == "" || delivery.Event == "" {
+		http.Error(w, "missing X-GitHub-Delivery or X-GitHub-Event", http.StatusBadRequest)
+		return
+	}
+	logger := slog.With("delivery", delivery.ID, "event", delivery.Event)
+	if delivery.Event == "ping" {
+		logger.Info("webhook ping received")
+		io.WriteString(w, "pong\n")
+		return
+	}
+
+	if err := h.Handle(delivery); err != nil {
+		logger.Error("failed to handle webhook delivery", "error", err)
+		http.Error(w, "failed to handle delivery", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// VerifySignature reports whether header, an X-Hub-Signature-256 value, is
+// the HMAC-SHA256 of payload keyed with secret.
+func VerifySignature(secret, payload []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok || len(secret) == 0 {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
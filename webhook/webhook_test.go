@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("s3cret")
+	payload := []byte(`{"zen":"Keep it logically awesome."}`)
+	valid := sign("s3cret", string(payload))
+
+	tests := []struct {
+		name   string
+		secret []byte
+		header string
+		want   bool
+	}{
+		{"valid", secret, valid, true},
+		{"wrong secret", []byte("other"), valid, false},
+		{"tampered", secret, sign("s3cret", `{"zen":"other"}`), false},
+		{"missing prefix", secret, strings.TrimPrefix(valid, "sha256="), false},
+		{"sha1 prefix", secret, "sha1=" + strings.TrimPrefix(valid, "sha256="), false},
+		{"not hex", secret, "sha256=zz", false},
+		{"empty header", secret, "", false},
+		{"empty secret", nil, sign("", string(payload)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, payload, tt.header); got != tt.want {
+				t.Errorf("VerifySignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	const secret = "s3cret"
+	payload := `{"action":"opened"}`
+
+	tests := []struct {
+		name      string
+		method    string
+		event     string
+		signature string
+		handleErr error
+		want      int
+		handled   bool
+	}{
+		{"accepted", http.MethodPost, "issues", sign(secret, payload), nil, http.StatusAccepted, true},
+		{"bad signature", http.MethodPost, "issues", sign("other", payload), nil, http.StatusUnauthorized, false},
+		{"unsigned", http.MethodPost, "issues", "", nil, http.StatusUnauthorized, false},
+		{"ping", http.MethodPost, "ping", sign(secret, payload), nil, http.StatusOK, false},
+		{"missing event", http.MethodPost, "", sign(secret, payload), nil, http.StatusBadRequest, false},
+		{"handler error", http.MethodPost, "issues", sign(secret, payload), errors.New("boom"), http.StatusInternalServerError, true},
+		{"GET", http.MethodGet, "issues", sign(secret, payload), nil, http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Delivery
+			handler := &Handler{Secret: []byte(secret), Handle: func(d Delivery) error {
+				got = &d
+				return tt.handleErr
+			}}
+
+			req := httptest.NewRequest(tt.method, "/webhook", strings.NewReader(payload))
+			req.Header.Set("X-GitHub-Delivery", "guid-1")
+			req.Header.Set("X-GitHub-Event", tt.event)
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature-256", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if (got != nil) != tt.handled {
+				t.Fatalf("handled = %v, want %v", got != nil, tt.handled)
+			}
+			if got != nil && (got.ID != "guid-1" || got.Event != tt.event || string(got.Payload) != payload) {
+				t.Errorf("delivery = %+v", got)
+			}
+		})
+	}
+}